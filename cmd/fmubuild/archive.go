@@ -0,0 +1,101 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveModified is the modification time of every archive entry so builds are reproducible.
+// Zip timestamps cannot be earlier than 1980.
+var archiveModified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var platforms = map[string]struct{ platform, ext string }{
+	"linux/amd64":   {"linux64", ".so"},
+	"linux/386":     {"linux32", ".so"},
+	"windows/amd64": {"win64", ".dll"},
+	"windows/386":   {"win32", ".dll"},
+	"darwin/amd64":  {"darwin64", ".dylib"},
+}
+
+// archiveFile is an entry in the FMU archive read from path, or data if path is empty
+type archiveFile struct {
+	name string
+	path string
+	data []byte
+}
+
+// platformBinary returns the FMI platform directory and shared library extension for GOOS and GOARCH
+func platformBinary(goos, goarch string) (platform string, ext string, err error) {
+	p, ok := platforms[goos+"/"+goarch]
+	if !ok {
+		return "", "", fmt.Errorf("Platform %s/%s is not supported by FMI 2.0", goos, goarch)
+	}
+	return p.platform, p.ext, nil
+}
+
+// directoryFiles lists all files in dir as archive entries under prefix.
+// An empty dir has no files.
+func directoryFiles(prefix, dir string) ([]archiveFile, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	var files []archiveFile
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, archiveFile{
+			name: prefix + "/" + filepath.ToSlash(rel),
+			path: path,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s directory: %w", prefix, err)
+	}
+	return files, nil
+}
+
+// writeArchive writes files to a zip archive in order
+func writeArchive(w io.Writer, files []archiveFile) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		data := f.data
+		if f.path != "" {
+			bs, err := ioutil.ReadFile(f.path)
+			if err != nil {
+				return fmt.Errorf("Error reading %s: %w", f.name, err)
+			}
+			data = bs
+		}
+
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: archiveModified,
+		})
+		if err != nil {
+			return fmt.Errorf("Error adding %s to archive: %w", f.name, err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			return fmt.Errorf("Error writing %s to archive: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("Error closing archive: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_platformBinary(t *testing.T) {
+	tests := []struct {
+		name         string
+		goos, goarch string
+		wantPlatform string
+		wantExt      string
+		wantErr      bool
+	}{
+		{
+			"Linux 64 bit",
+			"linux", "amd64",
+			"linux64", ".so",
+			false,
+		},
+		{
+			"Windows 32 bit",
+			"windows", "386",
+			"win32", ".dll",
+			false,
+		},
+		{
+			"Unsupported platform",
+			"linux", "arm64",
+			"", "",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform, ext, err := platformBinary(tt.goos, tt.goarch)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("platformBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if platform != tt.wantPlatform || ext != tt.wantExt {
+				t.Errorf("platformBinary() = %v, %v, want %v, %v", platform, ext, tt.wantPlatform, tt.wantExt)
+			}
+		})
+	}
+}
+
+func Test_modelIdentifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		desc    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			"Model identifier is required",
+			`<fmiModelDescription></fmiModelDescription>`,
+			nil,
+			true,
+		},
+		{
+			"Invalid XML",
+			`<fmiModelDescription>`,
+			nil,
+			true,
+		},
+		{
+			"Shared model identifier is returned once",
+			`<fmiModelDescription><ModelExchange modelIdentifier="a"/><CoSimulation modelIdentifier="a"/></fmiModelDescription>`,
+			[]string{"a"},
+			false,
+		},
+		{
+			"Different model identifiers are returned",
+			`<fmiModelDescription><ModelExchange modelIdentifier="a"/><CoSimulation modelIdentifier="b"/></fmiModelDescription>`,
+			[]string{"a", "b"},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := modelIdentifiers([]byte(tt.desc))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("modelIdentifiers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("modelIdentifiers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fmubuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"a.txt": "a", "sub/b.txt": "b"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := directoryFiles("resources", dir)
+	if err != nil {
+		t.Fatalf("directoryFiles() error = %v", err)
+	}
+	files = append([]archiveFile{{name: "modelDescription.xml", data: []byte("xml")}}, files...)
+
+	write := func() []byte {
+		bs := &bytes.Buffer{}
+		if err := writeArchive(bs, files); err != nil {
+			t.Fatalf("writeArchive() error = %v", err)
+		}
+		return bs.Bytes()
+	}
+	bs := write()
+	if !bytes.Equal(bs, write()) {
+		t.Errorf("Expected archive to be reproducible")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(bs), int64(len(bs)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(r)
+		r.Close()
+		got[f.Name] = string(data)
+	}
+	want := map[string]string{
+		"modelDescription.xml": "xml",
+		"resources/a.txt":      "a",
+		"resources/sub/b.txt":  "b",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("writeArchive() entries = %v, want %v", got, want)
+	}
+}
+
+func Test_directoryFiles(t *testing.T) {
+	files, err := directoryFiles("documentation", "")
+	if err != nil || files != nil {
+		t.Errorf("directoryFiles() = %v, %v, want no files", files, err)
+	}
+	if _, err := directoryFiles("documentation", "does-not-exist"); err == nil {
+		t.Errorf("directoryFiles() expected error for missing directory")
+	}
+}
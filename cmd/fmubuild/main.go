@@ -0,0 +1,226 @@
+/*
+fmubuild builds an FMU archive from a Go model package.
+
+	fmubuild [flags] <package>
+
+The package is built with `go build -buildmode c-shared` and run with `go run` to generate
+modelDescription.xml, so its main function should call fmi.WriteModelDescription.
+The shared library is added to binaries/<platform> for every model identifier in the model description.
+resources/ and documentation/ are added from the package directory if present.
+*/
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// crossEnv are the environment variables for a cross build, which do not apply to
+// running the model package on the build machine
+var crossEnv = []string{
+	"GOOS", "GOARCH", "GO386", "GOAMD64", "GOARM", "GOMIPS", "GOMIPS64", "GOPPC64",
+	"CC", "CXX", "CGO_CFLAGS", "CGO_CPPFLAGS", "CGO_CXXFLAGS", "CGO_FFLAGS", "CGO_LDFLAGS",
+}
+
+type options struct {
+	pkg           string
+	output        string
+	resources     string
+	documentation string
+	goos          string
+	goarch        string
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.output, "o", "", "output `file`, defaults to <modelIdentifier>.fmu")
+	flag.StringVar(&opts.resources, "resources", "", "resources `directory`, defaults to <package>/resources")
+	flag.StringVar(&opts.documentation, "documentation", "", "documentation `directory`, defaults to <package>/documentation")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <package>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.pkg = flag.Arg(0)
+	opts.goos = env("GOOS", runtime.GOOS)
+	opts.goarch = env("GOARCH", runtime.GOARCH)
+
+	if err := build(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func build(opts options) error {
+	platform, ext, err := platformBinary(opts.goos, opts.goarch)
+	if err != nil {
+		return err
+	}
+
+	src, err := sourceDir(opts.pkg)
+	if err != nil {
+		return err
+	}
+	desc, err := modelDescription(opts.pkg)
+	if err != nil {
+		return err
+	}
+	ids, err := modelIdentifiers(desc)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "fmubuild")
+	if err != nil {
+		return fmt.Errorf("Error creating build directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	lib := filepath.Join(dir, ids[0]+ext)
+	if err := goCommand("build", "-buildmode", "c-shared", "-trimpath", "-o", lib, opts.pkg).Run(); err != nil {
+		return fmt.Errorf("Error building shared library for %s: %w", opts.pkg, err)
+	}
+
+	files := []archiveFile{
+		{name: "modelDescription.xml", data: desc},
+	}
+	for _, id := range ids {
+		files = append(files, archiveFile{
+			name: "binaries/" + platform + "/" + id + ext,
+			path: lib,
+		})
+	}
+	for _, d := range []struct{ name, dir string }{
+		{"resources", opts.resources},
+		{"documentation", opts.documentation},
+	} {
+		fs, err := directoryFiles(d.name, packageDir(src, d.dir, d.name))
+		if err != nil {
+			return err
+		}
+		files = append(files, fs...)
+	}
+
+	output := opts.output
+	if output == "" {
+		output = ids[0] + ".fmu"
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("Error creating FMU: %w", err)
+	}
+	if err := writeArchive(f, files); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// modelDescription runs the model package on the build machine to generate modelDescription.xml
+func modelDescription(pkg string) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	cmd := goCommand("run", pkg)
+	cmd.Env = hostEnv(os.Environ())
+	cmd.Stdout = stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Error generating model description for %s: %w", pkg, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// modelIdentifiers returns the unique model identifiers of model exchange and co-simulation
+func modelIdentifiers(desc []byte) ([]string, error) {
+	type identifier struct {
+		ModelIdentifier string `xml:"modelIdentifier,attr"`
+	}
+	md := struct {
+		ModelExchange *identifier
+		CoSimulation  *identifier
+	}{}
+	if err := xml.Unmarshal(desc, &md); err != nil {
+		return nil, fmt.Errorf("Error parsing model description: %w", err)
+	}
+
+	var ids []string
+	for _, id := range []*identifier{md.ModelExchange, md.CoSimulation} {
+		if id == nil || id.ModelIdentifier == "" {
+			continue
+		}
+		if len(ids) == 0 || ids[0] != id.ModelIdentifier {
+			ids = append(ids, id.ModelIdentifier)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("Model description must define ModelExchange or CoSimulation with a modelIdentifier")
+	}
+	return ids, nil
+}
+
+// hostEnv returns environ without the settings for a cross build
+func hostEnv(environ []string) []string {
+	var env []string
+	for _, e := range environ {
+		cross := false
+		for _, k := range crossEnv {
+			cross = cross || strings.HasPrefix(e, k+"=")
+		}
+		if !cross {
+			env = append(env, e)
+		}
+	}
+	return env
+}
+
+// sourceDir returns the source directory of pkg, which can be an import path or a relative path
+func sourceDir(pkg string) (string, error) {
+	stdout := &bytes.Buffer{}
+	cmd := goCommand("list", "-e", "-f", "{{.Dir}}", pkg)
+	cmd.Stdout = stdout
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error finding directory of %s: %w", pkg, err)
+	}
+	dir := strings.TrimSpace(stdout.String())
+	if dir == "" {
+		return "", fmt.Errorf("Package %s has no directory", pkg)
+	}
+	return dir, nil
+}
+
+// packageDir returns dir if set, otherwise the named directory in the package directory src if it exists
+func packageDir(src, dir, name string) string {
+	if dir != "" {
+		return dir
+	}
+	d := filepath.Join(src, name)
+	if _, err := os.Stat(d); err != nil {
+		return ""
+	}
+	return d
+}
+
+func goCommand(args ...string) *exec.Cmd {
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+func env(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_hostEnv(t *testing.T) {
+	got := hostEnv([]string{
+		"PATH=/usr/bin",
+		"GOOS=windows",
+		"GOARCH=386",
+		"CC=i686-w64-mingw32-gcc",
+		"CGO_LDFLAGS=-static",
+		"CGO_ENABLED=1",
+		"GOPATH=/go",
+		"GOOSE=honk",
+	})
+	want := []string{"PATH=/usr/bin", "CGO_ENABLED=1", "GOPATH=/go", "GOOSE=honk"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hostEnv() = %v, want %v", got, want)
+	}
+}
+
+func Test_sourceDir(t *testing.T) {
+	want, err := filepath.Abs(filepath.Join("..", "..", "examples", "BouncingBall"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range []string{
+		"github.com/tanenbaum/go-fmi/examples/BouncingBall",
+		"../../examples/BouncingBall",
+	} {
+		got, err := sourceDir(pkg)
+		if err != nil {
+			t.Errorf("sourceDir(%s) error = %v", pkg, err)
+		}
+		if got != want {
+			t.Errorf("sourceDir(%s) = %v, want %v", pkg, got, want)
+		}
+	}
+}
+
+func Test_packageDir(t *testing.T) {
+	src, err := ioutil.TempDir("", "fmubuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(src)
+	if err := os.Mkdir(filepath.Join(src, "resources"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := packageDir(src, "", "resources"), filepath.Join(src, "resources"); got != want {
+		t.Errorf("packageDir() = %v, want %v", got, want)
+	}
+	if got := packageDir(src, "", "documentation"); got != "" {
+		t.Errorf("packageDir() = %v, want no directory", got)
+	}
+	if got := packageDir(src, "docs", "documentation"); got != "docs" {
+		t.Errorf("packageDir() = %v, want docs", got)
+	}
+}
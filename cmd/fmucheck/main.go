@@ -0,0 +1,83 @@
+/*
+fmucheck checks that an FMU complies with the FMI 2.0 standard.
+
+	fmucheck [flags] <fmu>
+
+The model description is validated and the shared library for the running platform is loaded to run
+the calling sequences of each interface type. The report is written as text, JSON or JUnit XML.
+The exit status is 1 if any check fails.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tanenbaum/go-fmi/pkg/checker"
+)
+
+type options struct {
+	fmu    string
+	format string
+	output string
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.format, "format", "text", "report `format`, text, json or junit")
+	flag.StringVar(&opts.output, "o", "", "report `file`, defaults to stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <fmu>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.fmu = flag.Arg(0)
+
+	failed, err := check(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// check checks the FMU and writes the report, returning true if any check failed
+func check(opts options) (bool, error) {
+	var write func(*checker.Report, io.Writer) error
+	switch opts.format {
+	case "text":
+		write = (*checker.Report).WriteText
+	case "json":
+		write = (*checker.Report).WriteJSON
+	case "junit":
+		write = (*checker.Report).WriteJUnit
+	default:
+		return false, fmt.Errorf("Unknown report format %s, expected text, json or junit", opts.format)
+	}
+
+	report, err := checker.Check(opts.fmu)
+	if err != nil {
+		return false, err
+	}
+	if opts.output == "" {
+		return report.Failed(), write(report, os.Stdout)
+	}
+	f, err := os.Create(opts.output)
+	if err != nil {
+		return false, fmt.Errorf("Error creating report: %w", err)
+	}
+	if err := write(report, f); err != nil {
+		f.Close()
+		return false, fmt.Errorf("Error writing report: %w", err)
+	}
+	return report.Failed(), f.Close()
+}
@@ -0,0 +1,26 @@
+package main
+
+import "github.com/tanenbaum/go-fmi/pkg/importer"
+
+// exchangeModel is a model exchange instance with the number of continuous states and event indicators
+// from the model description, so it can be integrated by a solver.CoSimulator
+type exchangeModel struct {
+	*importer.Instance
+	nx, ni int
+}
+
+func (m exchangeModel) GetContinuousStates() ([]float64, error) {
+	return m.Instance.GetContinuousStates(m.nx)
+}
+
+func (m exchangeModel) GetDerivatives() ([]float64, error) {
+	return m.Instance.GetDerivatives(m.nx)
+}
+
+func (m exchangeModel) GetEventIndicators() ([]float64, error) {
+	return m.Instance.GetEventIndicators(m.ni)
+}
+
+func (m exchangeModel) GetNominalsOfContinuousStates() ([]float64, error) {
+	return m.Instance.GetNominalsOfContinuousStates(m.nx)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// inputs are variable values over time read from a CSV file
+type inputs struct {
+	times []float64
+	// rows are the values at each time, in the order of variables
+	rows      [][]float64
+	variables []fmi.ScalarVariable
+}
+
+// readInputs reads a CSV file with a time column followed by a column for each variable
+func readInputs(md fmi.ModelDescription, name string) (*inputs, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening input file: %w", err)
+	}
+	defer f.Close()
+	in, err := parseInputs(md, f)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading input file %s: %w", name, err)
+	}
+	return in, nil
+}
+
+func parseInputs(md fmi.ModelDescription, r io.Reader) (*inputs, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("Missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header[0] != "time" {
+		return nil, fmt.Errorf("First column must be time, got %s", header[0])
+	}
+	in := &inputs{}
+	for _, name := range header[1:] {
+		sv, ok := variable(md, name)
+		if !ok {
+			return nil, fmt.Errorf("Unknown variable %s", name)
+		}
+		if sv.Type() == fmi.VariableTypeString {
+			return nil, fmt.Errorf("String variable %s is not supported", name)
+		}
+		in.variables = append(in.variables, sv)
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make([]float64, len(record))
+		for i, s := range record {
+			if row[i], err = parseValue(s); err != nil {
+				return nil, fmt.Errorf("Invalid value %s for %s: %w", s, header[i], err)
+			}
+		}
+		if n := len(in.times); n > 0 && row[0] < in.times[n-1] {
+			return nil, fmt.Errorf("Time %g is before the previous row", row[0])
+		}
+		in.times = append(in.times, row[0])
+		in.rows = append(in.rows, row[1:])
+	}
+	if len(in.times) == 0 {
+		return nil, errors.New("No input values")
+	}
+	return in, nil
+}
+
+// parseValue parses a number, or true and false for booleans
+func parseValue(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return f, nil
+	}
+	if b, berr := strconv.ParseBool(s); berr == nil {
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, err
+}
+
+/*
+values returns the input values at time. Real values are interpolated linearly between rows and other
+types keep the value of the last row at or before time. Values before the first row and after the last row
+are the values of the first and last rows.
+*/
+func (in *inputs) values(time float64) []float64 {
+	// i is the last row at or before time
+	i := sort.Search(len(in.times), func(i int) bool { return in.times[i] > time }) - 1
+	if i < 0 {
+		return in.rows[0]
+	}
+	if i == len(in.times)-1 {
+		return in.rows[i]
+	}
+	s := (time - in.times[i]) / (in.times[i+1] - in.times[i])
+	vs := make([]float64, len(in.variables))
+	for j, sv := range in.variables {
+		vs[j] = in.rows[i][j]
+		if sv.Type() == fmi.VariableTypeReal {
+			vs[j] += s * (in.rows[i+1][j] - in.rows[i][j])
+		}
+	}
+	return vs
+}
+
+// apply sets the input values at time, nil inputs set nothing
+func (in *inputs) apply(setter fmi.ValueSetter, time float64) error {
+	if in == nil || len(in.variables) == 0 {
+		return nil
+	}
+	var reals, integers, booleans fmi.ValueReference
+	var fs []float64
+	var is []int32
+	var bs []bool
+	for j, v := range in.values(time) {
+		sv := in.variables[j]
+		switch sv.Type() {
+		case fmi.VariableTypeReal:
+			reals = append(reals, sv.ValueReference)
+			fs = append(fs, v)
+		case fmi.VariableTypeInteger, fmi.VariableTypeEnumeration:
+			integers = append(integers, sv.ValueReference)
+			is = append(is, int32(v))
+		case fmi.VariableTypeBoolean:
+			booleans = append(booleans, sv.ValueReference)
+			bs = append(bs, v != 0)
+		}
+	}
+	if len(reals) > 0 {
+		if err := setter.SetReal(reals, fs); err != nil {
+			return fmt.Errorf("Error setting Real inputs at time %g: %w", time, err)
+		}
+	}
+	if len(integers) > 0 {
+		if err := setter.SetInteger(integers, is); err != nil {
+			return fmt.Errorf("Error setting Integer inputs at time %g: %w", time, err)
+		}
+	}
+	if len(booleans) > 0 {
+		if err := setter.SetBoolean(booleans, bs); err != nil {
+			return fmt.Errorf("Error setting Boolean inputs at time %g: %w", time, err)
+		}
+	}
+	return nil
+}
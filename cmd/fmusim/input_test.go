@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+func inputDescription() fmi.ModelDescription {
+	input := fmi.VariableCausalityInput
+	return fmi.ModelDescription{
+		ModelVariables: []fmi.ScalarVariable{
+			{Name: "u", ValueReference: 0, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "n", ValueReference: 0, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{Integer: &fmi.IntegerVariable{}}},
+			{Name: "b", ValueReference: 0, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{Boolean: &fmi.BooleanVariable{}}},
+			{Name: "s", ValueReference: 0, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{String: &fmi.StringVariable{}}},
+		},
+	}
+}
+
+// setter records the values set
+type setter struct {
+	reals    []float64
+	integers []int32
+	booleans []bool
+	err      error
+}
+
+func (s *setter) SetReal(_ fmi.ValueReference, fs []float64) error {
+	s.reals = fs
+	return s.err
+}
+
+func (s *setter) SetInteger(_ fmi.ValueReference, is []int32) error {
+	s.integers = is
+	return s.err
+}
+
+func (s *setter) SetBoolean(_ fmi.ValueReference, bs []bool) error {
+	s.booleans = bs
+	return s.err
+}
+
+func (s *setter) SetString(fmi.ValueReference, []string) error {
+	return s.err
+}
+
+func Test_parseInputs_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"Empty", ""},
+		{"No time column", "u\n1\n"},
+		{"Unknown variable", "time,x\n0,1\n"},
+		{"String variable", "time,s\n0,a\n"},
+		{"No rows", "time,u\n"},
+		{"Invalid value", "time,u\n0,a\n"},
+		{"Missing value", "time,u\n0\n"},
+		{"Decreasing time", "time,u\n1,1\n0,1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseInputs(inputDescription(), strings.NewReader(tt.input)); err == nil {
+				t.Errorf("parseInputs() expected error")
+			}
+		})
+	}
+}
+
+func Test_inputs_apply(t *testing.T) {
+	in, err := parseInputs(inputDescription(), strings.NewReader(
+		"# inputs\ntime,u,n,b\n0,0,0,false\n1,2,4,true\n1,4,5,0\n2,0,6,1\n"))
+	if err != nil {
+		t.Fatalf("parseInputs() error = %v", err)
+	}
+	tests := []struct {
+		time float64
+		want setter
+	}{
+		{-1, setter{[]float64{0}, []int32{0}, []bool{false}, nil}},
+		{0.5, setter{[]float64{1}, []int32{0}, []bool{false}, nil}},
+		// the last row at a time is used at events
+		{1, setter{[]float64{4}, []int32{5}, []bool{false}, nil}},
+		{1.75, setter{[]float64{1}, []int32{5}, []bool{false}, nil}},
+		{3, setter{[]float64{0}, []int32{6}, []bool{true}, nil}},
+	}
+	for _, tt := range tests {
+		s := &setter{}
+		if err := in.apply(s, tt.time); err != nil {
+			t.Fatalf("apply() error = %v", err)
+		}
+		if diff := cmp.Diff(&tt.want, s, cmp.AllowUnexported(setter{})); diff != "" {
+			t.Errorf("apply(%g) mismatch (-want +got):\n%s", tt.time, diff)
+		}
+	}
+
+	if err := in.apply(&setter{err: errors.New("Set failed")}, 0); err == nil {
+		t.Errorf("apply() expected error from setter")
+	}
+	var none *inputs
+	if err := none.apply(&setter{err: errors.New("Set failed")}, 0); err != nil {
+		t.Errorf("apply() on nil inputs error = %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+/*
+fmusim simulates an FMU and writes the results as CSV.
+
+	fmusim [flags] <fmu>
+
+The shared library for the running platform is extracted from the FMU and loaded directly, so no other
+simulation tool is needed. Co-simulation FMUs are stepped at the step size. Model exchange FMUs are
+integrated with RK4 at the step size, locating state events in between.
+
+Start, stop time and step size default to the DefaultExperiment in the model description.
+Start values are overridden with -set name=value, which may be repeated. Inputs are read from a CSV file
+in the FMI cross-check format: a time column followed by a column for each variable. Real inputs are
+interpolated linearly between rows and other types keep the value of the last row before the time.
+
+The output CSV has a time column followed by the output variables, or the variables matching -record.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type options struct {
+	fmu    string
+	output string
+	// fmuType is "cs" or "me", empty to use co-simulation if the FMU supports it
+	fmuType string
+	// start, stop and step are nil to use the default experiment
+	start, stop, step *float64
+	interval          float64
+	sets              assignments
+	input             string
+	record            []string
+}
+
+// assignments are the name=value pairs of -set flags
+type assignments []assignment
+
+type assignment struct {
+	name, value string
+}
+
+func (a *assignments) String() string {
+	ss := make([]string, len(*a))
+	for i, s := range *a {
+		ss[i] = s.name + "=" + s.value
+	}
+	return strings.Join(ss, ",")
+}
+
+func (a *assignments) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return fmt.Errorf("Expected name=value, got %s", s)
+	}
+	*a = append(*a, assignment{name: s[:i], value: s[i+1:]})
+	return nil
+}
+
+func main() {
+	opts := options{}
+	var start, stop, step float64
+	var record string
+	flag.StringVar(&opts.output, "o", "", "output CSV `file`, defaults to <modelName>_out.csv")
+	flag.StringVar(&opts.fmuType, "interface", "", "FMI `type` to simulate, cs or me, defaults to cs if supported")
+	flag.Float64Var(&start, "start", 0, "start `time`, defaults to the default experiment or 0")
+	flag.Float64Var(&stop, "stop", 0, "stop `time`, defaults to the default experiment or start + 1")
+	flag.Float64Var(&step, "step", 0, "step `size`, defaults to the default experiment or 1/500 of the run")
+	flag.Float64Var(&opts.interval, "interval", 0, "output `interval`, defaults to every step")
+	flag.Var(&opts.sets, "set", "set a start value as `name=value`, may be repeated")
+	flag.StringVar(&opts.input, "input", "", "input CSV `file`")
+	flag.StringVar(&record, "record", "", "comma separated `variables` or patterns to record, defaults to outputs")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <fmu>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	opts.fmu = flag.Arg(0)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "start":
+			opts.start = &start
+		case "stop":
+			opts.stop = &stop
+		case "step":
+			opts.step = &step
+		}
+	})
+	if record != "" {
+		opts.record = strings.Split(record, ",")
+	}
+
+	if err := simulate(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+	"github.com/tanenbaum/go-fmi/pkg/recorder"
+	"github.com/tanenbaum/go-fmi/pkg/solver"
+)
+
+// instance is an instantiated FMU, a co-simulation instance or model exchange instance wrapped by a solver
+type instance interface {
+	fmi.ModelInstance
+	fmi.ValueGetterSetter
+}
+
+// stepper does a communication step, returning the end time and whether the FMU terminated the simulation
+type stepper func(time, stepSize float64) (float64, bool, error)
+
+// experiment is the simulated time interval
+type experiment struct {
+	start, stop, step float64
+	tolerance         *float64
+}
+
+func simulate(opts options) error {
+	a, err := importer.OpenArchive(opts.fmu)
+	if err != nil {
+		return err
+	}
+	md := a.ModelDescription
+	fmuType, id, err := interfaceType(md, opts.fmuType)
+	if err != nil {
+		a.Close()
+		return err
+	}
+	exp, err := newExperiment(md, opts)
+	if err != nil {
+		a.Close()
+		return err
+	}
+	dir, err := a.ExtractTemp()
+	a.Close()
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var in *inputs
+	if opts.input != "" {
+		if in, err = readInputs(md, opts.input); err != nil {
+			return err
+		}
+	}
+	record := recorder.Options{Interval: opts.interval, Variables: opts.record}
+	if len(opts.record) == 0 {
+		record.Causalities = []fmi.VariableCausality{fmi.VariableCausalityOutput}
+	}
+	rec, err := recorder.New(md, record)
+	if err != nil {
+		return err
+	}
+
+	binary, err := importer.BinaryPath(dir, id)
+	if err != nil {
+		return err
+	}
+	resources, err := importer.ResourceLocation(dir)
+	if err != nil {
+		return err
+	}
+	lib, err := importer.Load(binary)
+	if err != nil {
+		return err
+	}
+	defer lib.Close()
+	fmu, err := lib.Instantiate(md.Name, fmuType, md.GUID, resources, importer.Callbacks{Logger: logger}, false, false)
+	if err != nil {
+		return err
+	}
+	defer fmu.FreeInstance()
+
+	inst, step := coSimulation(fmu)
+	if fmuType == fmi.FMUTypeModelExchange {
+		inst, step = modelExchange(md, fmu, exp.step)
+	}
+	if err := run(inst, step, md, exp, opts.sets, in, rec); err != nil {
+		return err
+	}
+
+	output := opts.output
+	if output == "" {
+		output = md.Name + "_out.csv"
+	}
+	return rec.WriteFile(output)
+}
+
+// interfaceType returns the FMU type and model identifier to simulate, "cs", "me" or empty for the default
+func interfaceType(md fmi.ModelDescription, name string) (fmi.FMUType, string, error) {
+	switch name {
+	case "":
+		if md.CoSimulation != nil {
+			return fmi.FMUTypeCoSimulation, md.CoSimulation.ModelIdentifier, nil
+		}
+		if md.ModelExchange != nil {
+			return fmi.FMUTypeModelExchange, md.ModelExchange.ModelIdentifier, nil
+		}
+		return 0, "", errors.New("FMU does not support co-simulation or model exchange")
+	case "cs":
+		if md.CoSimulation == nil {
+			return 0, "", errors.New("FMU does not support co-simulation")
+		}
+		return fmi.FMUTypeCoSimulation, md.CoSimulation.ModelIdentifier, nil
+	case "me":
+		if md.ModelExchange == nil {
+			return 0, "", errors.New("FMU does not support model exchange")
+		}
+		return fmi.FMUTypeModelExchange, md.ModelExchange.ModelIdentifier, nil
+	}
+	return 0, "", fmt.Errorf("Unknown interface type %s, expected cs or me", name)
+}
+
+// newExperiment returns the experiment from options, with defaults from the model description
+func newExperiment(md fmi.ModelDescription, opts options) (experiment, error) {
+	exp := experiment{}
+	def := fmi.Experiment{}
+	if md.DefaultExperiment != nil {
+		def = *md.DefaultExperiment
+	}
+	exp.tolerance = def.Tolerance
+
+	switch {
+	case opts.start != nil:
+		exp.start = *opts.start
+	case def.StartTime != nil:
+		exp.start = *def.StartTime
+	}
+	switch {
+	case opts.stop != nil:
+		exp.stop = *opts.stop
+	case def.StopTime != nil:
+		exp.stop = *def.StopTime
+	default:
+		exp.stop = exp.start + 1
+	}
+	if !(exp.stop > exp.start) {
+		return exp, fmt.Errorf("Stop time %g must be after start time %g", exp.stop, exp.start)
+	}
+	switch {
+	case opts.step != nil:
+		exp.step = *opts.step
+	case def.StepSize != nil:
+		exp.step = *def.StepSize
+	default:
+		exp.step = (exp.stop - exp.start) / 500
+	}
+	if !(exp.step > 0) {
+		return exp, fmt.Errorf("Step size must be positive, got %g", exp.step)
+	}
+	return exp, nil
+}
+
+// coSimulation steps a co-simulation FMU, stopping when it terminates with fmi2Discard
+func coSimulation(fmu *importer.Instance) (instance, stepper) {
+	return fmu, func(time, stepSize float64) (float64, bool, error) {
+		err := fmu.DoStep(time, stepSize, true)
+		if importer.StatusOf(err) == fmi.StatusDiscard {
+			terminated, serr := fmu.GetBooleanStatus(fmi.StatusKindTerminated)
+			if serr == nil && terminated {
+				end, serr := fmu.GetRealStatus(fmi.StatusKindLastSuccessfulTime)
+				if serr != nil {
+					return 0, false, serr
+				}
+				return end, true, nil
+			}
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		return time + stepSize, false, nil
+	}
+}
+
+// modelExchange integrates a model exchange FMU with RK4 in steps of stepSize
+func modelExchange(md fmi.ModelDescription, fmu *importer.Instance, stepSize float64) (instance, stepper) {
+	model := exchangeModel{Instance: fmu, ni: int(md.NumberOfEventIndicators)}
+	if md.ModelStructure.Derivatives != nil {
+		model.nx = len(*md.ModelStructure.Derivatives)
+	}
+	c := solver.NewCoSimulator(model, solver.NewRK4(), stepSize)
+	return c, func(time, stepSize float64) (float64, bool, error) {
+		res, err := c.DoStep(time, stepSize, true)
+		if err != nil {
+			return 0, false, err
+		}
+		if res == fmi.StepResultPartial {
+			return c.LastSuccessfulTime(), true, nil
+		}
+		return time + stepSize, false, nil
+	}
+}
+
+// run initializes the instance and steps it from start to stop, recording every step
+func run(inst instance, step stepper, md fmi.ModelDescription, exp experiment,
+	sets assignments, in *inputs, rec *recorder.Recorder) error {
+	if err := setStartValues(inst, md, sets); err != nil {
+		return err
+	}
+	tolerance := 0.0
+	if exp.tolerance != nil {
+		tolerance = *exp.tolerance
+	}
+	if err := inst.SetupExperiment(exp.tolerance != nil, tolerance, exp.start, true, exp.stop); err != nil {
+		return err
+	}
+	if err := inst.EnterInitializationMode(); err != nil {
+		return err
+	}
+	if err := in.apply(inst, exp.start); err != nil {
+		return err
+	}
+	if err := inst.ExitInitializationMode(); err != nil {
+		return err
+	}
+	if err := rec.Sample(exp.start, inst); err != nil {
+		return err
+	}
+
+	// communication points are multiples of the step size from the start, so errors do not accumulate
+	epsilon := exp.step * 1e-9
+	for n := 0; ; n++ {
+		time := exp.start + float64(n)*exp.step
+		if time >= exp.stop-epsilon {
+			break
+		}
+		if err := in.apply(inst, time); err != nil {
+			return err
+		}
+		end, terminated, err := step(time, math.Min(exp.step, exp.stop-time))
+		if err != nil {
+			return fmt.Errorf("Error simulating step at time %g: %w", time, err)
+		}
+		if err := rec.Sample(end, inst); err != nil {
+			return err
+		}
+		if terminated {
+			break
+		}
+	}
+	return inst.Terminate()
+}
+
+// setStartValues sets the values of -set flags by variable name
+func setStartValues(setter fmi.ValueSetter, md fmi.ModelDescription, sets assignments) error {
+	for _, s := range sets {
+		sv, ok := variable(md, s.name)
+		if !ok {
+			return fmt.Errorf("Unknown variable %s", s.name)
+		}
+		vr := fmi.ValueReference{sv.ValueReference}
+		var err error
+		switch t := sv.Type(); t {
+		case fmi.VariableTypeReal:
+			var f float64
+			if f, err = strconv.ParseFloat(s.value, 64); err == nil {
+				err = setter.SetReal(vr, []float64{f})
+			}
+		case fmi.VariableTypeInteger, fmi.VariableTypeEnumeration:
+			var i int64
+			if i, err = strconv.ParseInt(s.value, 10, 32); err == nil {
+				err = setter.SetInteger(vr, []int32{int32(i)})
+			}
+		case fmi.VariableTypeBoolean:
+			var b bool
+			if b, err = strconv.ParseBool(s.value); err == nil {
+				err = setter.SetBoolean(vr, []bool{b})
+			}
+		case fmi.VariableTypeString:
+			err = setter.SetString(vr, []string{s.value})
+		default:
+			err = fmt.Errorf("Unsupported type %v", t)
+		}
+		if err != nil {
+			return fmt.Errorf("Error setting %s to %s: %w", s.name, s.value, err)
+		}
+	}
+	return nil
+}
+
+func variable(md fmi.ModelDescription, name string) (fmi.ScalarVariable, bool) {
+	for _, sv := range md.ModelVariables {
+		if sv.Name == name && sv.ScalarVariableType != nil {
+			return sv, true
+		}
+	}
+	return fmi.ScalarVariable{}, false
+}
+
+// logger prints messages logged by the FMU to stderr
+func logger(status fmi.Status, category, message string) {
+	fmt.Fprintf(os.Stderr, "[%s] %s: %s\n", status, category, message)
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// bouncingBall is the BouncingBall example built as an FMU in TestMain
+var bouncingBall string
+
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "fmusim")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	bouncingBall = filepath.Join(dir, "BouncingBall.fmu")
+	cmd := exec.Command("go", "run", "../fmubuild", "-o", bouncingBall, "../../examples/BouncingBall")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error building BouncingBall.fmu:", err)
+		return 1
+	}
+	return m.Run()
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+// readOutput reads the output CSV header and values
+func readOutput(t *testing.T, name string) ([]string, [][]float64) {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Error opening output: %v", err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("Error reading output: %v", err)
+	}
+	var rows [][]float64
+	for _, r := range records[1:] {
+		row := make([]float64, len(r))
+		for i, s := range r {
+			if row[i], err = strconv.ParseFloat(s, 64); err != nil {
+				t.Fatalf("Invalid output value %s: %v", s, err)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return records[0], rows
+}
+
+func Test_simulate(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       options
+		wantHeader []string
+		// wantFirst is the first row and wantTime the last time
+		wantFirst []float64
+		wantTime  float64
+		wantRows  int
+	}{
+		{
+			"Co-simulation default experiment",
+			options{interval: 0.5},
+			[]string{"time", "h", "v"},
+			[]float64{0, 1, 0}, 3, 7,
+		},
+		{
+			"Model exchange",
+			options{fmuType: "me", stop: float(1), step: float(0.01), interval: 0.1},
+			[]string{"time", "h", "v"},
+			[]float64{0, 1, 0}, 1, 11,
+		},
+		{
+			"Set start values",
+			options{fmuType: "cs", start: float(1), stop: float(2), step: float(0.25), sets: assignments{{"h", "2"}, {"v", "-1"}}},
+			[]string{"time", "h", "v"},
+			[]float64{1, 2, -1}, 2, 5,
+		},
+		{
+			"Record variables",
+			options{stop: float(0.1), step: float(0.1), record: []string{"e", "g"}, sets: assignments{{"e", "0.5"}}},
+			[]string{"time", "g", "e"},
+			[]float64{0, -9.81, 0.5}, 0.1, 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.fmu = bouncingBall
+			tt.opts.output = filepath.Join(t.TempDir(), "out.csv")
+			if err := simulate(tt.opts); err != nil {
+				t.Fatalf("simulate() error = %v", err)
+			}
+			header, rows := readOutput(t, tt.opts.output)
+			if diff := cmp.Diff(tt.wantHeader, header); diff != "" {
+				t.Errorf("Header mismatch (-want +got):\n%s", diff)
+			}
+			if len(rows) != tt.wantRows {
+				t.Fatalf("Output has %d rows, want %d", len(rows), tt.wantRows)
+			}
+			if diff := cmp.Diff(tt.wantFirst, rows[0]); diff != "" {
+				t.Errorf("First row mismatch (-want +got):\n%s", diff)
+			}
+			if last := rows[len(rows)-1][0]; math.Abs(last-tt.wantTime) > 1e-9 {
+				t.Errorf("Last time %g, want %g", last, tt.wantTime)
+			}
+		})
+	}
+}
+
+func Test_simulate_Interfaces(t *testing.T) {
+	// model exchange is integrated with RK4 at the step size, as the co-simulation FMU is
+	var outputs [][][]float64
+	for _, fmuType := range []string{"cs", "me"} {
+		output := filepath.Join(t.TempDir(), fmuType+".csv")
+		opts := options{fmu: bouncingBall, output: output, fmuType: fmuType, stop: float(1), step: float(0.1)}
+		if err := simulate(opts); err != nil {
+			t.Fatalf("simulate() %s error = %v", fmuType, err)
+		}
+		_, rows := readOutput(t, output)
+		outputs = append(outputs, rows)
+	}
+	if len(outputs[0]) != len(outputs[1]) {
+		t.Fatalf("Co-simulation has %d rows, model exchange %d", len(outputs[0]), len(outputs[1]))
+	}
+	for i := range outputs[0] {
+		for j := range outputs[0][i] {
+			if math.Abs(outputs[0][i][j]-outputs[1][i][j]) > 1e-6 {
+				t.Fatalf("Row %d co-simulation %v, model exchange %v", i, outputs[0][i], outputs[1][i])
+			}
+		}
+	}
+}
+
+func Test_simulate_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts options
+	}{
+		{"Missing FMU", options{fmu: "missing.fmu"}},
+		{"Unknown interface", options{fmuType: "xx"}},
+		{"Stop before start", options{start: float(2), stop: float(1)}},
+		{"Zero step", options{step: float(0)}},
+		{"Unknown variable", options{sets: assignments{{"x", "1"}}}},
+		{"Invalid value", options{sets: assignments{{"h", "high"}}}},
+		{"Missing input file", options{input: "missing.csv"}},
+		{"Unknown recorded variable", options{record: []string{"x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.fmu == "" {
+				tt.opts.fmu = bouncingBall
+			}
+			tt.opts.output = filepath.Join(t.TempDir(), "out.csv")
+			if err := simulate(tt.opts); err == nil {
+				t.Errorf("simulate() expected error")
+			}
+			if _, err := os.Stat(tt.opts.output); err == nil {
+				t.Errorf("Output written after error")
+			}
+		})
+	}
+}
+
+func Test_assignments_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    assignments
+		wantErr bool
+	}{
+		{"Single", []string{"h=2"}, assignments{{"h", "2"}}, false},
+		{"Repeated", []string{"h=2", "der(h)=1=2"}, assignments{{"h", "2"}, {"der(h)", "1=2"}}, false},
+		{"Empty value", []string{"s="}, assignments{{"s", ""}}, false},
+		{"Missing value", []string{"h"}, nil, true},
+		{"Missing name", []string{"=2"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a assignments
+			var err error
+			for _, v := range tt.values {
+				if err = a.Set(v); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !cmp.Equal(tt.want, a, cmp.AllowUnexported(assignment{})) {
+				t.Errorf("Set() = %v, want %v", a, tt.want)
+			}
+		})
+	}
+}
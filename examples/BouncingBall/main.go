@@ -5,9 +5,10 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
-	"math"
+	"os"
 
 	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/solver"
 )
 
 const (
@@ -34,30 +35,174 @@ func init() {
 type model struct{}
 
 func (m model) Description() fmi.ModelDescription {
+	shared := fmi.FMUShared{
+		ModelIdentifier:      name,
+		CanGetAndSetFMUstate: true,
+		CanSerializeFMUstate: true,
+	}
 	return fmi.ModelDescription{
-		GUID: guid,
-		Name: name,
+		GUID:                    guid,
+		Name:                    name,
+		Description:             "This model calculates the trajectory, over time, of a ball dropped from a height of 1 m.",
+		NumberOfEventIndicators: 1,
+		ModelExchange: &fmi.ModelExchange{
+			FMUShared: shared,
+		},
+		CoSimulation: &fmi.CoSimulation{
+			FMUShared:                              shared,
+			CanHandleVariableCommunicationStepSize: true,
+		},
+		UnitDefinitions: &[]fmi.Unit{
+			{Name: "m", BaseUnit: &fmi.BaseUnit{M: integer(1)}},
+			{Name: "m/s", BaseUnit: &fmi.BaseUnit{M: integer(1), S: integer(-1)}},
+			{Name: "m/s2", BaseUnit: &fmi.BaseUnit{M: integer(1), S: integer(-2)}},
+		},
+		TypeDefinitions: &[]fmi.SimpleType{
+			realType("Position", "m"),
+			realType("Velocity", "m/s"),
+			realType("Acceleration", "m/s2"),
+		},
+		DefaultExperiment: &fmi.Experiment{
+			StartTime: float(0),
+			StopTime:  float(3),
+			StepSize:  float(fixedSolverStep),
+		},
+		ModelVariables: []fmi.ScalarVariable{
+			{
+				Name:           "h",
+				ValueReference: vr_h,
+				Description:    "Position of the ball",
+				Causality:      causality(fmi.VariableCausalityOutput),
+				Variability:    variability(fmi.VariableVariabilityContinuous),
+				Initial:        initial(fmi.VariableInitialExact),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Position"},
+						Start:        float(1),
+					},
+				},
+			},
+			{
+				Name:           "der(h)",
+				ValueReference: vr_der_h,
+				Description:    "Derivative of h",
+				Causality:      causality(fmi.VariableCausalityLocal),
+				Variability:    variability(fmi.VariableVariabilityContinuous),
+				Initial:        initial(fmi.VariableInitialCalculated),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Velocity"},
+						Derivative:   float(vr_h),
+					},
+				},
+			},
+			{
+				Name:           "v",
+				ValueReference: vr_v,
+				Description:    "Velocity of the ball",
+				Causality:      causality(fmi.VariableCausalityOutput),
+				Variability:    variability(fmi.VariableVariabilityContinuous),
+				Initial:        initial(fmi.VariableInitialExact),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Velocity"},
+						Start:        float(0),
+						Reinit:       true,
+					},
+				},
+			},
+			{
+				Name:           "der(v)",
+				ValueReference: vr_der_v,
+				Description:    "Derivative of v",
+				Causality:      causality(fmi.VariableCausalityLocal),
+				Variability:    variability(fmi.VariableVariabilityContinuous),
+				Initial:        initial(fmi.VariableInitialCalculated),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Acceleration"},
+						Derivative:   float(vr_v),
+					},
+				},
+			},
+			{
+				Name:           "g",
+				ValueReference: vr_g,
+				Description:    "Gravity acting on the ball",
+				Causality:      causality(fmi.VariableCausalityParameter),
+				Variability:    variability(fmi.VariableVariabilityFixed),
+				Initial:        initial(fmi.VariableInitialExact),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Acceleration"},
+						Start:        float(-9.81),
+						Derivative:   float(vr_der_h),
+					},
+				},
+			},
+			{
+				Name:           "e",
+				ValueReference: vr_e,
+				Description:    "Coefficient of restitution",
+				Causality:      causality(fmi.VariableCausalityParameter),
+				Variability:    variability(fmi.VariableVariabilityTunable),
+				Initial:        initial(fmi.VariableInitialExact),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						RealType: fmi.RealType{
+							Min: float(0.5),
+							Max: float(1),
+						},
+						Start: float(0.7),
+					},
+				},
+			},
+			{
+				Name:           "v_min",
+				ValueReference: vr_v_min,
+				Description:    "Velocity below which the ball stops bouncing",
+				Variability:    variability(fmi.VariableVariabilityConstant),
+				ScalarVariableType: &fmi.ScalarVariableType{
+					Real: &fmi.RealVariable{
+						DeclaredType: fmi.DeclaredType{DeclaredType: "Velocity"},
+						Start:        float(v_min),
+					},
+				},
+			},
+		},
+		ModelStructure: fmi.ModelStructure{
+			Outputs: &[]fmi.Unknown{
+				{Index: vr_h},
+				{Index: vr_v},
+			},
+			Derivatives: &[]fmi.Unknown{
+				{Index: vr_der_h},
+				{Index: vr_der_v},
+			},
+			InitialUnknowns: &[]fmi.Unknown{
+				{Index: vr_der_h, Dependencies: fmi.UintAttributeList{vr_v}, DependenciesKind: fmi.StringAttributeList{"constant"}},
+				{Index: vr_der_v, Dependencies: fmi.UintAttributeList{vr_g}, DependenciesKind: fmi.StringAttributeList{"constant"}},
+			},
+		},
 	}
 }
 
+// Instantiate returns the ball wrapped in a co-simulator, which also forwards model exchange calls
 func (m model) Instantiate(l fmi.Logger) (fmi.ModelInstance, error) {
-	return &bouncingBall{
+	b := &bouncingBall{
 		Logger: l,
 		data:   initialState(),
-		z:      make([]float64, 1),
-		prez:   make([]float64, 1),
-	}, nil
+	}
+	return solver.NewCoSimulator(b, solver.NewRK4(), fixedSolverStep), nil
 }
 
 type bouncingBall struct {
 	fmi.Logger
 	*data
+	time                 float64
 	terminateSimulation  bool
 	nextEventTimeDefined bool
 	nextEventTime        float64
-	nSteps               uint
-	z                    []float64
-	prez                 []float64
 }
 
 type data struct {
@@ -95,6 +240,7 @@ func (b bouncingBall) Terminate() error {
 
 func (b *bouncingBall) Reset() error {
 	b.data = initialState()
+	b.time = 0
 	return nil
 }
 
@@ -117,70 +263,55 @@ func (b *bouncingBall) Decode(rs []byte) error {
 	return nil
 }
 
-func (b *bouncingBall) DoStep(
-	currentCommunicationPoint, communicationStepSize float64,
-	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
-
-	time := math.Abs(currentCommunicationPoint)
-	tNext := currentCommunicationPoint + communicationStepSize
-
-	epsilon := (1 + time) * math.Nextafter(0, 1)
-
-	for time+fixedSolverStep < tNext+epsilon {
-
-		x := b.getContinuousStates()
-		dx := b.getDerivatives()
-
-		// forward Euler step
-		for i, d := range dx {
-			x[i] += fixedSolverStep * d
-		}
-
-		b.setContinuousStates(x...)
-
-		stateEvent := false
-
-		b.z = b.getEventIndicators()
+func (b *bouncingBall) EnterEventMode() error {
+	return nil
+}
 
-		// check for zero-crossings
-		for i := range b.z {
-			stateEvent = stateEvent || b.prez[i] < 0 && b.z[i] >= 0
-			stateEvent = stateEvent || b.prez[i] > 0 && b.z[i] <= 0
-		}
+func (b *bouncingBall) NewDiscreteStates() (fmi.EventInfo, error) {
+	changed := b.eventUpdate()
+	if changed {
+		b.Event(fmt.Sprintf("State event at t=%f s.", b.time))
+	}
+	return fmi.EventInfo{
+		TerminateSimulation:             b.terminateSimulation,
+		ValuesOfContinuousStatesChanged: changed,
+		NextEventTimeDefined:            b.nextEventTimeDefined,
+		NextEventTime:                   b.nextEventTime,
+	}, nil
+}
 
-		// remember the current event indicators
-		temp := b.z
-		b.z = b.prez
-		b.prez = temp
+func (b *bouncingBall) EnterContinuousTimeMode() error {
+	return nil
+}
 
-		// check for time event
-		timeEvent := b.nextEventTimeDefined && (time+fixedSolverStep*1e-2) >= b.nextEventTime
+func (b *bouncingBall) CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint bool) (bool, bool, error) {
+	return false, b.terminateSimulation, nil
+}
 
-		// log events
-		if timeEvent {
-			b.Event(fmt.Sprintf("Time event detected at t=%f s.", time))
-		}
-		if stateEvent {
-			b.Event(fmt.Sprintf("State event detected at t=%f s.", time))
-		}
+func (b *bouncingBall) SetTime(time float64) error {
+	b.time = time
+	return nil
+}
 
-		if stateEvent || timeEvent {
-			b.eventUpdate()
+func (b *bouncingBall) SetContinuousStates(x []float64) error {
+	b.setContinuousStates(x...)
+	return nil
+}
 
-			// update previous event indicators
-			b.prez = b.getEventIndicators()
-		}
+func (b *bouncingBall) GetDerivatives() ([]float64, error) {
+	return b.getDerivatives(), nil
+}
 
-		// terminate simulation, if requested by the model in the previous step
-		if b.terminateSimulation {
-			return fmi.StepResultPartial, nil
-		}
+func (b *bouncingBall) GetEventIndicators() ([]float64, error) {
+	return b.getEventIndicators(), nil
+}
 
-		b.nSteps++
-		time = fixedSolverStep * float64(b.nSteps)
-	}
+func (b *bouncingBall) GetContinuousStates() ([]float64, error) {
+	return b.getContinuousStates(), nil
+}
 
-	return fmi.StepResultSuccess, nil
+func (b *bouncingBall) GetNominalsOfContinuousStates() ([]float64, error) {
+	return []float64{1, 1}, nil
 }
 
 func (d *data) GetReal(vrs fmi.ValueReference) ([]float64, error) {
@@ -206,16 +337,26 @@ func (d *data) GetReal(vrs fmi.ValueReference) ([]float64, error) {
 	return fs, nil
 }
 
-func (d *data) GetInteger(fmi.ValueReference) ([]int32, error) {
-	return nil, errors.New("GetInteger not allowed")
+// the model has no Integer, Boolean or String variables, so only zero-length arrays are accepted
+func (d *data) GetInteger(vrs fmi.ValueReference) ([]int32, error) {
+	if len(vrs) > 0 {
+		return nil, fmt.Errorf("Integer value reference %d not recognised", vrs[0])
+	}
+	return []int32{}, nil
 }
 
-func (d *data) GetBoolean(fmi.ValueReference) ([]bool, error) {
-	return nil, errors.New("GetBoolean not allowed")
+func (d *data) GetBoolean(vrs fmi.ValueReference) ([]bool, error) {
+	if len(vrs) > 0 {
+		return nil, fmt.Errorf("Boolean value reference %d not recognised", vrs[0])
+	}
+	return []bool{}, nil
 }
 
-func (d *data) GetString(fmi.ValueReference) ([]string, error) {
-	return nil, errors.New("GetString not allowed")
+func (d *data) GetString(vrs fmi.ValueReference) ([]string, error) {
+	if len(vrs) > 0 {
+		return nil, fmt.Errorf("String value reference %d not recognised", vrs[0])
+	}
+	return []string{}, nil
 }
 
 func (d *data) SetReal(vrs fmi.ValueReference, fs []float64) error {
@@ -238,19 +379,29 @@ func (d *data) SetReal(vrs fmi.ValueReference, fs []float64) error {
 	return nil
 }
 
-func (d *data) SetInteger(fmi.ValueReference, []int32) error {
-	return errors.New("SetInteger not allowed")
+func (d *data) SetInteger(vrs fmi.ValueReference, _ []int32) error {
+	if len(vrs) > 0 {
+		return fmt.Errorf("Integer value reference %d not recognised", vrs[0])
+	}
+	return nil
 }
 
-func (d *data) SetBoolean(fmi.ValueReference, []bool) error {
-	return errors.New("SetBoolean not allowed")
+func (d *data) SetBoolean(vrs fmi.ValueReference, _ []bool) error {
+	if len(vrs) > 0 {
+		return fmt.Errorf("Boolean value reference %d not recognised", vrs[0])
+	}
+	return nil
 }
 
-func (d *data) SetString(fmi.ValueReference, []string) error {
-	return errors.New("SetString not allowed")
+func (d *data) SetString(vrs fmi.ValueReference, _ []string) error {
+	if len(vrs) > 0 {
+		return fmt.Errorf("String value reference %d not recognised", vrs[0])
+	}
+	return nil
 }
 
-func (d *data) eventUpdate() {
+// eventUpdate returns true if the continuous states were changed
+func (d *data) eventUpdate() bool {
 	if d.H <= 0 && d.V < 0 {
 		d.H = 0
 		d.V = -d.V * d.E
@@ -260,7 +411,9 @@ func (d *data) eventUpdate() {
 			d.V = 0
 			d.G = 0
 		}
+		return true
 	}
+	return false
 }
 
 func (d *data) setContinuousStates(x ...float64) {
@@ -284,5 +437,40 @@ func (d *data) getEventIndicators() []float64 {
 	}
 }
 
+func realType(name, unit string) fmi.SimpleType {
+	return fmi.SimpleType{
+		Name: name,
+		Real: &fmi.RealType{
+			TypeDefinition: fmi.TypeDefinition{Quantity: name},
+			Unit:           unit,
+		},
+	}
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func integer(i int) *int {
+	return &i
+}
+
+func causality(c fmi.VariableCausality) *fmi.VariableCausality {
+	return &c
+}
+
+func variability(v fmi.VariableVariability) *fmi.VariableVariability {
+	return &v
+}
+
+func initial(i fmi.VariableInitial) *fmi.VariableInitial {
+	return &i
+}
+
+// main writes modelDescription.xml to stdout, it is not called when built as a shared library
 func main() {
+	if err := fmi.WriteModelDescription(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
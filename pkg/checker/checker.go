@@ -0,0 +1,183 @@
+/*
+Package checker checks that an FMU complies with the FMI 2.0 standard.
+
+Check validates the model description in a .fmu file, then loads its shared library for the running platform and
+runs calling sequences for each interface type it supports: instantiating, simulating, resetting, getting,
+setting and serializing FMU state, calls with zero-length arrays, and illegal call sequences that must return
+fmi2Error. The results are returned as a Report that can be written as text, JSON or JUnit XML.
+
+	report, err := checker.Check("BouncingBall.fmu")
+	...
+	err = report.WriteJUnit(os.Stdout)
+	if report.Failed() {
+		...
+	}
+*/
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// skipError is returned by checks that do not apply to an FMU
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+func skip(reason string) error {
+	return &skipError{reason}
+}
+
+// fmu is an FMU library loaded for one interface type
+type fmu struct {
+	md        fmi.ModelDescription
+	lib       *importer.Library
+	fmuType   fmi.FMUType
+	resources string
+	// canGetAndSetFMUstate and canSerializeFMUstate are the capabilities of the interface type
+	canGetAndSetFMUstate, canSerializeFMUstate bool
+	// log collects messages logged by instances during a check
+	log []string
+}
+
+// instantiate creates an instance with a logger collecting messages in f.log
+func (f *fmu) instantiate(name string) (*importer.Instance, error) {
+	logger := func(status fmi.Status, category, message string) {
+		f.log = append(f.log, fmt.Sprintf("%s [%s] %s: %s", name, status, category, message))
+	}
+	return f.lib.Instantiate(name, f.fmuType, f.md.GUID, f.resources, importer.Callbacks{Logger: logger}, false, true)
+}
+
+// interfaceType is an interface type declared in the model description
+type interfaceType struct {
+	name                                       string
+	fmuType                                    fmi.FMUType
+	modelIdentifier                            string
+	canGetAndSetFMUstate, canSerializeFMUstate bool
+}
+
+func interfaceTypes(md fmi.ModelDescription) []interfaceType {
+	var its []interfaceType
+	if me := md.ModelExchange; me != nil {
+		its = append(its, interfaceType{"ModelExchange", fmi.FMUTypeModelExchange, me.ModelIdentifier,
+			me.CanGetAndSetFMUstate, me.CanSerializeFMUstate})
+	}
+	if cs := md.CoSimulation; cs != nil {
+		its = append(its, interfaceType{"CoSimulation", fmi.FMUTypeCoSimulation, cs.ModelIdentifier,
+			cs.CanGetAndSetFMUstate, cs.CanSerializeFMUstate})
+	}
+	return its
+}
+
+// Check checks the FMU at path. An error is returned if the FMU cannot be read, failed checks are in the Report.
+func Check(path string) (*Report, error) {
+	platform, err := importer.Platform()
+	if err != nil {
+		return nil, err
+	}
+	a, err := importer.OpenArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+	md := a.ModelDescription
+	r := &Report{FMU: path, Platform: platform}
+
+	r.add(result("ModelDescription", "", validate(md), nil))
+	its := interfaceTypes(md)
+	if len(its) == 0 {
+		r.add(result("InterfaceTypes", "", errors.New("Model description must define ModelExchange or CoSimulation"), nil))
+		return r, nil
+	}
+	supported := false
+	for _, p := range a.Platforms() {
+		supported = supported || p == platform
+	}
+	if !supported {
+		r.add(result("Platform", "", fmt.Errorf("FMU has no binaries for %s", platform), nil))
+		return r, nil
+	}
+
+	dir, err := a.ExtractTemp()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	resources, err := importer.ResourceLocation(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range its {
+		checkInterface(r, dir, resources, md, it)
+	}
+	return r, nil
+}
+
+// validate returns the model description validation errors as one error
+func validate(md fmi.ModelDescription) error {
+	errs := md.Validate()
+	if len(errs) == 0 {
+		return nil
+	}
+	ss := make([]string, len(errs))
+	for i, err := range errs {
+		ss[i] = err.Error()
+	}
+	return errors.New(strings.Join(ss, "; "))
+}
+
+// checkInterface loads the library of an interface type and runs every calling sequence check
+func checkInterface(r *Report, dir, resources string, md fmi.ModelDescription, it interfaceType) {
+	binary, err := importer.BinaryPath(dir, it.modelIdentifier)
+	if err == nil {
+		_, err = os.Stat(binary)
+	}
+	var lib *importer.Library
+	if err == nil {
+		lib, err = importer.Load(binary)
+	}
+	r.add(result("Load", it.name, err, nil))
+	if err != nil {
+		return
+	}
+	defer lib.Close()
+
+	f := &fmu{
+		md:                   md,
+		lib:                  lib,
+		fmuType:              it.fmuType,
+		resources:            resources,
+		canGetAndSetFMUstate: it.canGetAndSetFMUstate,
+		canSerializeFMUstate: it.canSerializeFMUstate,
+	}
+	for _, c := range checks {
+		f.log = nil
+		err := c.run(f)
+		r.add(result(c.name, it.name, err, f.log))
+	}
+}
+
+func result(check, interfaceName string, err error, log []string) Result {
+	res := Result{Check: check, Interface: interfaceName, Outcome: OutcomePass}
+	var se *skipError
+	switch {
+	case errors.As(err, &se):
+		res.Outcome = OutcomeSkip
+		res.Message = se.reason
+	case err != nil:
+		res.Outcome = OutcomeFail
+		res.Message = err.Error()
+		res.Log = log
+	}
+	return res
+}
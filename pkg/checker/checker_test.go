@@ -0,0 +1,207 @@
+package checker_test
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tanenbaum/go-fmi/pkg/checker"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// bouncingBall is the BouncingBall example built as an FMU in TestMain
+var bouncingBall string
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "checker")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	bouncingBall = filepath.Join(dir, "BouncingBall.fmu")
+	cmd := exec.Command("go", "run", "../../cmd/fmubuild", "-o", bouncingBall, "../../examples/BouncingBall")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error building BouncingBall.fmu:", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// copyFMU copies the files of the BouncingBall FMU for which keep returns true, with edit applied to the contents
+func copyFMU(t *testing.T, keep func(name string) bool, edit func(name, contents string) string) string {
+	zr, err := zip.OpenReader(bouncingBall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	path := filepath.Join(t.TempDir(), "copy.fmu")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, zf := range zr.File {
+		if !keep(zf.Name) {
+			continue
+		}
+		r, err := zf.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zw.Create(zf.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, edit(zf.Name, string(bs))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func outcomes(r *checker.Report) map[string]checker.Outcome {
+	m := map[string]checker.Outcome{}
+	for _, res := range r.Results {
+		m[res.Name()] = res.Outcome
+	}
+	return m
+}
+
+func TestCheck(t *testing.T) {
+	if _, err := importer.Platform(); err != nil {
+		t.Skip(err)
+	}
+	r, err := checker.Check(bouncingBall)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	want := map[string]checker.Outcome{"ModelDescription": checker.OutcomePass}
+	for _, it := range []string{"ModelExchange", "CoSimulation"} {
+		for _, c := range []string{"Load", "Instantiate", "InstantiateTwice", "Simulate", "Reset",
+			"FMUState", "SerializeFMUState", "ZeroLengthArrays", "IllegalSequences"} {
+			want[it+"/"+c] = checker.OutcomePass
+		}
+	}
+	if diff := cmp.Diff(want, outcomes(r)); diff != "" {
+		t.Errorf("Check() outcomes mismatch (-want +got):\n%s", diff)
+	}
+	if r.Failed() {
+		var b strings.Builder
+		r.WriteText(&b)
+		t.Errorf("Check() failed:\n%s", b.String())
+	}
+}
+
+func TestCheck_Failures(t *testing.T) {
+	platform, err := importer.Platform()
+	if err != nil {
+		t.Skip(err)
+	}
+	all := func(string) bool { return true }
+	same := func(_, contents string) string { return contents }
+	tests := []struct {
+		name       string
+		fmu        func(t *testing.T) string
+		want       map[string]checker.Outcome
+		wantFailed bool
+	}{
+		{
+			"No binaries",
+			func(t *testing.T) string {
+				return copyFMU(t, func(name string) bool { return !strings.HasPrefix(name, "binaries/") }, same)
+			},
+			map[string]checker.Outcome{
+				"ModelDescription": checker.OutcomePass,
+				"Platform":         checker.OutcomeFail,
+			},
+			true,
+		},
+		{
+			"Wrong model identifier",
+			func(t *testing.T) string {
+				return copyFMU(t, all, func(name, contents string) string {
+					if name != "modelDescription.xml" {
+						return contents
+					}
+					return strings.Replace(contents, `<CoSimulation modelIdentifier="BouncingBall"`,
+						`<CoSimulation modelIdentifier="Missing"`, 1)
+				})
+			},
+			map[string]checker.Outcome{
+				"ModelExchange/Simulate": checker.OutcomePass,
+				"CoSimulation/Load":      checker.OutcomeFail,
+			},
+			true,
+		},
+		{
+			"No state capabilities",
+			func(t *testing.T) string {
+				return copyFMU(t, all, func(name, contents string) string {
+					if name != "modelDescription.xml" {
+						return contents
+					}
+					return strings.Replace(contents, `canGetAndSetFMUstate="true"`, "", -1)
+				})
+			},
+			map[string]checker.Outcome{
+				"ModelExchange/FMUState":          checker.OutcomeSkip,
+				"ModelExchange/SerializeFMUState": checker.OutcomeSkip,
+				"CoSimulation/FMUState":           checker.OutcomeSkip,
+				"CoSimulation/SerializeFMUState":  checker.OutcomeSkip,
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := checker.Check(tt.fmu(t))
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if r.Platform != platform {
+				t.Errorf("Platform = %s, want %s", r.Platform, platform)
+			}
+			got := outcomes(r)
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("Check %s outcome = %s, want %s", name, got[name], want)
+				}
+			}
+			if r.Failed() != tt.wantFailed {
+				t.Errorf("Failed() = %v, want %v", r.Failed(), tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestCheck_Errors(t *testing.T) {
+	if _, err := importer.Platform(); err != nil {
+		t.Skip(err)
+	}
+	if _, err := checker.Check(filepath.Join(t.TempDir(), "missing.fmu")); err == nil {
+		t.Errorf("Check() expected error for missing file")
+	}
+}
@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Outcome is the result of a check
+type Outcome string
+
+const (
+	OutcomePass Outcome = "pass"
+	OutcomeFail Outcome = "fail"
+	// OutcomeSkip is used for checks of capabilities the FMU does not declare
+	OutcomeSkip Outcome = "skip"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Check string `json:"check"`
+	// Interface is ModelExchange or CoSimulation for calling sequence checks, empty for checks of the archive
+	Interface string  `json:"interface,omitempty"`
+	Outcome   Outcome `json:"outcome"`
+	Message   string  `json:"message,omitempty"`
+	// Log are the messages logged by the FMU during a failed check
+	Log []string `json:"log,omitempty"`
+}
+
+// Name is the check prefixed with the interface, if any
+func (r Result) Name() string {
+	if r.Interface == "" {
+		return r.Check
+	}
+	return r.Interface + "/" + r.Check
+}
+
+// Report is the result of checking an FMU
+type Report struct {
+	FMU      string   `json:"fmu"`
+	Platform string   `json:"platform"`
+	Results  []Result `json:"results"`
+}
+
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+}
+
+// Failed returns true if any check failed
+func (r *Report) Failed() bool {
+	for _, res := range r.Results {
+		if res.Outcome == OutcomeFail {
+			return true
+		}
+	}
+	return false
+}
+
+// count returns the number of results with outcome
+func (r *Report) count(outcome Outcome) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Outcome == outcome {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteText writes a line for each result and a summary
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "%-4s %s", strings.ToUpper(string(res.Outcome)), res.Name())
+		if res.Message != "" {
+			fmt.Fprintf(&b, ": %s", res.Message)
+		}
+		b.WriteString("\n")
+		for _, l := range res.Log {
+			fmt.Fprintf(&b, "     %s\n", l)
+		}
+	}
+	fmt.Fprintf(&b, "%s: %d passed, %d failed, %d skipped\n",
+		r.FMU, r.count(OutcomePass), r.count(OutcomeFail), r.count(OutcomeSkip))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// WriteJUnit writes the report as a JUnit XML test suite, as read by most CI servers
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:     r.FMU,
+		Tests:    len(r.Results),
+		Failures: r.count(OutcomeFail),
+		Skipped:  r.count(OutcomeSkip),
+	}
+	for _, res := range r.Results {
+		c := junitCase{
+			Name:      res.Check,
+			ClassName: res.Interface,
+			SystemOut: strings.Join(res.Log, "\n"),
+		}
+		if c.ClassName == "" {
+			c.ClassName = "FMU"
+		}
+		switch res.Outcome {
+		case OutcomeFail:
+			c.Failure = &junitMessage{res.Message}
+		case OutcomeSkip:
+			c.Skipped = &junitMessage{res.Message}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,86 @@
+package checker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tanenbaum/go-fmi/pkg/checker"
+)
+
+func report() *checker.Report {
+	return &checker.Report{
+		FMU:      "model.fmu",
+		Platform: "linux64",
+		Results: []checker.Result{
+			{Check: "ModelDescription", Outcome: checker.OutcomePass},
+			{Check: "Reset", Interface: "CoSimulation", Outcome: checker.OutcomeFail,
+				Message: "fmi2Reset returned fmi2Error", Log: []string{"instance [fmi2Error] logAll: failed"}},
+			{Check: "FMUState", Interface: "CoSimulation", Outcome: checker.OutcomeSkip, Message: "canGetAndSetFMUstate is false"},
+		},
+	}
+}
+
+func TestReport_Failed(t *testing.T) {
+	r := report()
+	if !r.Failed() {
+		t.Errorf("Failed() = false, want true")
+	}
+	r.Results = append(r.Results[:1], r.Results[2:]...)
+	if r.Failed() {
+		t.Errorf("Failed() = true without failed results")
+	}
+}
+
+func TestReport_WriteText(t *testing.T) {
+	var b bytes.Buffer
+	if err := report().WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+	want := `PASS ModelDescription
+FAIL CoSimulation/Reset: fmi2Reset returned fmi2Error
+     instance [fmi2Error] logAll: failed
+SKIP CoSimulation/FMUState: canGetAndSetFMUstate is false
+model.fmu: 1 passed, 1 failed, 1 skipped
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteText() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := report().WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	got := &checker.Report{}
+	if err := json.Unmarshal(b.Bytes(), got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if diff := cmp.Diff(report(), got); diff != "" {
+		t.Errorf("WriteJSON() round trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	if err := report().WriteJUnit(&b); err != nil {
+		t.Fatalf("WriteJUnit() error = %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="model.fmu" tests="3" failures="1" skipped="1">
+  <testcase name="ModelDescription" classname="FMU"></testcase>
+  <testcase name="Reset" classname="CoSimulation">
+    <failure message="fmi2Reset returned fmi2Error"></failure>
+    <system-out>instance [fmi2Error] logAll: failed</system-out>
+  </testcase>
+  <testcase name="FMUState" classname="CoSimulation">
+    <skipped message="canGetAndSetFMUstate is false"></skipped>
+  </testcase>
+</testsuite>
+`
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteJUnit() mismatch (-want +got):\n%s", diff)
+	}
+}
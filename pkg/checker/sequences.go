@@ -0,0 +1,362 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// steps is the number of communication or integrator steps taken by simulating checks
+const steps = 10
+
+// checks are run in order for each interface type
+var checks = []struct {
+	name string
+	run  func(f *fmu) error
+}{
+	{"Instantiate", checkInstantiate},
+	{"InstantiateTwice", checkInstantiateTwice},
+	{"Simulate", checkSimulate},
+	{"Reset", checkReset},
+	{"FMUState", checkFMUState},
+	{"SerializeFMUState", checkSerializeFMUState},
+	{"ZeroLengthArrays", checkZeroLengthArrays},
+	{"IllegalSequences", checkIllegalSequences},
+}
+
+// withInstance instantiates the FMU, runs fn and frees the instance
+func withInstance(f *fmu, name string, fn func(inst *importer.Instance) error) error {
+	inst, err := f.instantiate(name)
+	if err != nil {
+		return err
+	}
+	defer inst.FreeInstance()
+	return fn(inst)
+}
+
+func checkInstantiate(f *fmu) error {
+	return withInstance(f, "instance", func(*importer.Instance) error { return nil })
+}
+
+// checkInstantiateTwice simulates two instances at the same time, they must not share values
+func checkInstantiateTwice(f *fmu) error {
+	return withInstance(f, "first", func(first *importer.Instance) error {
+		return withInstance(f, "second", func(second *importer.Instance) error {
+			s1, s2 := newSimulation(f, first), newSimulation(f, second)
+			if err := s1.initialize(); err != nil {
+				return fmt.Errorf("First instance: %w", err)
+			}
+			before, err := reals(f.md, first)
+			if err != nil {
+				return err
+			}
+			if err := s2.initialize(); err != nil {
+				return fmt.Errorf("Second instance: %w", err)
+			}
+			if err := s2.run(steps); err != nil {
+				return fmt.Errorf("Second instance: %w", err)
+			}
+			after, err := reals(f.md, first)
+			if err != nil {
+				return err
+			}
+			if err := equal(before, after); err != nil {
+				return fmt.Errorf("Simulating the second instance changed the first: %w", err)
+			}
+			if err := s1.run(steps); err != nil {
+				return fmt.Errorf("First instance: %w", err)
+			}
+			if err := first.Terminate(); err != nil {
+				return err
+			}
+			return second.Terminate()
+		})
+	})
+}
+
+func checkSimulate(f *fmu) error {
+	return withInstance(f, "instance", func(inst *importer.Instance) error {
+		s := newSimulation(f, inst)
+		if err := s.initialize(); err != nil {
+			return err
+		}
+		if err := s.run(steps); err != nil {
+			return err
+		}
+		return inst.Terminate()
+	})
+}
+
+// checkReset resets during and after a simulation, values after initialization must be the same each time
+func checkReset(f *fmu) error {
+	return withInstance(f, "instance", func(inst *importer.Instance) error {
+		s := newSimulation(f, inst)
+		var initial []float64
+		for i, terminate := range []bool{false, true, true} {
+			if i > 0 {
+				if err := inst.Reset(); err != nil {
+					return err
+				}
+			}
+			if err := s.initialize(); err != nil {
+				return err
+			}
+			values, err := reals(f.md, inst)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				initial = values
+			} else if err := equal(initial, values); err != nil {
+				return fmt.Errorf("Values after Reset differ from the first initialization: %w", err)
+			}
+			if err := s.run(steps); err != nil {
+				return err
+			}
+			if terminate {
+				if err := inst.Terminate(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// checkFMUState restores a state and checks the values and the following steps are the same
+func checkFMUState(f *fmu) error {
+	if !f.canGetAndSetFMUstate {
+		return skip("canGetAndSetFMUstate is false")
+	}
+	return checkState(f, func(inst *importer.Instance, state *importer.FMUState) (*importer.FMUState, error) {
+		return state, nil
+	})
+}
+
+// checkSerializeFMUState restores a serialized and deserialized state
+func checkSerializeFMUState(f *fmu) error {
+	if !f.canGetAndSetFMUstate || !f.canSerializeFMUstate {
+		return skip("canSerializeFMUstate is false")
+	}
+	return checkState(f, func(inst *importer.Instance, state *importer.FMUState) (*importer.FMUState, error) {
+		bs, err := inst.SerializeFMUstate(state)
+		if err != nil {
+			return nil, err
+		}
+		if len(bs) == 0 {
+			return nil, errors.New("Serialized state is empty")
+		}
+		if err := inst.FreeFMUstate(state); err != nil {
+			return nil, err
+		}
+		return inst.DeSerializeFMUstate(bs)
+	})
+}
+
+// checkState gets a state after initialization, converts it with restore and sets it after simulating
+func checkState(f *fmu, restore func(*importer.Instance, *importer.FMUState) (*importer.FMUState, error)) error {
+	return withInstance(f, "instance", func(inst *importer.Instance) error {
+		s := newSimulation(f, inst)
+		if err := s.initialize(); err != nil {
+			return err
+		}
+		if err := s.run(steps / 2); err != nil {
+			return err
+		}
+		// the simulation keeps event indicators and time events, so is saved with the state
+		snapshot := *s
+		saved, err := reals(f.md, inst)
+		if err != nil {
+			return err
+		}
+		state, err := inst.GetFMUstate()
+		if err != nil {
+			return err
+		}
+		if err := s.run(steps / 2); err != nil {
+			return err
+		}
+		end, err := reals(f.md, inst)
+		if err != nil {
+			return err
+		}
+
+		if state, err = restore(inst, state); err != nil {
+			return err
+		}
+		defer inst.FreeFMUstate(state)
+		if err := inst.SetFMUstate(state); err != nil {
+			return err
+		}
+		restored, err := reals(f.md, inst)
+		if err != nil {
+			return err
+		}
+		if err := equal(saved, restored); err != nil {
+			return fmt.Errorf("Values after SetFMUstate differ: %w", err)
+		}
+		*s = snapshot
+		if err := s.run(steps / 2); err != nil {
+			return err
+		}
+		repeated, err := reals(f.md, inst)
+		if err != nil {
+			return err
+		}
+		if err := equal(end, repeated); err != nil {
+			return fmt.Errorf("Steps after SetFMUstate differ: %w", err)
+		}
+		return inst.Terminate()
+	})
+}
+
+// checkZeroLengthArrays gets and sets no values, which must succeed
+func checkZeroLengthArrays(f *fmu) error {
+	return withInstance(f, "instance", func(inst *importer.Instance) error {
+		if err := newSimulation(f, inst).initialize(); err != nil {
+			return err
+		}
+		// discrete variables can only be set in event mode
+		if f.fmuType == fmi.FMUTypeModelExchange {
+			if err := inst.EnterEventMode(); err != nil {
+				return err
+			}
+		}
+		calls := []func() error{
+			func() error { _, err := inst.GetReal(nil); return err },
+			func() error { _, err := inst.GetInteger(nil); return err },
+			func() error { _, err := inst.GetBoolean(nil); return err },
+			func() error { _, err := inst.GetString(nil); return err },
+			func() error { return inst.SetReal(nil, nil) },
+			func() error { return inst.SetInteger(nil, nil) },
+			func() error { return inst.SetBoolean(nil, nil) },
+			func() error { return inst.SetString(nil, nil) },
+		}
+		for _, call := range calls {
+			if err := call(); err != nil {
+				return err
+			}
+		}
+		return inst.Terminate()
+	})
+}
+
+// sequence is an illegal call after a legal preparation
+type sequence struct {
+	name    string
+	fmuType *fmi.FMUType
+	prepare func(s *simulation) error
+	call    func(inst *importer.Instance) error
+}
+
+var (
+	modelExchange = fmi.FMUTypeModelExchange
+	coSimulation  = fmi.FMUTypeCoSimulation
+)
+
+func setup(s *simulation) error {
+	return s.inst.SetupExperiment(false, 0, s.start, false, 0)
+}
+
+func enterInitialization(s *simulation) error {
+	if err := setup(s); err != nil {
+		return err
+	}
+	return s.inst.EnterInitializationMode()
+}
+
+func exitInitialization(s *simulation) error {
+	if err := enterInitialization(s); err != nil {
+		return err
+	}
+	return s.inst.ExitInitializationMode()
+}
+
+func terminate(s *simulation) error {
+	if err := s.initialize(); err != nil {
+		return err
+	}
+	return s.inst.Terminate()
+}
+
+var illegalSequences = []sequence{
+	{"ExitInitializationMode before EnterInitializationMode", nil, setup,
+		func(inst *importer.Instance) error { return inst.ExitInitializationMode() }},
+	{"EnterInitializationMode twice", nil, enterInitialization,
+		func(inst *importer.Instance) error { return inst.EnterInitializationMode() }},
+	{"SetupExperiment after initialization", nil, exitInitialization,
+		func(inst *importer.Instance) error { return inst.SetupExperiment(false, 0, 0, false, 0) }},
+	{"DoStep before initialization", &coSimulation, setup,
+		func(inst *importer.Instance) error { return inst.DoStep(0, 1e-3, true) }},
+	{"DoStep in initialization mode", &coSimulation, enterInitialization,
+		func(inst *importer.Instance) error { return inst.DoStep(0, 1e-3, true) }},
+	{"DoStep after Terminate", &coSimulation, terminate,
+		func(inst *importer.Instance) error { return inst.DoStep(0, 1e-3, true) }},
+	{"NewDiscreteStates before initialization", &modelExchange, setup,
+		func(inst *importer.Instance) error { _, err := inst.NewDiscreteStates(); return err }},
+	{"EnterContinuousTimeMode in initialization mode", &modelExchange, enterInitialization,
+		func(inst *importer.Instance) error { return inst.EnterContinuousTimeMode() }},
+	{"CompletedIntegratorStep in event mode", &modelExchange, exitInitialization,
+		func(inst *importer.Instance) error { _, _, err := inst.CompletedIntegratorStep(true); return err }},
+}
+
+// checkIllegalSequences runs each illegal sequence in a new instance, the illegal call must return fmi2Error
+func checkIllegalSequences(f *fmu) error {
+	var failures []string
+	for _, seq := range illegalSequences {
+		if seq.fmuType != nil && *seq.fmuType != f.fmuType {
+			continue
+		}
+		err := withInstance(f, "instance", func(inst *importer.Instance) error {
+			if err := seq.prepare(newSimulation(f, inst)); err != nil {
+				return fmt.Errorf("Error preparing sequence: %w", err)
+			}
+			if s := importer.StatusOf(seq.call(inst)); s != fmi.StatusError {
+				return fmt.Errorf("returned %s, expected %s", s, fmi.StatusError)
+			}
+			return nil
+		})
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", seq.name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// reals gets all Real variables that are not constant, for comparing states
+func reals(md fmi.ModelDescription, inst *importer.Instance) ([]float64, error) {
+	var vr fmi.ValueReference
+	for _, sv := range md.ModelVariables {
+		if sv.ScalarVariableType == nil || sv.Type() != fmi.VariableTypeReal {
+			continue
+		}
+		if sv.Variability != nil && *sv.Variability == fmi.VariableVariabilityConstant {
+			continue
+		}
+		vr = append(vr, sv.ValueReference)
+	}
+	if len(vr) == 0 {
+		return nil, nil
+	}
+	return inst.GetReal(vr)
+}
+
+// equal compares values exactly, treating NaN as equal to NaN
+func equal(want, got []float64) error {
+	if len(want) != len(got) {
+		return fmt.Errorf("Got %d values, expected %d", len(got), len(want))
+	}
+	for i := range want {
+		if want[i] != got[i] && !(math.IsNaN(want[i]) && math.IsNaN(got[i])) {
+			return fmt.Errorf("Value %d is %g, expected %g", i, got[i], want[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package checker
+
+import (
+	"fmt"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// maxEventIterations limits fmi2NewDiscreteStates calls at one event
+const maxEventIterations = 100
+
+/*
+simulation runs the calling sequence of an interface type from the default experiment. Co-simulation FMUs
+are stepped with fmi2DoStep and model exchange FMUs are integrated with explicit Euler steps, handling
+events signalled by the event indicators, time events and fmi2CompletedIntegratorStep.
+*/
+type simulation struct {
+	f    *fmu
+	inst *importer.Instance
+
+	start, stepSize, time float64
+	tolerance             *float64
+	// nx and ni are the number of continuous states and event indicators
+	nx, ni     int
+	indicators []float64
+	event      fmi.EventInfo
+	terminated bool
+}
+
+func newSimulation(f *fmu, inst *importer.Instance) *simulation {
+	s := &simulation{f: f, inst: inst, ni: int(f.md.NumberOfEventIndicators)}
+	stop := 1.0
+	if exp := f.md.DefaultExperiment; exp != nil {
+		if exp.StartTime != nil {
+			s.start = *exp.StartTime
+		}
+		stop = s.start + 1
+		if exp.StopTime != nil && *exp.StopTime > s.start {
+			stop = *exp.StopTime
+		}
+		s.tolerance = exp.Tolerance
+	}
+	s.stepSize = (stop - s.start) / 100
+	if exp := f.md.DefaultExperiment; exp != nil && exp.StepSize != nil && *exp.StepSize > 0 {
+		s.stepSize = *exp.StepSize
+	}
+	if d := f.md.ModelStructure.Derivatives; d != nil {
+		s.nx = len(*d)
+	}
+	return s
+}
+
+// initialize sets up the experiment and initializes the FMU, model exchange FMUs enter continuous time mode
+func (s *simulation) initialize() error {
+	s.time = s.start
+	s.terminated = false
+	tolerance := 0.0
+	if s.tolerance != nil {
+		tolerance = *s.tolerance
+	}
+	if err := s.inst.SetupExperiment(s.tolerance != nil, tolerance, s.start, false, 0); err != nil {
+		return err
+	}
+	if err := s.inst.EnterInitializationMode(); err != nil {
+		return err
+	}
+	if err := s.inst.ExitInitializationMode(); err != nil {
+		return err
+	}
+	if s.f.fmuType != fmi.FMUTypeModelExchange {
+		return nil
+	}
+	return s.handleEvent()
+}
+
+// run takes n steps, stopping early if the FMU terminates the simulation
+func (s *simulation) run(n int) error {
+	for i := 0; i < n && !s.terminated; i++ {
+		var err error
+		if s.f.fmuType == fmi.FMUTypeModelExchange {
+			err = s.integratorStep()
+		} else {
+			err = s.doStep()
+		}
+		if err != nil {
+			return fmt.Errorf("Error at time %g: %w", s.time, err)
+		}
+	}
+	return nil
+}
+
+func (s *simulation) doStep() error {
+	err := s.inst.DoStep(s.time, s.stepSize, true)
+	if importer.StatusOf(err) == fmi.StatusDiscard {
+		if terminated, serr := s.inst.GetBooleanStatus(fmi.StatusKindTerminated); serr == nil && terminated {
+			s.terminated = true
+			return nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+	s.time += s.stepSize
+	return nil
+}
+
+// integratorStep takes an explicit Euler step and handles events at its end
+func (s *simulation) integratorStep() error {
+	x, err := s.inst.GetContinuousStates(s.nx)
+	if err != nil {
+		return err
+	}
+	dx, err := s.inst.GetDerivatives(s.nx)
+	if err != nil {
+		return err
+	}
+	h := s.stepSize
+	timeEvent := s.event.NextEventTimeDefined && s.event.NextEventTime <= s.time+h
+	if timeEvent {
+		h = s.event.NextEventTime - s.time
+	}
+	s.time += h
+	if err := s.inst.SetTime(s.time); err != nil {
+		return err
+	}
+	for i := range x {
+		x[i] += h * dx[i]
+	}
+	if err := s.inst.SetContinuousStates(x); err != nil {
+		return err
+	}
+	z, err := s.inst.GetEventIndicators(s.ni)
+	if err != nil {
+		return err
+	}
+	stateEvent := false
+	for i := range z {
+		stateEvent = stateEvent || (s.indicators[i] > 0) != (z[i] > 0)
+	}
+	enterEventMode, terminate, err := s.inst.CompletedIntegratorStep(true)
+	if err != nil {
+		return err
+	}
+	if terminate {
+		s.terminated = true
+		return nil
+	}
+	if !enterEventMode && !stateEvent && !timeEvent {
+		s.indicators = z
+		return nil
+	}
+	if err := s.inst.EnterEventMode(); err != nil {
+		return err
+	}
+	return s.handleEvent()
+}
+
+// handleEvent iterates fmi2NewDiscreteStates in event mode, then enters continuous time mode
+func (s *simulation) handleEvent() error {
+	for i := 0; ; i++ {
+		if i == maxEventIterations {
+			return fmt.Errorf("Event iteration did not converge after %d iterations", maxEventIterations)
+		}
+		info, err := s.inst.NewDiscreteStates()
+		if err != nil {
+			return err
+		}
+		s.event = info
+		if info.TerminateSimulation {
+			s.terminated = true
+			return nil
+		}
+		if !info.NewDiscreteStatesNeeded {
+			break
+		}
+	}
+	if err := s.inst.EnterContinuousTimeMode(); err != nil {
+		return err
+	}
+	z, err := s.inst.GetEventIndicators(s.ni)
+	if err != nil {
+		return err
+	}
+	s.indicators = z
+	return nil
+}
@@ -0,0 +1,60 @@
+package fmi
+
+// #include <stdlib.h>
+// #include "./c/fmi2Functions.h"
+// #include "bridge.h"
+import "C"
+
+import "unsafe"
+
+/*
+allocator allocates the memory that is handed to the environment: the component,
+FMU states and strings returned by fmi2GetString.
+The environment can track memory through the allocateMemory and freeMemory callbacks
+given to fmi2Instantiate, as the model description does not set canNotUseMemoryManagementFunctions.
+*/
+type allocator interface {
+	// alloc returns size bytes of zeroed memory, or nil if no memory is available
+	alloc(size int) unsafe.Pointer
+	// free releases memory returned by alloc, nil is ignored
+	free(p unsafe.Pointer)
+}
+
+// cAllocator allocates with the C standard library, if the environment has no memory callbacks
+type cAllocator struct{}
+
+func (cAllocator) alloc(size int) unsafe.Pointer {
+	return C.calloc(1, C.size_t(size))
+}
+
+func (cAllocator) free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+// hostAllocator allocates with the fmi2CallbackFunctions of the environment
+type hostAllocator struct {
+	allocateMemory C.fmi2CallbackAllocateMemory
+	freeMemory     C.fmi2CallbackFreeMemory
+}
+
+func (h hostAllocator) alloc(size int) unsafe.Pointer {
+	return C.bridge_fmi2CallbackAllocateMemory(h.allocateMemory, 1, C.size_t(size))
+}
+
+func (h hostAllocator) free(p unsafe.Pointer) {
+	if p == nil {
+		return
+	}
+	C.bridge_fmi2CallbackFreeMemory(h.freeMemory, p)
+}
+
+// newAllocator returns the allocator for the callbacks, the C allocator is used unless both are set
+func newAllocator(functions C.fmi2CallbackFunctions_t) allocator {
+	if functions == nil || functions.allocateMemory == nil || functions.freeMemory == nil {
+		return cAllocator{}
+	}
+	return hostAllocator{
+		allocateMemory: functions.allocateMemory,
+		freeMemory:     functions.freeMemory,
+	}
+}
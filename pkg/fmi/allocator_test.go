@@ -0,0 +1,45 @@
+package fmi
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func Test_newAllocator(t *testing.T) {
+	if got := newAllocator(nil); got != (cAllocator{}) {
+		t.Errorf("newAllocator(nil) = %#v, want cAllocator", got)
+	}
+}
+
+func Test_cAllocator(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{
+			"Single byte",
+			1,
+		},
+		{
+			"Buffer",
+			1024,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a cAllocator
+			p := a.alloc(tt.size)
+			if p == nil {
+				t.Fatalf("alloc(%d) = nil", tt.size)
+			}
+			defer a.free(p)
+			var bs []byte
+			carrayToSlice(p, unsafe.Pointer(&bs), tt.size)
+			for i, b := range bs {
+				if b != 0 {
+					t.Fatalf("alloc(%d)[%d] = %d, want zeroed memory", tt.size, i, b)
+				}
+			}
+		})
+	}
+}
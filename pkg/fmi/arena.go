@@ -0,0 +1,89 @@
+package fmi
+
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+)
+
+// minStringCapacity is the smallest buffer allocated for a string, to avoid reallocating for small changes
+const minStringCapacity = 16
+
+/*
+stringArena owns the C strings returned to the environment by fmi2GetString.
+The standard only requires the strings to be valid until the next call on the instance,
+so the buffers are reused by the next fmi2GetString and are freed in fmi2FreeInstance.
+*/
+type stringArena struct {
+	memory allocator
+	// mu guards the buffers as fmi2GetString copies the strings after the call to the instance returned
+	mu   sync.Mutex
+	bufs []*C.char
+	caps []int
+	// freed is set once the buffers are released by FreeInstance
+	freed bool
+}
+
+// strings copies vs into the arena buffers and returns C strings for them, valid until the next call
+func (a *stringArena) strings(vs []string) ([]*C.char, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.freed {
+		return nil, errors.New("String buffers of the FMU have been freed")
+	}
+
+	for len(a.bufs) < len(vs) {
+		a.bufs = append(a.bufs, nil)
+		a.caps = append(a.caps, 0)
+	}
+	for i, v := range vs {
+		n := len(v) + 1
+		if a.caps[i] < n {
+			c := 2 * a.caps[i]
+			if c < minStringCapacity {
+				c = minStringCapacity
+			}
+			if c < n {
+				c = n
+			}
+			// the previous string is overwritten so the buffer is not copied
+			p := a.memory.alloc(c)
+			if p == nil {
+				return nil, errors.New("Out of memory allocating strings")
+			}
+			a.memory.free(unsafe.Pointer(a.bufs[i]))
+			a.bufs[i] = (*C.char)(p)
+			a.caps[i] = c
+		}
+		var bs []byte
+		carrayToSlice(unsafe.Pointer(a.bufs[i]), unsafe.Pointer(&bs), n)
+		copy(bs, v)
+		bs[len(v)] = 0
+	}
+	return a.bufs[:len(vs)], nil
+}
+
+// allocated returns the number of bytes held by the arena
+func (a *stringArena) allocated() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, c := range a.caps {
+		n += c
+	}
+	return n
+}
+
+// free releases the buffers, strings cannot be returned afterwards
+func (a *stringArena) free() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, b := range a.bufs {
+		a.memory.free(unsafe.Pointer(b))
+	}
+	a.bufs = nil
+	a.caps = nil
+	a.freed = true
+}
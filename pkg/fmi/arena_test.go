@@ -0,0 +1,131 @@
+package fmi
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+// cBytes returns n bytes of C memory at p
+func cBytes(p unsafe.Pointer, n int) string {
+	var bs []byte
+	carrayToSlice(p, unsafe.Pointer(&bs), n)
+	return string(bs)
+}
+
+func Test_stringArena_strings(t *testing.T) {
+	tests := []struct {
+		name string
+		// calls are the values of successive fmi2GetString calls
+		calls [][]string
+		// reused are the indexes of strings that reuse the buffer of the previous call
+		reused []int
+		want   int
+	}{
+		{
+			"No strings",
+			[][]string{{}},
+			nil,
+			0,
+		},
+		{
+			"Strings are copied",
+			[][]string{{"a", "", "bc"}},
+			nil,
+			3 * minStringCapacity,
+		},
+		{
+			"Buffers are reused",
+			[][]string{{"abc", "d"}, {"e", "fgh"}},
+			[]int{0, 1},
+			2 * minStringCapacity,
+		},
+		{
+			"Buffer grows for longer string",
+			[][]string{{"a", "b"}, {strings.Repeat("x", 20), "c"}},
+			[]int{1},
+			3 * minStringCapacity,
+		},
+		{
+			"Fewer strings keep buffers",
+			[][]string{{"a", "b", "c"}, {"d"}},
+			[]int{0},
+			3 * minStringCapacity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := stringArena{memory: cAllocator{}}
+			defer a.free()
+			var prev []unsafe.Pointer
+			for _, vs := range tt.calls {
+				cs, err := a.strings(vs)
+				if err != nil {
+					t.Fatalf("strings() error = %v", err)
+				}
+				if len(cs) != len(vs) {
+					t.Fatalf("strings() returned %d strings, want %d", len(cs), len(vs))
+				}
+				for i, v := range vs {
+					if got := cBytes(unsafe.Pointer(cs[i]), len(v)+1); got != v+"\x00" {
+						t.Errorf("strings()[%d] = %q, want %q", i, got, v+"\x00")
+					}
+				}
+				if prev != nil {
+					for _, i := range tt.reused {
+						if unsafe.Pointer(cs[i]) != prev[i] {
+							t.Errorf("strings()[%d] did not reuse the buffer", i)
+						}
+					}
+				}
+				prev = make([]unsafe.Pointer, len(cs))
+				for i, c := range cs {
+					prev[i] = unsafe.Pointer(c)
+				}
+			}
+			if got := a.allocated(); got != tt.want {
+				t.Errorf("allocated() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_stringArena_leak(t *testing.T) {
+	const (
+		calls      = 10000
+		maxStrings = 20
+		maxLength  = 100
+	)
+	// buffers at most double so a buffer is less than twice the longest string
+	const limit = maxStrings * 2 * (maxLength + 1)
+
+	a := stringArena{memory: cAllocator{}}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < calls; i++ {
+		vs := make([]string, r.Intn(maxStrings+1))
+		for j := range vs {
+			vs[j] = strings.Repeat("s", r.Intn(maxLength+1))
+		}
+		cs, err := a.strings(vs)
+		if err != nil {
+			t.Fatalf("strings() error = %v", err)
+		}
+		for j, v := range vs {
+			if got := cBytes(unsafe.Pointer(cs[j]), len(v)+1); got != v+"\x00" {
+				t.Fatalf("Call %d strings()[%d] = %q, want %q", i, j, got, v+"\x00")
+			}
+		}
+		if n := a.allocated(); n > limit {
+			t.Fatalf("Call %d allocated() = %d, want at most %d", i, n, limit)
+		}
+	}
+
+	a.free()
+	if n := a.allocated(); n != 0 {
+		t.Errorf("allocated() after free = %d, want 0", n)
+	}
+	if _, err := a.strings([]string{"a"}); err == nil {
+		t.Errorf("strings() expected error after free")
+	}
+}
@@ -0,0 +1,368 @@
+package fmi
+
+// #include <stdlib.h>
+// #include "./c/fmi2Functions.h"
+// #include "bridge.h"
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const pendingStatusInProgress = "DoStep is in progress"
+
+var (
+	statusKindNames = [...]string{"fmi2DoStepStatus", "fmi2PendingStatus", "fmi2LastSuccessfulTime", "fmi2Terminated"}
+
+	// statusStrings caches C strings returned by fmi2GetStringStatus.
+	// Only a small fixed set of strings is ever returned so these are never freed.
+	statusStrings   = map[string]C.fmi2String{}
+	statusStringsMu sync.Mutex
+)
+
+// stepStatus records the outcome of the last DoStep for the fmi2GetXXXStatus functions
+type stepStatus struct {
+	status             Status
+	lastSuccessfulTime float64
+	terminated         bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (k StatusKind) String() string {
+	if int(k) < len(statusKindNames) {
+		return statusKindNames[k]
+	}
+	return "unknown"
+}
+
+// completeStep records the step result, end is the communication point the step was computing to
+func (f *FMU) completeStep(cosim CoSimulator, end float64, s Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recordStep(cosim, end, s)
+}
+
+// recordStep records the step result for the status queries, f.mu must be held
+func (f *FMU) recordStep(cosim CoSimulator, end float64, s Status) {
+	f.step.status = s
+	if s == StatusOK || s == StatusWarning {
+		f.step.lastSuccessfulTime = end
+	}
+	if r, ok := cosim.(StepStatusReporter); ok {
+		f.step.lastSuccessfulTime = r.LastSuccessfulTime()
+		f.step.terminated = r.Terminated()
+	}
+}
+
+// doStepAsync runs DoStep in a goroutine and moves the FMU to the pending state of DoStep.
+// The FMU moves to the state for the step status, and the stepFinished callback is called,
+// once the step completes unless it was cancelled.
+func (f *FMU) doStepAsync(cosim CoSimulator, currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) Status {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	f.mu.Lock()
+	f.State = f.nextState("DoStep", StatusPending)
+	f.step.status = StatusPending
+	f.step.cancel = cancel
+	f.step.done = done
+	f.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		// the step runs outside of any fmi2 call so a panic is recovered here
+		defer func() {
+			if r := recover(); r != nil {
+				f.mu.Lock()
+				cancelled := f.State == ModelStateStepCanceled
+				if !cancelled {
+					f.State = f.nextState("DoStep", StatusFatal)
+				}
+				f.mu.Unlock()
+				if cancelled {
+					// the instance stays cancelled, a panic after cancellation only ends the step
+					f.logger.Error(panicError("DoStep", r))
+					return
+				}
+				f.logger.Fatal(panicError("DoStep", r))
+				if f.stepFinished != nil {
+					f.stepFinished(StatusFatal)
+				}
+			}
+		}()
+
+		var res StepResult
+		var err error
+		if async, ok := cosim.(AsyncCoSimulator); ok {
+			res, err = async.DoStepContext(ctx,
+				currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint)
+		} else {
+			res, err = cosim.DoStep(
+				currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint)
+		}
+
+		s := res.Status()
+		if err != nil {
+			s = f.modelStatus("DoStep", err)
+		}
+
+		f.mu.Lock()
+		// the result of a cancelled step is not recorded
+		cancelled := f.State == ModelStateStepCanceled
+		if !cancelled {
+			f.recordStep(cosim, currentCommunicationPoint+communicationStepSize, s)
+			f.State = f.nextState("DoStep", s)
+		}
+		f.mu.Unlock()
+
+		if !cancelled && f.stepFinished != nil {
+			f.stepFinished(s)
+		}
+	}()
+
+	f.logger.Event(fmt.Sprintf("DoStep started asynchronously at t=%f", currentCommunicationPoint))
+	return StatusPending
+}
+
+//export fmi2CancelStep
+func fmi2CancelStep(c C.fmi2Component) C.fmi2Status {
+	return C.fmi2Status(CancelStep(FMUID(c)))
+}
+
+/*
+CancelStep can be called if DoStep returned fmi2Pending in order to stop the current
+asynchronous execution. The master calls this function if, for example, the co-simulation
+run is stopped by the user or one of the slaves. Afterwards it is only allowed to call the
+functions Terminate, Reset or FreeInstance.
+Cancellation is passed to the model through the context given to AsyncCoSimulator.DoStepContext.
+CancelStep waits for the model to return from the step, whose result is not recorded:
+GetStatus returns fmi2Error for the step and the other status queries return the
+last successful time and termination of the last completed step.
+*/
+func CancelStep(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "CancelStep")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	if !fmu.cancelStep() {
+		fmu.logger.Error(errors.New("CancelStep called but step has already finished"))
+		return StatusError
+	}
+	fmu.logger.Event("DoStep was cancelled")
+	return StatusOK
+}
+
+// cancelStep cancels the asynchronous step in progress, if any, and waits for the model to return from it.
+// It returns false if no step was in progress.
+func (f *FMU) cancelStep() bool {
+	f.mu.Lock()
+	if f.State != ModelStateStepInProgress {
+		f.mu.Unlock()
+		return false
+	}
+	// the step is not recorded once cancelled, the status queries return
+	// StatusError for DoStep and the time and termination of the last recorded step
+	f.State = f.nextState("CancelStep", StatusOK)
+	f.step.status = StatusError
+	cancel, done := f.step.cancel, f.step.done
+	f.mu.Unlock()
+
+	// the state can be in progress without a step when it is set directly
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+	return true
+}
+
+// waitStep waits for the goroutine of the last asynchronous step, which may still be calling stepFinished
+func (f *FMU) waitStep() {
+	f.mu.Lock()
+	done := f.step.done
+	f.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+//export fmi2GetStatus
+func fmi2GetStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Status) C.fmi2Status {
+	if value == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "value"))
+	}
+	v, status := GetStatus(FMUID(c), StatusKind(s))
+	if status != StatusOK {
+		return C.fmi2Status(status)
+	}
+	*value = C.fmi2Status(v)
+	return C.fmi2OK
+}
+
+/*
+GetStatus informs the master about the actual status of the simulation run.
+Only StatusKindDoStep is supported, which returns the status of the last DoStep call,
+StatusPending if an asynchronous step is still running or StatusError if it was cancelled.
+*/
+func GetStatus(id FMUID, kind StatusKind) (_ Status, status Status) {
+	fmu, s := allowedStatus(id, "GetStatus", kind, StatusKindDoStep)
+	if s != StatusOK {
+		return StatusOK, s
+	}
+	defer fmu.release(&status)
+
+	fmu.mu.Lock()
+	defer fmu.mu.Unlock()
+	return fmu.step.status, StatusOK
+}
+
+//export fmi2GetRealStatus
+func fmi2GetRealStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Real) C.fmi2Status {
+	if value == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "value"))
+	}
+	v, status := GetRealStatus(FMUID(c), StatusKind(s))
+	if status != StatusOK {
+		return C.fmi2Status(status)
+	}
+	*value = C.fmi2Real(v)
+	return C.fmi2OK
+}
+
+/*
+GetRealStatus supports StatusKindLastSuccessfulTime, which returns the end time of the
+last successfully completed communication step. If the last step was discarded this is
+the time up to which the slave computed successfully.
+*/
+func GetRealStatus(id FMUID, kind StatusKind) (_ float64, status Status) {
+	fmu, s := allowedStatus(id, "GetRealStatus", kind, StatusKindLastSuccessfulTime)
+	if s != StatusOK {
+		return 0, s
+	}
+	defer fmu.release(&status)
+
+	fmu.mu.Lock()
+	defer fmu.mu.Unlock()
+	return fmu.step.lastSuccessfulTime, StatusOK
+}
+
+//export fmi2GetIntegerStatus
+func fmi2GetIntegerStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Integer) C.fmi2Status {
+	if value == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "value"))
+	}
+	v, status := GetIntegerStatus(FMUID(c), StatusKind(s))
+	if status != StatusOK {
+		return C.fmi2Status(status)
+	}
+	*value = C.fmi2Integer(v)
+	return C.fmi2OK
+}
+
+/*
+GetIntegerStatus is part of the FMI interface but no integer status kinds are defined
+by FMI 2.0, so fmi2Discard is always returned for a valid FMU.
+*/
+func GetIntegerStatus(id FMUID, kind StatusKind) (int32, Status) {
+	_, s := allowedStatus(id, "GetIntegerStatus", kind)
+	return 0, s
+}
+
+//export fmi2GetBooleanStatus
+func fmi2GetBooleanStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Boolean) C.fmi2Status {
+	if value == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "value"))
+	}
+	v, status := GetBooleanStatus(FMUID(c), StatusKind(s))
+	if status != StatusOK {
+		return C.fmi2Status(status)
+	}
+	*value = boolFMU(v)
+	return C.fmi2OK
+}
+
+/*
+GetBooleanStatus supports StatusKindTerminated, which returns true if the slave wants to
+terminate the simulation. It can be called after DoStep returned fmi2Discard.
+*/
+func GetBooleanStatus(id FMUID, kind StatusKind) (_ bool, status Status) {
+	fmu, s := allowedStatus(id, "GetBooleanStatus", kind, StatusKindTerminated)
+	if s != StatusOK {
+		return false, s
+	}
+	defer fmu.release(&status)
+
+	fmu.mu.Lock()
+	defer fmu.mu.Unlock()
+	return fmu.step.terminated, StatusOK
+}
+
+//export fmi2GetStringStatus
+func fmi2GetStringStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2String) C.fmi2Status {
+	if value == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "value"))
+	}
+	v, status := GetStringStatus(FMUID(c), StatusKind(s))
+	if status != StatusOK {
+		return C.fmi2Status(status)
+	}
+	*value = statusString(v)
+	return C.fmi2OK
+}
+
+/*
+GetStringStatus supports StatusKindPending, which returns a description of the
+asynchronous step in progress. An empty string is returned if no step is pending.
+*/
+func GetStringStatus(id FMUID, kind StatusKind) (_ string, status Status) {
+	fmu, s := allowedStatus(id, "GetStringStatus", kind, StatusKindPending)
+	if s != StatusOK {
+		return "", s
+	}
+	defer fmu.release(&status)
+
+	fmu.mu.Lock()
+	defer fmu.mu.Unlock()
+	if fmu.State == ModelStateStepInProgress {
+		return pendingStatusInProgress, StatusOK
+	}
+	return "", StatusOK
+}
+
+// allowedStatus returns StatusDiscard if kind is not one of supported.
+// The FMU is only returned, and must be released, if the status is StatusOK.
+func allowedStatus(id FMUID, name string, kind StatusKind, supported ...StatusKind) (*FMU, Status) {
+	fmu, ok := allowedState(id, name)
+	if !ok {
+		return nil, StatusError
+	}
+
+	for _, k := range supported {
+		if k == kind {
+			return fmu, StatusOK
+		}
+	}
+	fmu.logger.Discard(fmt.Sprintf("%s does not support status kind %v", name, kind))
+	fmu.calls.Unlock()
+	return nil, StatusDiscard
+}
+
+func statusString(s string) C.fmi2String {
+	statusStringsMu.Lock()
+	defer statusStringsMu.Unlock()
+
+	cs, ok := statusStrings[s]
+	if !ok {
+		cs = C.CString(s)
+		statusStrings[s] = cs
+	}
+	return cs
+}
@@ -0,0 +1,328 @@
+package fmi_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// mockAsyncInstance blocks in DoStepContext until the step is cancelled
+type mockAsyncInstance struct {
+	mockInstance
+}
+
+func (m mockAsyncInstance) DoStepContext(ctx context.Context,
+	currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
+	<-ctx.Done()
+	return fmi.StepResultPartial, nil
+}
+
+// returnAsyncInstance blocks in DoStepContext until the step is cancelled and reports when it returns
+type returnAsyncInstance struct {
+	mockInstance
+	returned chan struct{}
+}
+
+func (r *returnAsyncInstance) DoStepContext(ctx context.Context,
+	currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
+	<-ctx.Done()
+	r.returned <- struct{}{}
+	return fmi.StepResultPartial, nil
+}
+
+// panicAsyncInstance blocks in DoStepContext until the step is cancelled and then panics
+type panicAsyncInstance struct {
+	mockInstance
+}
+
+func (p panicAsyncInstance) DoStepContext(ctx context.Context,
+	currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
+	<-ctx.Done()
+	panic("cancelled")
+}
+
+var returnAsync = &returnAsyncInstance{returned: make(chan struct{}, 1)}
+
+func init() {
+	// async model that completes steps immediately
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "Async",
+		instance: &mockInstance{},
+		async:    true,
+	})
+	// async model that blocks until step is cancelled
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "AsyncCancel",
+		instance: &mockAsyncInstance{},
+		async:    true,
+	})
+	// async model that blocks until step is cancelled and reports when the step returns
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "AsyncReturn",
+		instance: returnAsync,
+		async:    true,
+	})
+	// async model that panics when the step is cancelled
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "AsyncCancelPanic",
+		instance: &panicAsyncInstance{},
+		async:    true,
+	})
+	// async model that panics in the step
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "AsyncPanic",
+		instance: &panicInstance{},
+		async:    true,
+	})
+}
+
+func instantiateAsync(guid string, state ...fmi.ModelState) fmi.FMUID {
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, guid, "", false, noopLogger))
+	instantiateState(id, state...)
+	return id
+}
+
+func waitForStep(t *testing.T, id fmi.FMUID) fmi.Status {
+	for i := 0; i < 1000; i++ {
+		s, status := fmi.GetStatus(id, fmi.StatusKindDoStep)
+		if status != fmi.StatusOK {
+			t.Fatalf("GetStatus() = %v, want %v", status, fmi.StatusOK)
+		}
+		if s != fmi.StatusPending {
+			return s
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for asynchronous step")
+	return fmi.StatusError
+}
+
+func TestDoStepAsync(t *testing.T) {
+	id := instantiateAsync("Async", fmi.ModelStateStepComplete)
+	defer fmi.FreeInstance(id)
+
+	if got := fmi.DoStep(id, 1, 0.5, false); got != fmi.StatusPending {
+		t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusPending)
+	}
+
+	if got := waitForStep(t, id); got != fmi.StatusOK {
+		t.Errorf("GetStatus() DoStep status = %v, want %v", got, fmi.StatusOK)
+	}
+
+	tm, s := fmi.GetRealStatus(id, fmi.StatusKindLastSuccessfulTime)
+	if s != fmi.StatusOK || tm != 1.5 {
+		t.Errorf("GetRealStatus() = %v, %v, want %v, %v", tm, s, 1.5, fmi.StatusOK)
+	}
+
+	pending, s := fmi.GetStringStatus(id, fmi.StatusKindPending)
+	if s != fmi.StatusOK || pending != "" {
+		t.Errorf("GetStringStatus() = %v, %v, want empty string", pending, s)
+	}
+
+	fmu, _ := fmi.GetFMU(id)
+	if fmu.State != fmi.ModelStateStepComplete {
+		t.Errorf("Expected FMU state %v, got %v", fmi.ModelStateStepComplete, fmu.State)
+	}
+}
+
+func TestDoStepAsync_Panic(t *testing.T) {
+	fatal := make(chan string, 1)
+	logger := func(status fmi.Status, category, message string) {
+		if status == fmi.StatusFatal {
+			fatal <- message
+		}
+	}
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "AsyncPanic", "", false, logger))
+	instantiateState(id, fmi.ModelStateStepComplete)
+	defer fmi.FreeInstance(id)
+
+	if got := fmi.DoStep(id, 0, 1, false); got != fmi.StatusPending {
+		t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusPending)
+	}
+	select {
+	case msg := <-fatal:
+		if !strings.HasPrefix(msg, "Panic in DoStep: DoStep") {
+			t.Errorf("Expected DoStep panic message, got %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for fatal message")
+	}
+
+	if _, s := fmi.GetStatus(id, fmi.StatusKindDoStep); s != fmi.StatusError {
+		t.Errorf("GetStatus() after fatal = %v, want %v", s, fmi.StatusError)
+	}
+}
+
+func TestCancelStep(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name      string
+		args      args
+		doStep    bool
+		want      fmi.Status
+		wantState fmi.ModelState
+	}{
+		{
+			"Step must be in progress",
+			args{
+				id: instantiateAsync("AsyncCancel", fmi.ModelStateStepComplete),
+			},
+			false,
+			fmi.StatusError,
+			fmi.ModelStateStepComplete,
+		},
+		{
+			"Pending step is cancelled",
+			args{
+				id: instantiateAsync("AsyncCancel", fmi.ModelStateStepComplete),
+			},
+			true,
+			fmi.StatusOK,
+			fmi.ModelStateStepCanceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.doStep {
+				if got := fmi.DoStep(tt.args.id, 0, 1, false); got != fmi.StatusPending {
+					t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusPending)
+				}
+				pending, _ := fmi.GetStringStatus(tt.args.id, fmi.StatusKindPending)
+				if pending == "" {
+					t.Errorf("Expected pending status description")
+				}
+			}
+			if got := fmi.CancelStep(tt.args.id); got != tt.want {
+				t.Errorf("CancelStep() = %v, want %v", got, tt.want)
+			}
+			verifyFMUStateAndCleanUp(t, tt.args.id, tt.wantState)
+		})
+	}
+}
+
+func TestCancelStep_Status(t *testing.T) {
+	for _, guid := range []string{"AsyncCancel", "AsyncCancelPanic"} {
+		t.Run(guid, func(t *testing.T) {
+			id := instantiateAsync(guid, fmi.ModelStateStepComplete)
+
+			if got := fmi.DoStep(id, 1, 0.5, false); got != fmi.StatusPending {
+				t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusPending)
+			}
+			if got := fmi.CancelStep(id); got != fmi.StatusOK {
+				t.Errorf("CancelStep() = %v, want %v", got, fmi.StatusOK)
+			}
+
+			if s, status := fmi.GetStatus(id, fmi.StatusKindDoStep); status != fmi.StatusOK || s != fmi.StatusError {
+				t.Errorf("GetStatus() = %v, %v, want %v", s, status, fmi.StatusError)
+			}
+			if tm, status := fmi.GetRealStatus(id, fmi.StatusKindLastSuccessfulTime); status != fmi.StatusOK || tm != 0 {
+				t.Errorf("GetRealStatus() = %v, %v, want 0", tm, status)
+			}
+			verifyFMUStateAndCleanUp(t, id, fmi.ModelStateStepCanceled)
+		})
+	}
+}
+
+func TestFreeInstance_PendingStep(t *testing.T) {
+	id := instantiateAsync("AsyncReturn", fmi.ModelStateStepComplete)
+
+	if got := fmi.DoStep(id, 0, 1, false); got != fmi.StatusPending {
+		t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusPending)
+	}
+	fmi.FreeInstance(id)
+
+	select {
+	case <-returnAsync.returned:
+	default:
+		t.Errorf("Expected pending step to be cancelled and returned when FreeInstance returns")
+	}
+	if _, err := fmi.GetFMU(id); err == nil {
+		t.Errorf("Expected FMU to be freed")
+	}
+}
+
+func TestGetStatusKinds(t *testing.T) {
+	tests := []struct {
+		name string
+		id   fmi.FMUID
+		get  func(fmi.FMUID) fmi.Status
+		want fmi.Status
+	}{
+		{
+			"GetStatus state is invalid",
+			instantiateDefault(fmi.ModelStateInitializationMode),
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetStatus(id, fmi.StatusKindDoStep)
+				return s
+			},
+			fmi.StatusError,
+		},
+		{
+			"GetStatus unsupported kind is discarded",
+			instantiateDefault(fmi.ModelStateStepComplete),
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetStatus(id, fmi.StatusKindTerminated)
+				return s
+			},
+			fmi.StatusDiscard,
+		},
+		{
+			"GetIntegerStatus is always discarded",
+			instantiateDefault(fmi.ModelStateStepComplete),
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetIntegerStatus(id, fmi.StatusKindDoStep)
+				return s
+			},
+			fmi.StatusDiscard,
+		},
+		{
+			"GetBooleanStatus terminated is returned",
+			instantiateDefault(fmi.ModelStateStepFailed),
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetBooleanStatus(id, fmi.StatusKindTerminated)
+				return s
+			},
+			fmi.StatusOK,
+		},
+		{
+			"GetRealStatus unsupported kind is discarded",
+			instantiateDefault(fmi.ModelStateStepComplete),
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetRealStatus(id, fmi.StatusKindPending)
+				return s
+			},
+			fmi.StatusDiscard,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.get(tt.id); got != tt.want {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+			fmi.FreeInstance(tt.id)
+		})
+	}
+}
+
+func TestGetRealStatusAfterDoStep(t *testing.T) {
+	id := instantiateDefault(fmi.ModelStateInstantiated)
+	defer fmi.FreeInstance(id)
+	fmi.SetupExperiment(id, false, 0, 2, false, 0)
+	instantiateState(id, fmi.ModelStateStepComplete)
+
+	if got := fmi.DoStep(id, 2, 1, false); got != fmi.StatusOK {
+		t.Fatalf("DoStep() = %v, want %v", got, fmi.StatusOK)
+	}
+	tm, _ := fmi.GetRealStatus(id, fmi.StatusKindLastSuccessfulTime)
+	if tm != 3 {
+		t.Errorf("GetRealStatus() = %v, want %v", tm, 3)
+	}
+}
@@ -8,4 +8,24 @@ void bridge_fmi2CallbackLogger(fmi2CallbackLogger f,
     fmi2String message)
 {
     f(componentEnvironment, instanceName, status, category, message);
-}
\ No newline at end of file
+}
+
+void bridge_fmi2StepFinished(fmi2StepFinished f,
+    fmi2ComponentEnvironment componentEnvironment,
+    fmi2Status status)
+{
+    f(componentEnvironment, status);
+}
+
+void* bridge_fmi2CallbackAllocateMemory(fmi2CallbackAllocateMemory f,
+    size_t nobj,
+    size_t size)
+{
+    return f(nobj, size);
+}
+
+void bridge_fmi2CallbackFreeMemory(fmi2CallbackFreeMemory f,
+    void* obj)
+{
+    f(obj);
+}
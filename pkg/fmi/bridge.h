@@ -7,9 +7,10 @@
 
 #include "./c/fmi2Functions.h"
 
-// ModelState is used to encode model state
+// ModelState is used to encode model state, data has room for capacity bytes
 typedef struct {
     size_t size;
+    size_t capacity;
     char data[1];
 } ModelState;
 
@@ -31,4 +32,15 @@ void bridge_fmi2CallbackLogger(fmi2CallbackLogger f,
     fmi2String category,
     fmi2String message);
 
+void bridge_fmi2StepFinished(fmi2StepFinished f,
+    fmi2ComponentEnvironment componentEnvironment,
+    fmi2Status status);
+
+void* bridge_fmi2CallbackAllocateMemory(fmi2CallbackAllocateMemory f,
+    size_t nobj,
+    size_t size);
+
+void bridge_fmi2CallbackFreeMemory(fmi2CallbackFreeMemory f,
+    void* obj);
+
 #endif  /* bridge_h */
\ No newline at end of file
@@ -0,0 +1,234 @@
+package fmi
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+)
+
+// StateCodec encodes the state of a model struct for ModelVariables.
+// The name is stored with FMU states, so states of another codec are rejected by fmi2SetFMUstate.
+type StateCodec interface {
+	// Name of the encoding, such as "gob"
+	Name() string
+	// Encode the exported fields of model, a struct or pointer to a struct
+	Encode(model interface{}) ([]byte, error)
+	// Decode bs into model, a pointer to a struct
+	Decode(bs []byte, model interface{}) error
+}
+
+var (
+	// GobStateCodec encodes the model struct with encoding/gob, the default codec of ModelVariables
+	GobStateCodec StateCodec = gobCodec{}
+	// JSONStateCodec encodes the model struct with encoding/json. Reals cannot be NaN or infinite.
+	JSONStateCodec StateCodec = jsonCodec{}
+	// BinaryStateCodec encodes each field as its value reference and value in little endian byte order,
+	// without the type information and reflection overhead of gob for models with many variables
+	BinaryStateCodec StateCodec = binaryCodec{}
+)
+
+// ModelVariablesOption configures ModelVariables created by NewModelVariables
+type ModelVariablesOption func(*modelVariables)
+
+// WithStateCodec sets the codec used by Encode and Decode
+func WithStateCodec(codec StateCodec) ModelVariablesOption {
+	return func(m *modelVariables) {
+		m.codec = codec
+	}
+}
+
+// CompressStateCodec compresses the state encoded by codec with DEFLATE, favouring speed over size
+func CompressStateCodec(codec StateCodec) StateCodec {
+	return compressedCodec{codec}
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Encode(model interface{}) ([]byte, error) {
+	bs := &bytes.Buffer{}
+	enc := gob.NewEncoder(bs)
+	if err := enc.Encode(model); err != nil {
+		return nil, fmt.Errorf("Error gob encoding model variable state: %w", err)
+	}
+	return bs.Bytes(), nil
+}
+
+func (gobCodec) Decode(bs []byte, model interface{}) error {
+	dec := gob.NewDecoder(bytes.NewBuffer(bs))
+	if err := dec.Decode(model); err != nil {
+		return err
+	}
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Encode(model interface{}) ([]byte, error) {
+	bs, err := json.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("Error JSON encoding model variable state: %w", err)
+	}
+	return bs, nil
+}
+
+func (jsonCodec) Decode(bs []byte, model interface{}) error {
+	if err := json.Unmarshal(bs, model); err != nil {
+		return fmt.Errorf("Error JSON decoding model variable state: %w", err)
+	}
+	return nil
+}
+
+type binaryCodec struct{}
+
+func (binaryCodec) Name() string {
+	return "binary"
+}
+
+func (binaryCodec) Encode(model interface{}) ([]byte, error) {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Requires struct kind, got %s", v.Kind())
+	}
+	// value reference and the largest fixed size value
+	bs := make([]byte, 0, v.NumField()*(4+8))
+	for i := 0; i < v.NumField(); i++ {
+		// value references are 1-based indexes
+		bs = appendUint32(bs, uint32(i+1))
+		f := v.Field(i)
+		switch f.Kind() {
+		case reflect.Float64:
+			bs = appendUint64(bs, math.Float64bits(f.Float()))
+		case reflect.Int32:
+			bs = appendUint32(bs, uint32(f.Int()))
+		case reflect.Bool:
+			var b byte
+			if f.Bool() {
+				b = 1
+			}
+			bs = append(bs, b)
+		case reflect.String:
+			bs = appendUint32(bs, uint32(f.Len()))
+			bs = append(bs, f.String()...)
+		default:
+			return nil, fmt.Errorf("Model struct field type %s not supported", f.Type())
+		}
+	}
+	return bs, nil
+}
+
+func (binaryCodec) Decode(bs []byte, model interface{}) error {
+	v := reflect.ValueOf(model)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("Model must be a pointer to a struct to decode state")
+	}
+	v = v.Elem()
+	errTruncated := errors.New("Binary model variable state is truncated")
+	for len(bs) > 0 {
+		if len(bs) < 4 {
+			return errTruncated
+		}
+		vr := binary.LittleEndian.Uint32(bs)
+		bs = bs[4:]
+		if vr == 0 || int(vr) > v.NumField() {
+			return fmt.Errorf("Value reference %d of binary model variable state is not a model field", vr)
+		}
+		f := v.Field(int(vr) - 1)
+		switch f.Kind() {
+		case reflect.Float64:
+			if len(bs) < 8 {
+				return errTruncated
+			}
+			f.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(bs)))
+			bs = bs[8:]
+		case reflect.Int32:
+			if len(bs) < 4 {
+				return errTruncated
+			}
+			f.SetInt(int64(int32(binary.LittleEndian.Uint32(bs))))
+			bs = bs[4:]
+		case reflect.Bool:
+			if len(bs) < 1 {
+				return errTruncated
+			}
+			f.SetBool(bs[0] != 0)
+			bs = bs[1:]
+		case reflect.String:
+			if len(bs) < 4 {
+				return errTruncated
+			}
+			n := binary.LittleEndian.Uint32(bs)
+			bs = bs[4:]
+			if uint64(len(bs)) < uint64(n) {
+				return errTruncated
+			}
+			f.SetString(string(bs[:n]))
+			bs = bs[n:]
+		default:
+			return fmt.Errorf("Model struct field type %s not supported", f.Type())
+		}
+	}
+	return nil
+}
+
+func appendUint32(bs []byte, v uint32) []byte {
+	return append(bs, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func appendUint64(bs []byte, v uint64) []byte {
+	return appendUint32(appendUint32(bs, uint32(v)), uint32(v>>32))
+}
+
+type compressedCodec struct {
+	codec StateCodec
+}
+
+func (c compressedCodec) Name() string {
+	return c.codec.Name() + "+deflate"
+}
+
+func (c compressedCodec) Encode(model interface{}) ([]byte, error) {
+	bs, err := c.codec.Encode(model)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	w, err := flate.NewWriter(buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(bs); err != nil {
+		return nil, fmt.Errorf("Error compressing model variable state: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("Error compressing model variable state: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (c compressedCodec) Decode(bs []byte, model interface{}) error {
+	r := flate.NewReader(bytes.NewReader(bs))
+	defer r.Close()
+	state, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Error decompressing model variable state: %w", err)
+	}
+	return c.codec.Decode(state, model)
+}
@@ -0,0 +1,222 @@
+package fmi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type codecModel struct {
+	A float64
+	B int32
+	C bool
+	D string
+}
+
+func TestStateCodec(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec StateCodec
+		want  string
+	}{
+		{
+			"gob",
+			GobStateCodec,
+			"gob",
+		},
+		{
+			"JSON",
+			JSONStateCodec,
+			"json",
+		},
+		{
+			"Binary",
+			BinaryStateCodec,
+			"binary",
+		},
+		{
+			"Compressed gob",
+			CompressStateCodec(GobStateCodec),
+			"gob+deflate",
+		},
+		{
+			"Compressed binary",
+			CompressStateCodec(BinaryStateCodec),
+			"binary+deflate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := codecModel{-1.5, -42, true, "foo"}
+			model := want
+			mv, err := NewModelVariables(&model, WithStateCodec(tt.codec))
+			if err != nil {
+				t.Fatalf("NewModelVariables() error = %v", err)
+			}
+			if got := mv.StateEncoding(); got != tt.want {
+				t.Errorf("StateEncoding() = %s, want %s", got, tt.want)
+			}
+
+			bs, err := mv.Encode()
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			model = codecModel{2.5, 7, false, "bar"}
+			if err := mv.Decode(bs); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(model, want) {
+				t.Errorf("Decode() model = %+v, want %+v", model, want)
+			}
+
+			// a struct value is copied, so the state is restored in the copy
+			mv, err = NewModelVariables(want, WithStateCodec(tt.codec))
+			if err != nil {
+				t.Fatalf("NewModelVariables() error = %v", err)
+			}
+			if bs, err = mv.Encode(); err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if err := mv.SetReal(ValueReference{1}, []float64{2.5}); err != nil {
+				t.Fatalf("SetReal() error = %v", err)
+			}
+			if err := mv.SetString(ValueReference{4}, []string{"bar"}); err != nil {
+				t.Fatalf("SetString() error = %v", err)
+			}
+			if err := mv.Decode(bs); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if fs, err := mv.GetReal(ValueReference{1}); err != nil || fs[0] != want.A {
+				t.Errorf("GetReal() after Decode = %v, %v, want %v", fs, err, want.A)
+			}
+			if ss, err := mv.GetString(ValueReference{4}); err != nil || ss[0] != want.D {
+				t.Errorf("GetString() after Decode = %v, %v, want %v", ss, err, want.D)
+			}
+		})
+	}
+}
+
+func Test_modelVariables_StateEncoding(t *testing.T) {
+	mv, err := NewModelVariables(codecModel{})
+	if err != nil {
+		t.Fatalf("NewModelVariables() error = %v", err)
+	}
+	if got := mv.StateEncoding(); got != "gob" {
+		t.Errorf("StateEncoding() = %s, want gob", got)
+	}
+}
+
+func Test_binaryCodec_Decode(t *testing.T) {
+	valid, err := BinaryStateCodec.Encode(codecModel{1, 2, true, "foo"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		bs    []byte
+		model interface{}
+		// err is part of the error message, empty if there is no error
+		err string
+	}{
+		{
+			"Empty state keeps fields",
+			nil,
+			&codecModel{},
+			"",
+		},
+		{
+			"Fields are keyed by value reference",
+			[]byte{2, 0, 0, 0, 7, 0, 0, 0},
+			&codecModel{},
+			"",
+		},
+		{
+			"Model must be a pointer",
+			valid,
+			codecModel{},
+			"pointer to a struct",
+		},
+		{
+			"Unknown value reference",
+			[]byte{5, 0, 0, 0},
+			&codecModel{},
+			"Value reference 5",
+		},
+		{
+			"Truncated value",
+			valid[:len(valid)-1],
+			&codecModel{},
+			"truncated",
+		},
+		{
+			"Truncated value reference",
+			[]byte{1, 0},
+			&codecModel{},
+			"truncated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := BinaryStateCodec.Decode(tt.bs, tt.model)
+			if tt.err == "" && err != nil {
+				t.Errorf("Decode() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Decode() error = %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func Test_binaryCodec_Encode(t *testing.T) {
+	bs, err := BinaryStateCodec.Encode(&codecModel{math.Inf(1), 1, false, ""})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	// value reference and value of each field
+	if want := (4 + 8) + (4 + 4) + (4 + 1) + (4 + 4); len(bs) != want {
+		t.Errorf("Encode() returned %d bytes, want %d", len(bs), want)
+	}
+	if _, err := BinaryStateCodec.Encode(struct{ A float32 }{}); err == nil {
+		t.Errorf("Encode() expected error for unsupported field type")
+	}
+}
+
+// realsModel returns a pointer to a model struct with n Real fields
+func realsModel(n int) interface{} {
+	fields := make([]reflect.StructField, n)
+	for i := range fields {
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("R%d", i), Type: reflect.TypeOf(float64(0))}
+	}
+	v := reflect.New(reflect.StructOf(fields))
+	for i := 0; i < n; i++ {
+		v.Elem().Field(i).SetFloat(float64(i) / 3)
+	}
+	return v.Interface()
+}
+
+func BenchmarkStateCodec(b *testing.B) {
+	codecs := []StateCodec{
+		GobStateCodec,
+		JSONStateCodec,
+		BinaryStateCodec,
+		CompressStateCodec(BinaryStateCodec),
+	}
+	model := realsModel(5000)
+	for _, c := range codecs {
+		b.Run(c.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bs, err := c.Encode(model)
+				if err != nil {
+					b.Fatalf("Encode() error = %v", err)
+				}
+				if err := c.Decode(bs, model); err != nil {
+					b.Fatalf("Decode() error = %v", err)
+				}
+			}
+		})
+	}
+}
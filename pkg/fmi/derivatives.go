@@ -0,0 +1,365 @@
+package fmi
+
+// #include <stdlib.h>
+// #include "./c/fmi2Functions.h"
+// #include "bridge.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// epsilon is the machine epsilon for float64
+var epsilon = math.Nextafter(1, 2) - 1
+
+//export fmi2GetDirectionalDerivative
+func fmi2GetDirectionalDerivative(c C.fmi2Component, vUnknown_ref C.valueReferences_t, nUnknown C.size_t,
+	vKnown_ref C.valueReferences_t, nKnown C.size_t,
+	dvKnown C.fmi2Reals_t, dvUnknown *C.fmi2Real) C.fmi2Status {
+	vUnknown, err := valueReferences(vUnknown_ref, nUnknown)
+	if err != nil {
+		return logError(c, err)
+	}
+	vKnown, err := valueReferences(vKnown_ref, nKnown)
+	if err != nil {
+		return logError(c, err)
+	}
+	dvs, err := fmi2Reals(dvKnown, nKnown)
+	if err != nil {
+		return logError(c, err)
+	}
+	if nUnknown > 0 && dvUnknown == nil {
+		return logError(c, fmt.Errorf("fmi2Real array is null but size is %d", nUnknown))
+	}
+
+	var rs []C.fmi2Real
+	carrayToSlice(unsafe.Pointer(dvUnknown), unsafe.Pointer(&rs), int(nUnknown))
+	fs, s := GetDirectionalDerivative(FMUID(c), vUnknown, vKnown, dvs)
+	if !succeeded(s) {
+		return C.fmi2Status(s)
+	}
+	copyRealArray(fs, rs)
+	return C.fmi2Status(s)
+}
+
+/*
+GetDirectionalDerivative computes the directional derivatives of an FMU.
+An FMU has different modes and in every mode an FMU might be described by different equations and different
+unknowns. The precise definitions are given in the mathematical descriptions of Model Exchange and Co-Simulation.
+In every mode, the general form of the FMU equations are:
+
+	v_unknown = h(v_known, v_rest)
+
+This function computes the directional derivative dv_unknown = J * dv_known,
+where J is the partial derivative of h with respect to v_known.
+
+If the model instance implements DirectionalDeriver the model computes the derivatives,
+otherwise a forward finite difference is computed by perturbing the knowns with SetReal
+and reading the unknowns with GetReal. The model state is restored afterwards with
+StateEncoder and StateDecoder if implemented, otherwise the knowns are reset to their original values.
+*/
+func GetDirectionalDerivative(id FMUID, vUnknown, vKnown ValueReference, dvKnown []float64) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetDirectionalDerivative")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	if c := fmu.capabilities(); c == nil || !c.ProvidesDirectionalDerivative {
+		fmu.logger.Error(errors.New("Model description does not set providesDirectionalDerivative"))
+		return nil, StatusError
+	}
+
+	if len(vKnown) != len(dvKnown) {
+		fmu.logger.Error(fmt.Errorf("Length of known value references %d must be same as seed vector %d", len(vKnown), len(dvKnown)))
+		return nil, StatusError
+	}
+
+	var dvs []float64
+	var err error
+	if dd, ok := fmu.instance.(DirectionalDeriver); ok {
+		dvs, err = dd.GetDirectionalDerivative(vUnknown, vKnown, dvKnown)
+	} else {
+		dvs, err = fmu.finiteDifference(vUnknown, vKnown, dvKnown)
+	}
+	s := fmu.modelStatus("GetDirectionalDerivative", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+
+	if len(dvs) != len(vUnknown) {
+		fmu.logger.Error(fmt.Errorf("GetDirectionalDerivative returned %d values but expected %d", len(dvs), len(vUnknown)))
+		return nil, StatusError
+	}
+	return dvs, s
+}
+
+// finiteDifference approximates J * dvKnown with a forward difference along dvKnown
+func (f *FMU) finiteDifference(vUnknown, vKnown ValueReference, dvKnown []float64) (dvs []float64, err error) {
+	vgs, err := f.valueGetterSetter()
+	if err != nil {
+		return nil, err
+	}
+
+	dvs = make([]float64, len(vUnknown))
+	seedNorm := norm(dvKnown)
+	if len(vUnknown) == 0 || seedNorm == 0 {
+		return dvs, nil
+	}
+
+	x, err := vgs.GetReal(vKnown)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting knowns: %w", err)
+	}
+	y, err := vgs.GetReal(vUnknown)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting unknowns: %w", err)
+	}
+
+	restore, err := f.snapshot(vgs, vKnown, x)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if rerr := restore(); rerr != nil && err == nil {
+			dvs, err = nil, rerr
+		}
+	}()
+
+	// step size scaled to the knowns so the perturbation is not lost to rounding
+	h := math.Sqrt(epsilon) * math.Max(1, norm(x)) / seedNorm
+	xh := make([]float64, len(x))
+	for i := range x {
+		xh[i] = x[i] + h*dvKnown[i]
+	}
+	if err := vgs.SetReal(vKnown, xh); err != nil {
+		return nil, fmt.Errorf("Error setting perturbed knowns: %w", err)
+	}
+	yh, err := vgs.GetReal(vUnknown)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting perturbed unknowns: %w", err)
+	}
+
+	for i := range dvs {
+		dvs[i] = (yh[i] - y[i]) / h
+	}
+	return dvs, nil
+}
+
+// snapshot stores the model state and returns a function to restore it.
+// If the model cannot encode its state, the knowns are reset to x instead.
+func (f *FMU) snapshot(vs ValueSetter, vKnown ValueReference, x []float64) (func() error, error) {
+	se, errEnc := f.StateEncoder()
+	sd, errDec := f.StateDecoder()
+	if errEnc != nil || errDec != nil {
+		return func() error {
+			if err := vs.SetReal(vKnown, x); err != nil {
+				return fmt.Errorf("Error resetting knowns: %w", err)
+			}
+			return nil
+		}, nil
+	}
+
+	state, err := se.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding state before finite difference: %w", err)
+	}
+	return func() error {
+		if err := sd.Decode(state); err != nil {
+			return fmt.Errorf("Error restoring state after finite difference: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+func norm(vs []float64) float64 {
+	var sum float64
+	for _, v := range vs {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
+
+//export fmi2SetRealInputDerivatives
+func fmi2SetRealInputDerivatives(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, order C.fmi2Integers_t, value C.fmi2Reals_t) C.fmi2Status {
+	vs, err := valueReferences(vr, nvr)
+	if err != nil {
+		return logError(c, err)
+	}
+	os, err := fmi2Integers(order, nvr)
+	if err != nil {
+		return logError(c, err)
+	}
+	fs, err := fmi2Reals(value, nvr)
+	if err != nil {
+		return logError(c, err)
+	}
+	return C.fmi2Status(SetRealInputDerivatives(FMUID(c), vs, os, fs))
+}
+
+/*
+SetRealInputDerivatives sets the n-th time derivative of real input variables.
+vr defines the value references of the variables, the array order contains the orders of the
+respective derivative (1 means the first derivative, 0 is not allowed). value is a vector with
+the values of the derivatives.
+Restrictions on using the function are the same as for the fmi2SetReal function.
+Inputs and their derivatives are set with respect to the beginning of a communication time step.
+
+The model description must set canInterpolateInputs and the model instance must implement
+InputDerivativeSetter. Every value reference must be a real input and orders must not exceed
+maxOutputDerivativeOrder, or 1 if maxOutputDerivativeOrder is not set.
+*/
+func SetRealInputDerivatives(id FMUID, vr ValueReference, order []int32, value []float64) (status Status) {
+	fmu, ok := allowedState(id, "SetRealInputDerivatives")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	cosim, err := fmu.CoSimulator()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+	cs, err := fmu.coSimulation()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+	if !cs.CanInterpolateInputs {
+		fmu.logger.Error(errors.New("Model description does not set canInterpolateInputs"))
+		return StatusError
+	}
+	setter, ok := cosim.(InputDerivativeSetter)
+	if !ok {
+		fmu.logger.Error(errors.New("FMU model instance does not implement InputDerivativeSetter"))
+		return StatusError
+	}
+
+	maxOrder := cs.MaxOutputDerivativeOrder
+	if maxOrder == 0 {
+		maxOrder = 1
+	}
+	if err := fmu.checkDerivatives(vr, order, VariableCausalityInput, maxOrder); err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+	if len(value) != len(vr) {
+		fmu.logger.Error(fmt.Errorf("Length of value references %d must be same as values %d", len(vr), len(value)))
+		return StatusError
+	}
+
+	return fmu.modelStatus("SetRealInputDerivatives", setter.SetRealInputDerivatives(vr, order, value))
+}
+
+//export fmi2GetRealOutputDerivatives
+func fmi2GetRealOutputDerivatives(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, order C.fmi2Integers_t, value *C.fmi2Real) C.fmi2Status {
+	vs, err := valueReferences(vr, nvr)
+	if err != nil {
+		return logError(c, err)
+	}
+	os, err := fmi2Integers(order, nvr)
+	if err != nil {
+		return logError(c, err)
+	}
+	if nvr > 0 && value == nil {
+		return logError(c, fmt.Errorf("fmi2Real array is null but size is %d", nvr))
+	}
+
+	var rs []C.fmi2Real
+	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&rs), int(nvr))
+	fs, s := GetRealOutputDerivatives(FMUID(c), vs, os)
+	if !succeeded(s) {
+		return C.fmi2Status(s)
+	}
+	copyRealArray(fs, rs)
+	return C.fmi2Status(s)
+}
+
+/*
+GetRealOutputDerivatives retrieves the n-th derivative of output values.
+vr defines the value references of the variables, the array order contains the order of the
+respective derivative (1 means the first derivative, 0 is not allowed). The derivatives
+are returned with respect to the current communication point.
+
+The model instance must implement OutputDerivativeGetter. Every value reference must be
+a real output and orders must not exceed maxOutputDerivativeOrder.
+*/
+func GetRealOutputDerivatives(id FMUID, vr ValueReference, order []int32) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetRealOutputDerivatives")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	cosim, err := fmu.CoSimulator()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+	cs, err := fmu.coSimulation()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+	getter, ok := cosim.(OutputDerivativeGetter)
+	if !ok {
+		fmu.logger.Error(errors.New("FMU model instance does not implement OutputDerivativeGetter"))
+		return nil, StatusError
+	}
+
+	maxOrder := cs.MaxOutputDerivativeOrder
+	if err := fmu.checkDerivatives(vr, order, VariableCausalityOutput, maxOrder); err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+
+	fs, err := getter.GetRealOutputDerivatives(vr, order)
+	s := fmu.modelStatus("GetRealOutputDerivatives", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+	if len(fs) != len(vr) {
+		fmu.logger.Error(fmt.Errorf("GetRealOutputDerivatives returned %d values but expected %d", len(fs), len(vr)))
+		return nil, StatusError
+	}
+	return fs, s
+}
+
+// checkDerivatives validates value references are real variables with the given causality
+// and orders are between 1 and maxOrder
+func (f *FMU) checkDerivatives(vr ValueReference, order []int32, causality VariableCausality, maxOrder uint) error {
+	if len(order) != len(vr) {
+		return fmt.Errorf("Length of value references %d must be same as orders %d", len(vr), len(order))
+	}
+
+	for i, v := range vr {
+		if order[i] < 1 || uint(order[i]) > maxOrder {
+			return fmt.Errorf("Derivative order %d of value reference %d must be between 1 and %d", order[i], v, maxOrder)
+		}
+		if !f.hasRealVariable(v, causality) {
+			return fmt.Errorf("Value reference %d is not a real variable with causality %s", v, variableCausalityEnum[causality])
+		}
+	}
+	return nil
+}
+
+// hasRealVariable checks the model description for a real scalar variable with value reference and causality
+func (f *FMU) hasRealVariable(vr uint, causality VariableCausality) bool {
+	for _, sv := range f.description.ModelVariables {
+		if sv.ValueReference != vr || sv.ScalarVariableType == nil || sv.Real == nil {
+			continue
+		}
+		c := VariableCausalityLocal
+		if sv.Causality != nil {
+			c = *sv.Causality
+		}
+		if c == causality {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,508 @@
+package fmi_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// mockLinearInstance has knowns x1, x2 (vr 1, 2) and unknowns
+// y1 = 2*x1 + 3*x2 (vr 3) and y2 = x1*x2 (vr 4)
+type mockLinearInstance struct {
+	mockInstance
+	X [2]float64
+}
+
+func (m *mockLinearInstance) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	fs := make([]float64, len(vr))
+	for i, v := range vr {
+		switch v {
+		case 1, 2:
+			fs[i] = m.X[v-1]
+		case 3:
+			fs[i] = 2*m.X[0] + 3*m.X[1]
+		case 4:
+			fs[i] = m.X[0] * m.X[1]
+		default:
+			return nil, errors.New("GetReal")
+		}
+	}
+	return fs, nil
+}
+
+func (m *mockLinearInstance) SetReal(vr fmi.ValueReference, fs []float64) error {
+	for i, v := range vr {
+		if v != 1 && v != 2 {
+			return errors.New("SetReal")
+		}
+		m.X[v-1] = fs[i]
+	}
+	return nil
+}
+
+func (m *mockLinearInstance) Encode() ([]byte, error) {
+	return json.Marshal(m.X)
+}
+
+func (m *mockLinearInstance) Decode(bs []byte) error {
+	return json.Unmarshal(bs, &m.X)
+}
+
+// mockDeriverInstance returns the seed vector as the derivative
+type mockDeriverInstance struct {
+	mockInstance
+}
+
+func (m mockDeriverInstance) GetDirectionalDerivative(vUnknown, vKnown fmi.ValueReference, dvKnown []float64) ([]float64, error) {
+	return dvKnown, nil
+}
+
+// mockInterpolatingInstance extrapolates inputs and returns output derivative orders as values
+type mockInterpolatingInstance struct {
+	mockInstance
+	inputs *fmi.InputExtrapolator
+}
+
+func (m mockInterpolatingInstance) SetRealInputDerivatives(vr fmi.ValueReference, order []int32, value []float64) error {
+	return m.inputs.SetRealInputDerivatives(vr, order, value)
+}
+
+func (m mockInterpolatingInstance) GetRealOutputDerivatives(vr fmi.ValueReference, order []int32) ([]float64, error) {
+	fs := make([]float64, len(order))
+	for i, o := range order {
+		fs[i] = float64(o)
+	}
+	return fs, nil
+}
+
+func scalarVariable(vr uint, causality fmi.VariableCausality, typ fmi.ScalarVariableType) fmi.ScalarVariable {
+	return fmi.ScalarVariable{
+		ValueReference:     vr,
+		Causality:          &causality,
+		ScalarVariableType: &typ,
+	}
+}
+
+var interpolatingVariables = []fmi.ScalarVariable{
+	scalarVariable(1, fmi.VariableCausalityInput, fmi.ScalarVariableType{Real: &fmi.RealVariable{}}),
+	scalarVariable(2, fmi.VariableCausalityOutput, fmi.ScalarVariableType{Real: &fmi.RealVariable{}}),
+	scalarVariable(3, fmi.VariableCausalityInput, fmi.ScalarVariableType{Integer: &fmi.IntegerVariable{}}),
+}
+
+func init() {
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "Interpolate",
+		instance: &mockInterpolatingInstance{
+			inputs: fmi.NewInputExtrapolator(),
+		},
+		interpolate: true,
+		outputOrder: 2,
+		variables:   interpolatingVariables,
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "InterpolateFirstOrder",
+		instance: &mockInterpolatingInstance{
+			inputs: fmi.NewInputExtrapolator(),
+		},
+		interpolate: true,
+		variables:   interpolatingVariables,
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "NoInterpolate",
+		instance: &mockInterpolatingInstance{
+			inputs: fmi.NewInputExtrapolator(),
+		},
+		outputOrder: 2,
+		variables:   interpolatingVariables,
+	})
+	// co-simulation instance of a model description without CoSimulation
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "InterpolateNoCoSimulation",
+		instance: &mockInterpolatingInstance{
+			inputs: fmi.NewInputExtrapolator(),
+		},
+		variables: interpolatingVariables,
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "Linear",
+		instance: &mockLinearInstance{
+			X: [2]float64{3, 4},
+		},
+		derivatives: true,
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid:        "Deriver",
+		instance:    &mockDeriverInstance{},
+		derivatives: true,
+	})
+}
+
+func TestGetDirectionalDerivative(t *testing.T) {
+	type args struct {
+		id       fmi.FMUID
+		vUnknown fmi.ValueReference
+		vKnown   fmi.ValueReference
+		dvKnown  []float64
+	}
+	tests := []struct {
+		name  string
+		args  args
+		want  []float64
+		want1 fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateAsync("Linear"),
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Model must provide directional derivatives",
+			args{
+				id: instantiateDefault(fmi.ModelStateStepComplete),
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Seed vector must match knowns",
+			args{
+				id:       instantiateAsync("Linear", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3},
+				vKnown:   fmi.ValueReference{1, 2},
+				dvKnown:  []float64{1},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Model directional derivative is used",
+			args{
+				id:       instantiateAsync("Deriver", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3, 4},
+				vKnown:   fmi.ValueReference{1, 2},
+				dvKnown:  []float64{5, 6},
+			},
+			[]float64{5, 6},
+			fmi.StatusOK,
+		},
+		{
+			"Model directional derivative size is checked",
+			args{
+				id:       instantiateAsync("Deriver", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3},
+				vKnown:   fmi.ValueReference{1, 2},
+				dvKnown:  []float64{5, 6},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Finite difference of linear unknown",
+			args{
+				id:       instantiateAsync("Linear", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3},
+				vKnown:   fmi.ValueReference{1, 2},
+				dvKnown:  []float64{1, 1},
+			},
+			[]float64{5},
+			fmi.StatusOK,
+		},
+		{
+			"Finite difference of nonlinear unknown",
+			args{
+				id:       instantiateAsync("Linear", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3, 4},
+				vKnown:   fmi.ValueReference{1},
+				dvKnown:  []float64{1},
+			},
+			[]float64{2, 4},
+			fmi.StatusOK,
+		},
+		{
+			"Zero seed returns zero derivatives",
+			args{
+				id:       instantiateAsync("Linear", fmi.ModelStateStepComplete),
+				vUnknown: fmi.ValueReference{3, 4},
+				vKnown:   fmi.ValueReference{1, 2},
+				dvKnown:  []float64{0, 0},
+			},
+			[]float64{0, 0},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := fmi.GetDirectionalDerivative(tt.args.id, tt.args.vUnknown, tt.args.vKnown, tt.args.dvKnown)
+			if got1 != tt.want1 {
+				t.Errorf("GetDirectionalDerivative() got1 = %v, want %v", got1, tt.want1)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetDirectionalDerivative() got = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if math.Abs(got[i]-tt.want[i]) > 1e-6 {
+					t.Errorf("GetDirectionalDerivative() got = %v, want %v", got, tt.want)
+				}
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
+
+func TestGetDirectionalDerivativeRestoresState(t *testing.T) {
+	id := instantiateAsync("Linear", fmi.ModelStateStepComplete)
+	defer fmi.FreeInstance(id)
+
+	before, _ := fmi.GetReal(id, fmi.ValueReference{1, 2})
+	if _, s := fmi.GetDirectionalDerivative(id, fmi.ValueReference{4}, fmi.ValueReference{1, 2}, []float64{1, 2}); s != fmi.StatusOK {
+		t.Fatalf("GetDirectionalDerivative() = %v, want %v", s, fmi.StatusOK)
+	}
+	after, _ := fmi.GetReal(id, fmi.ValueReference{1, 2})
+	if before[0] != after[0] || before[1] != after[1] {
+		t.Errorf("Expected knowns %v to be restored, got %v", before, after)
+	}
+}
+
+func TestSetRealInputDerivatives(t *testing.T) {
+	type args struct {
+		id    fmi.FMUID
+		vr    fmi.ValueReference
+		order []int32
+		value []float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateAsync("Interpolate", fmi.ModelStateTerminated),
+			},
+			fmi.StatusError,
+		},
+		{
+			"FMU type should be cosimulation",
+			args{
+				id: instantiateModelExchange(),
+			},
+			fmi.StatusError,
+		},
+		{
+			"Model description must define CoSimulation",
+			args{
+				id:    instantiateAsync("InterpolateNoCoSimulation", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{1},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Model must set canInterpolateInputs",
+			args{
+				id:    instantiateAsync("NoInterpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{1},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Model must implement InputDerivativeSetter",
+			args{
+				id: instantiateAsync("Linear", fmi.ModelStateStepComplete),
+			},
+			fmi.StatusError,
+		},
+		{
+			"Order zero is not allowed",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{0},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Order must not exceed max output derivative order",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{3},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"First order is allowed without max output derivative order",
+			args{
+				id:    instantiateAsync("InterpolateFirstOrder", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{1},
+				value: []float64{1},
+			},
+			fmi.StatusOK,
+		},
+		{
+			"Second order is not allowed without max output derivative order",
+			args{
+				id:    instantiateAsync("InterpolateFirstOrder", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{2},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Value reference must be an input",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2},
+				order: []int32{1},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Value reference must be a real",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{3},
+				order: []int32{1},
+				value: []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Input derivatives are set",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateInstantiated),
+				vr:    fmi.ValueReference{1, 1},
+				order: []int32{1, 2},
+				value: []float64{1, 2},
+			},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmi.SetRealInputDerivatives(tt.args.id, tt.args.vr, tt.args.order, tt.args.value); got != tt.want {
+				t.Errorf("SetRealInputDerivatives() = %v, want %v", got, tt.want)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
+
+func TestGetRealOutputDerivatives(t *testing.T) {
+	type args struct {
+		id    fmi.FMUID
+		vr    fmi.ValueReference
+		order []int32
+	}
+	tests := []struct {
+		name  string
+		args  args
+		want  []float64
+		want1 fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateAsync("Interpolate", fmi.ModelStateInitializationMode),
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Model must implement OutputDerivativeGetter",
+			args{
+				id: instantiateAsync("Linear", fmi.ModelStateStepComplete),
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Model description must define CoSimulation",
+			args{
+				id:    instantiateAsync("InterpolateNoCoSimulation", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2},
+				order: []int32{1},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Orders must match value references",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2},
+				order: []int32{1, 2},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Order must not exceed max output derivative order",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2},
+				order: []int32{3},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Output derivatives are not provided without max output derivative order",
+			args{
+				id:    instantiateAsync("InterpolateFirstOrder", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2},
+				order: []int32{1},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Value reference must be an output",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{1},
+				order: []int32{1},
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"Output derivatives are returned",
+			args{
+				id:    instantiateAsync("Interpolate", fmi.ModelStateStepComplete),
+				vr:    fmi.ValueReference{2, 2},
+				order: []int32{1, 2},
+			},
+			[]float64{1, 2},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := fmi.GetRealOutputDerivatives(tt.args.id, tt.args.vr, tt.args.order)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRealOutputDerivatives() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("GetRealOutputDerivatives() got1 = %v, want %v", got1, tt.want1)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
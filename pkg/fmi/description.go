@@ -3,6 +3,8 @@ package fmi
 import (
 	"encoding/xml"
 	"fmt"
+	"io"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -115,6 +117,8 @@ type ModelDescription struct {
 	// TypeDefinitions to be shared by ModelVariables
 	TypeDefinitions *[]SimpleType `xml:"TypeDefinitions>SimpleType,omitempty"`
 
+	modelDescriptionLogCategories
+
 	// DefaultExperiment is optional default experiment parameters.
 	DefaultExperiment *Experiment `xml:"DefaultExperiment,omitempty"`
 
@@ -145,14 +149,48 @@ type ModelDescription struct {
 	ModelStructure ModelStructure `xml:"ModelStructure"`
 }
 
+/*
+	MarshalXML adds the fields this package controls, such as fmiVersion and LogCategories, to a description
+	built for a Go model. A description read with ParseModelDescription already has fmiVersion set, so
+	it is written as parsed and round trips unchanged.
+*/
 func (m ModelDescription) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	if m.FMIVersion == "" {
+		m.setStaticFields()
+	}
+	type element ModelDescription
+	return e.Encode(element(m))
+}
+
+// setStaticFields sets fields that are fixed by this package on a copy of the description
+func (m *ModelDescription) setStaticFields() {
 	m.modelDescriptionStatic = modelDescriptionStatic{
 		FMIVersion:               GetVersion(),
 		VariableNamingConvention: "flat",
-		LogCategories:            buildLogCategories(),
 	}
-	type element ModelDescription
-	return e.Encode(element(m))
+	m.modelDescriptionLogCategories = modelDescriptionLogCategories{
+		LogCategories: buildLogCategories(),
+	}
+	// memory returned to the environment is allocated with its callbacks if they are set
+	if m.ModelExchange != nil {
+		me := *m.ModelExchange
+		me.CanNotUseMemoryManagementFunctions = false
+		m.ModelExchange = &me
+	}
+	if m.CoSimulation != nil {
+		cs := *m.CoSimulation
+		cs.CanNotUseMemoryManagementFunctions = false
+		m.CoSimulation = &cs
+	}
+}
+
+// ParseModelDescription reads a modelDescription.xml file, for example from a third party FMU
+func ParseModelDescription(r io.Reader) (ModelDescription, error) {
+	var m ModelDescription
+	if err := xml.NewDecoder(r).Decode(&m); err != nil {
+		return ModelDescription{}, fmt.Errorf("Error parsing model description: %w", err)
+	}
+	return m, nil
 }
 
 /*
@@ -169,12 +207,6 @@ type ModelExchange struct {
 	CompletedIntegratorStepNotNeeded bool `xml:"completedIntegratorStepNotNeeded,attr,omitempty"`
 }
 
-func (m ModelExchange) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	m.CanNotUseMemoryManagementFunctions = true
-	type element ModelExchange
-	return e.EncodeElement(element(m), start)
-}
-
 /*
 	CoSimulation defines fields for model and simulation engine/comms tool to a simulation engine.
 	The environment provides the master algorithm to couple FMUs together.
@@ -187,12 +219,6 @@ type CoSimulation struct {
 	CanRunAsynchronuously                  bool `xml:"canRunAsynchronuously,attr,omitempty"`
 }
 
-func (m CoSimulation) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	m.CanNotUseMemoryManagementFunctions = true
-	type element CoSimulation
-	return e.EncodeElement(element(m), start)
-}
-
 // FMUShared contains fields shared between ModelExchange and CoSimulation
 type FMUShared struct {
 	fmuStatic
@@ -309,6 +335,10 @@ type modelDescriptionStatic struct {
 	FMIVersion string `xml:"fmiVersion,attr"`
 	// VariableNamingConvention defines convention of variables. Set to "flat" in this library.
 	VariableNamingConvention string `xml:"variableNamingConvention,attr,omitempty"`
+}
+
+// modelDescriptionLogCategories is embedded separately so LogCategories follows TypeDefinitions as the schema requires
+type modelDescriptionLogCategories struct {
 	// LogCategories are fixed log categories based on logger
 	LogCategories *[]logCategory `xml:"LogCategories>Category,omitempty"`
 }
@@ -599,6 +629,8 @@ type ScalarVariableType struct {
 	Boolean *BooleanVariable `xml:",omitempty"`
 	// String holds attributes for string variable
 	String *StringVariable `xml:",omitempty"`
+	// Enumeration holds attributes for enumeration (int32) variable
+	Enumeration *EnumerationVariable `xml:",omitempty"`
 }
 
 func (v *ScalarVariableType) updateVariableType() {
@@ -614,6 +646,8 @@ func (v *ScalarVariableType) updateVariableType() {
 		v.variableType = VariableTypeBoolean
 	} else if v.String != nil {
 		v.variableType = VariableTypeString
+	} else if v.Enumeration != nil {
+		v.variableType = VariableTypeEnumeration
 	} else {
 		panic("Scalar variable type is empty")
 	}
@@ -776,6 +810,15 @@ type StringVariable struct {
 	Start string `xml:"start,attr,omitempty"`
 }
 
+// EnumerationVariable is used in scalar variables to define Enumeration.
+// DeclaredType is required and must name an Enumeration SimpleType in TypeDefinitions.
+type EnumerationVariable struct {
+	IntegerType
+	DeclaredType
+	// Start is defined as per RealVariable.Start
+	Start *int32 `xml:"start,attr,omitempty"`
+}
+
 /*
 	ModelStructure is with respect to the underlying model equations, independently how these model equations are solved.
 	[For example, when exporting a model both in Model Exchange and Co-Simulation format; then the model structure is identical in both cases.
@@ -920,6 +963,21 @@ type Unknown struct {
 	DependenciesKind StringAttributeList `xml:"dependenciesKind,attr,omitempty"`
 }
 
+// MarshalXML writes an empty, non-nil Dependencies list as dependencies="", which means the Unknown
+// depends on none of the Knowns, instead of omitting it, which means it depends on all Knowns
+func (u Unknown) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type element Unknown
+	if u.Dependencies == nil || len(u.Dependencies) > 0 {
+		return e.EncodeElement(element(u), start)
+	}
+	type noDependencies struct {
+		Index            uint                `xml:"index,attr"`
+		Dependencies     string              `xml:"dependencies,attr"`
+		DependenciesKind StringAttributeList `xml:"dependenciesKind,attr,omitempty"`
+	}
+	return e.EncodeElement(noDependencies{Index: u.Index, DependenciesKind: u.DependenciesKind}, start)
+}
+
 // UintAttributeList represents space delimited list of unsigned integers in an xml attribute
 type UintAttributeList []uint
 
@@ -934,8 +992,27 @@ func (l StringAttributeList) MarshalText() (text []byte, err error) {
 	return []byte(strings.Trim(fmt.Sprintf("%v", l), "[]")), nil
 }
 
+func (l *UintAttributeList) UnmarshalText(text []byte) error {
+	fs := strings.Fields(string(text))
+	list := make(UintAttributeList, len(fs))
+	for i, f := range fs {
+		u, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return fmt.Errorf("Invalid unsigned integer %s in list: %w", f, err)
+		}
+		list[i] = uint(u)
+	}
+	*l = list
+	return nil
+}
+
+func (l *StringAttributeList) UnmarshalText(text []byte) error {
+	*l = StringAttributeList(strings.Fields(string(text)))
+	return nil
+}
+
 func enumMarshalText(enum int, vs []string) (text []byte, err error) {
-	if enum > len(vs) {
+	if enum >= len(vs) {
 		err = fmt.Errorf("Index %d out of range %d", enum, len(vs))
 		return
 	}
@@ -1,7 +1,12 @@
 package fmi
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -290,17 +295,8 @@ func TestModelDescription_MarshallIndent(t *testing.T) {
 			},
 			[]byte(`<?xml version="1.0" encoding="UTF-8"?>
 <fmiModelDescription fmiVersion="2.0" variableNamingConvention="flat" modelName="name" guid="guid-guid" description="Thing here" author="Bob Smith" version="v0.0.1" copyright="Blah" license="MIT" generationTool="Golang" generationDateAndTime="0001-01-01T00:00:00Z" numberOfEventIndicators="2">
-    <LogCategories>
-        <Category name="logEvents"></Category>
-        <Category name="logStatusWarning"></Category>
-        <Category name="logStatusDiscard"></Category>
-        <Category name="logStatusError"></Category>
-        <Category name="logStatusFatal"></Category>
-        <Category name="logStatusPending"></Category>
-        <Category name="logAll"></Category>
-    </LogCategories>
-    <ModelExchange canNotUseMemoryManagementFunctions="true" modelIdentifier="id" needsExecutionTool="true" canBeInstantiatedOnlyOncePerProcess="true" canGetAndSetFMUstate="true" canSerializeFMUstate="true" providesDirectionalDerivative="true" completedIntegratorStepNotNeeded="true"></ModelExchange>
-    <CoSimulation canNotUseMemoryManagementFunctions="true" modelIdentifier="id" needsExecutionTool="true" canBeInstantiatedOnlyOncePerProcess="true" canGetAndSetFMUstate="true" canSerializeFMUstate="true" providesDirectionalDerivative="true" canHandleVariableCommunicationStepSize="true" canInterpolateInputs="true" maxOutputDerivativeOrder="2" canRunAsynchronuously="true"></CoSimulation>
+    <ModelExchange modelIdentifier="id" needsExecutionTool="true" canBeInstantiatedOnlyOncePerProcess="true" canGetAndSetFMUstate="true" canSerializeFMUstate="true" providesDirectionalDerivative="true" completedIntegratorStepNotNeeded="true"></ModelExchange>
+    <CoSimulation modelIdentifier="id" needsExecutionTool="true" canBeInstantiatedOnlyOncePerProcess="true" canGetAndSetFMUstate="true" canSerializeFMUstate="true" providesDirectionalDerivative="true" canHandleVariableCommunicationStepSize="true" canInterpolateInputs="true" maxOutputDerivativeOrder="2" canRunAsynchronuously="true"></CoSimulation>
     <UnitDefinitions>
         <Unit name="rads/s">
             <BaseUnit s="-1" rad="1"></BaseUnit>
@@ -330,6 +326,15 @@ func TestModelDescription_MarshallIndent(t *testing.T) {
             </Enumeration>
         </SimpleType>
     </TypeDefinitions>
+    <LogCategories>
+        <Category name="logEvents"></Category>
+        <Category name="logStatusWarning"></Category>
+        <Category name="logStatusDiscard"></Category>
+        <Category name="logStatusError"></Category>
+        <Category name="logStatusFatal"></Category>
+        <Category name="logStatusPending"></Category>
+        <Category name="logAll"></Category>
+    </LogCategories>
     <DefaultExperiment startTime="1" stopTime="2" tolerance="0.1" stepSize="0.001"></DefaultExperiment>
     <VendorAnnotations>
         <Tool name="Foo"><Bar>Baz</Bar></Tool>
@@ -386,6 +391,7 @@ func TestModelDescription_MarshallIndent(t *testing.T) {
 			},
 			[]byte(`<?xml version="1.0" encoding="UTF-8"?>
 <fmiModelDescription fmiVersion="2.0" variableNamingConvention="flat" modelName="name" guid="guid-guid">
+    <CoSimulation modelIdentifier="id"></CoSimulation>
     <LogCategories>
         <Category name="logEvents"></Category>
         <Category name="logStatusWarning"></Category>
@@ -395,7 +401,6 @@ func TestModelDescription_MarshallIndent(t *testing.T) {
         <Category name="logStatusPending"></Category>
         <Category name="logAll"></Category>
     </LogCategories>
-    <CoSimulation canNotUseMemoryManagementFunctions="true" modelIdentifier="id"></CoSimulation>
     <ModelVariables>
         <ScalarVariable name="v1" valueReference="1"></ScalarVariable>
     </ModelVariables>
@@ -450,3 +455,248 @@ func TestUintAttributeList_MarshalText(t *testing.T) {
 		})
 	}
 }
+
+func TestUintAttributeList_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    []byte
+		want    UintAttributeList
+		wantErr bool
+	}{
+		{
+			"empty string returns empty list",
+			[]byte(""),
+			UintAttributeList{},
+			false,
+		},
+		{
+			"space delimited numbers",
+			[]byte(" 1 2\n 3 "),
+			UintAttributeList{1, 2, 3},
+			false,
+		},
+		{
+			"negative numbers are invalid",
+			[]byte("1 -2"),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got UintAttributeList
+			err := got.UnmarshalText(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("UintAttributeList.UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UintAttributeList.UnmarshalText() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringAttributeList_UnmarshalText(t *testing.T) {
+	var got StringAttributeList
+	if err := got.UnmarshalText([]byte("dependent  constant fixed")); err != nil {
+		t.Fatalf("StringAttributeList.UnmarshalText() error = %v", err)
+	}
+	want := StringAttributeList{"dependent", "constant", "fixed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StringAttributeList.UnmarshalText() = %v, want %v", got, want)
+	}
+}
+
+func TestParseModelDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		verify   func(t *testing.T, m ModelDescription)
+		wantErrs []string
+	}{
+		{
+			"BouncingBall example",
+			"BouncingBall.xml",
+			func(t *testing.T, m ModelDescription) {
+				assert.Equal(t, "BouncingBall", m.Name)
+				assert.Equal(t, "flat", m.VariableNamingConvention)
+				assert.Equal(t, "BouncingBall", m.ModelExchange.ModelIdentifier)
+				assert.True(t, m.CoSimulation.CanHandleVariableCommunicationStepSize)
+				assert.True(t, m.CoSimulation.CanNotUseMemoryManagementFunctions)
+				assert.Len(t, *m.LogCategories, len(*buildLogCategories()))
+				assert.Len(t, m.ModelVariables, 7)
+				assert.Equal(t, 0.001, *m.DefaultExperiment.StepSize)
+			},
+			nil,
+		},
+		{
+			"Model exchange specification example",
+			"SpringMassDamperME.xml",
+			func(t *testing.T, m ModelDescription) {
+				assert.Equal(t, "2.0", m.FMIVersion)
+				assert.Equal(t, "structured", m.VariableNamingConvention)
+				assert.Equal(t, time.Date(2011, 9, 23, 16, 57, 33, 0, time.UTC), *m.GenerationDateAndTime)
+				assert.Equal(t, uint(2), m.NumberOfEventIndicators)
+				assert.Nil(t, m.CoSimulation)
+				assert.False(t, m.ModelExchange.CanNotUseMemoryManagementFunctions)
+				assert.Nil(t, m.LogCategories)
+
+				units := *m.UnitDefinitions
+				assert.Equal(t, "rad/s", units[1].Name)
+				assert.Equal(t, -1, *units[1].BaseUnit.S)
+				assert.Equal(t, 57.2957795130823, *units[0].DisplayUnits[0].Factor)
+				assert.Equal(t, 0.0, *(*m.TypeDefinitions)[0].Real.Min)
+
+				vs := m.ModelVariables
+				assert.Len(t, vs, 8)
+				assert.Equal(t, VariableCausalityParameter, *vs[0].Causality)
+				assert.Equal(t, VariableVariabilityFixed, *vs[0].Variability)
+				assert.Equal(t, VariableTypeReal, vs[0].Type())
+				assert.Equal(t, "Modelica.SIunits.Inertia", vs[0].Real.DeclaredType.DeclaredType)
+				assert.Equal(t, 1.0, *vs[0].Real.Start)
+				assert.Equal(t, uint(536870912), vs[1].ValueReference)
+				assert.Equal(t, VariableInitialExact, *vs[4].Initial)
+				assert.Equal(t, 5.0, *vs[6].Real.Derivative)
+
+				unknowns := *m.ModelStructure.InitialUnknowns
+				assert.Len(t, unknowns, 4)
+				assert.Equal(t, UintAttributeList{5, 2}, unknowns[2].Dependencies)
+				assert.Len(t, *m.ModelStructure.Derivatives, 2)
+			},
+			// the specification example lists dependencies out of order
+			[]string{
+				"ScalarVariable 7 (der(x[1])): ModelStructure InitialUnknowns dependencies must be ordered by index without duplicates",
+			},
+		},
+		{
+			"Co-simulation specification example",
+			"SpringMassDamperCS.xml",
+			func(t *testing.T, m ModelDescription) {
+				assert.Nil(t, m.ModelExchange)
+				assert.Equal(t, "MyLibrary_SpringMassDamper", m.CoSimulation.ModelIdentifier)
+				assert.True(t, m.CoSimulation.CanInterpolateInputs)
+				assert.Len(t, m.ModelVariables, 4)
+				assert.Nil(t, m.ModelStructure.Derivatives)
+				assert.Len(t, *m.ModelStructure.Outputs, 2)
+			},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			m, err := ParseModelDescription(f)
+			if err != nil {
+				t.Fatalf("ParseModelDescription() error = %v", err)
+			}
+
+			bs, err := m.MarshallIndent()
+			if err != nil {
+				t.Fatalf("ModelDescription.MarshallIndent() error = %v", err)
+			}
+			got, err := ParseModelDescription(bytes.NewReader(bs))
+			if err != nil {
+				t.Fatalf("ParseModelDescription() of marshalled description error = %v", err)
+			}
+			assert.Equal(t, m, got)
+
+			tt.verify(t, m)
+			var errs []string
+			for _, err := range m.Validate() {
+				errs = append(errs, err.Error())
+			}
+			assert.Equal(t, tt.wantErrs, errs)
+		})
+	}
+}
+
+func TestParseModelDescription_RoundTrip(t *testing.T) {
+	want, err := ioutil.ReadFile(filepath.Join("testdata", "BouncingBall.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ParseModelDescription(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("ParseModelDescription() error = %v", err)
+	}
+	got, err := m.MarshallIndent()
+	if err != nil {
+		t.Fatalf("ModelDescription.MarshallIndent() error = %v", err)
+	}
+	assert.Equal(t, string(want), string(got))
+}
+
+func TestParseModelDescription_RoundTripNoDependencies(t *testing.T) {
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "BouncingBall.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := `<Unknown index="2" dependencies="3" dependenciesKind="constant"></Unknown>`
+	want := strings.Replace(string(bs), old, `<Unknown index="2" dependencies=""></Unknown>`, 1)
+	if want == string(bs) {
+		t.Fatalf("Unknown %s not found in testdata", old)
+	}
+	m, err := ParseModelDescription(strings.NewReader(want))
+	if err != nil {
+		t.Fatalf("ParseModelDescription() error = %v", err)
+	}
+	got, err := m.MarshallIndent()
+	if err != nil {
+		t.Fatalf("ModelDescription.MarshallIndent() error = %v", err)
+	}
+	assert.Equal(t, want, string(got))
+
+	// a Go model declares no feedthrough with an empty list
+	m = ModelDescription{
+		ModelStructure: ModelStructure{
+			Outputs: &[]Unknown{{Index: 1, Dependencies: UintAttributeList{}}, {Index: 2}},
+		},
+	}
+	if got, err = m.MarshallIndent(); err != nil {
+		t.Fatalf("ModelDescription.MarshallIndent() error = %v", err)
+	}
+	m, err = ParseModelDescription(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("ParseModelDescription() error = %v", err)
+	}
+	outputs := *m.ModelStructure.Outputs
+	if outputs[0].Dependencies == nil || len(outputs[0].Dependencies) != 0 {
+		t.Errorf("Dependencies = %#v, want empty list", outputs[0].Dependencies)
+	}
+	if outputs[1].Dependencies != nil {
+		t.Errorf("Dependencies = %#v, want nil", outputs[1].Dependencies)
+	}
+}
+
+func TestParseModelDescription_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		xml  string
+	}{
+		{
+			"Invalid XML",
+			`<fmiModelDescription>`,
+		},
+		{
+			"Unknown causality",
+			`<fmiModelDescription><ModelVariables><ScalarVariable causality="unknown"/></ModelVariables></fmiModelDescription>`,
+		},
+		{
+			"Invalid dependencies",
+			`<fmiModelDescription><ModelStructure><Outputs><Unknown index="1" dependencies="a"/></Outputs></ModelStructure></fmiModelDescription>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseModelDescription(strings.NewReader(tt.xml)); err == nil {
+				t.Errorf("ParseModelDescription() expected error")
+			}
+		})
+	}
+}
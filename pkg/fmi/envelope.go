@@ -0,0 +1,199 @@
+package fmi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	// stateMagic starts every FMU state returned by fmi2GetFMUstate
+	stateMagic = "GFMS"
+	// stateFormat is the layout of the envelope after the magic header
+	stateFormat = 1
+	// defaultStateEncoding names the encoding of models that do not implement StateEncodingNamer
+	defaultStateEncoding = "raw"
+)
+
+// StateVersioner can be implemented by models to version the layout of their encoded state.
+// States of other versions are rejected by fmi2SetFMUstate and fmi2DeSerializeFMUstate,
+// unless the model implements StateMigrator for older versions. Models without a version are version 0.
+type StateVersioner interface {
+	// StateVersion returns the version of states returned by Encode
+	StateVersion() uint32
+}
+
+// StateEncodingNamer can be implemented by models to name the encoding of their state, such as "gob".
+// States with another encoding of the same version are rejected.
+type StateEncodingNamer interface {
+	// StateEncoding returns the name of the encoding used by Encode
+	StateEncoding() string
+}
+
+// StateMigrator can be implemented by models to set states encoded by an older version of the model.
+type StateMigrator interface {
+	// MigrateState converts a state of an older version and encoding to the current version for Decode
+	MigrateState(version uint32, encoding string, state []byte) ([]byte, error)
+}
+
+/*
+stateEnvelope wraps the state encoded by the model so that a state set or deserialized
+by another model or another version of the model is rejected instead of decoded into garbage.
+The little endian layout is
+
+	magic "GFMS" | format uint8 | version uint32 | GUID length uint16 | GUID
+	| encoding length uint16 | encoding | state length uint32 | state | CRC-32 uint32
+
+where the checksum covers all preceding bytes.
+*/
+type stateEnvelope struct {
+	guid     string
+	version  uint32
+	encoding string
+	state    []byte
+}
+
+func (e stateEnvelope) marshal() []byte {
+	buf := &bytes.Buffer{}
+	buf.Grow(len(stateMagic) + 1 + 4 + 2 + len(e.guid) + 2 + len(e.encoding) + 4 + len(e.state) + 4)
+	buf.WriteString(stateMagic)
+	buf.WriteByte(stateFormat)
+	_ = binary.Write(buf, binary.LittleEndian, e.version)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(e.guid)))
+	buf.WriteString(e.guid)
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(e.encoding)))
+	buf.WriteString(e.encoding)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(e.state)))
+	buf.Write(e.state)
+	_ = binary.Write(buf, binary.LittleEndian, crc32.ChecksumIEEE(buf.Bytes()))
+	return buf.Bytes()
+}
+
+// unmarshalStateEnvelope reads the envelope of bs and checks its checksum
+func unmarshalStateEnvelope(bs []byte) (e stateEnvelope, err error) {
+	if len(bs) < len(stateMagic)+1 || string(bs[:len(stateMagic)]) != stateMagic {
+		return e, errors.New("FMU state has no state header, it was not returned by fmi2GetFMUstate")
+	}
+	if bs[len(stateMagic)] != stateFormat {
+		return e, fmt.Errorf("FMU state format %d is not supported, want format %d", bs[len(stateMagic)], stateFormat)
+	}
+	if len(bs) < len(stateMagic)+1+4 {
+		return e, errTruncatedState
+	}
+	body, sum := bs[:len(bs)-4], binary.LittleEndian.Uint32(bs[len(bs)-4:])
+	if c := crc32.ChecksumIEEE(body); c != sum {
+		return e, fmt.Errorf("FMU state checksum %08x does not match %08x, the state is corrupt", c, sum)
+	}
+
+	r := bytes.NewReader(body[len(stateMagic)+1:])
+	var guidLen, encodingLen uint16
+	var stateLen uint32
+	if err := binary.Read(r, binary.LittleEndian, &e.version); err != nil {
+		return e, errTruncatedState
+	}
+	if err := binary.Read(r, binary.LittleEndian, &guidLen); err != nil {
+		return e, errTruncatedState
+	}
+	guid, err := readStateBytes(r, int(guidLen))
+	if err != nil {
+		return e, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &encodingLen); err != nil {
+		return e, errTruncatedState
+	}
+	encoding, err := readStateBytes(r, int(encodingLen))
+	if err != nil {
+		return e, err
+	}
+	if err := binary.Read(r, binary.LittleEndian, &stateLen); err != nil {
+		return e, errTruncatedState
+	}
+	if e.state, err = readStateBytes(r, int(stateLen)); err != nil {
+		return e, err
+	}
+	if r.Len() != 0 {
+		return e, fmt.Errorf("FMU state has %d unexpected trailing bytes", r.Len())
+	}
+	e.guid = string(guid)
+	e.encoding = string(encoding)
+	return e, nil
+}
+
+var errTruncatedState = errors.New("FMU state is truncated")
+
+// readStateBytes reads the next n bytes of the envelope
+func readStateBytes(r *bytes.Reader, n int) ([]byte, error) {
+	if n > r.Len() {
+		return nil, errTruncatedState
+	}
+	bs := make([]byte, n)
+	_, _ = r.Read(bs)
+	return bs, nil
+}
+
+// stateSchema returns the version and encoding name of states encoded by the model
+func (f *FMU) stateSchema() (uint32, string) {
+	var version uint32
+	if v, ok := f.instance.(StateVersioner); ok {
+		version = v.StateVersion()
+	}
+	encoding := defaultStateEncoding
+	if n, ok := f.instance.(StateEncodingNamer); ok {
+		encoding = n.StateEncoding()
+	}
+	return version, encoding
+}
+
+// wrapState returns state encoded by the model in an envelope
+func (f *FMU) wrapState(state []byte) []byte {
+	version, encoding := f.stateSchema()
+	return stateEnvelope{
+		guid:     f.GUID,
+		version:  version,
+		encoding: encoding,
+		state:    state,
+	}.marshal()
+}
+
+// openState returns the envelope of bs if its state can be decoded by the model, possibly after migration
+func (f *FMU) openState(bs []byte) (stateEnvelope, error) {
+	e, err := unmarshalStateEnvelope(bs)
+	if err != nil {
+		return e, err
+	}
+	if e.guid != f.GUID {
+		return e, fmt.Errorf("FMU state of model with GUID %s cannot be set in model with GUID %s", e.guid, f.GUID)
+	}
+	version, encoding := f.stateSchema()
+	switch {
+	case e.version > version:
+		return e, fmt.Errorf("FMU state version %d is newer than model state version %d", e.version, version)
+	case e.version < version:
+		if _, ok := f.instance.(StateMigrator); !ok {
+			return e, fmt.Errorf("FMU state version %d is older than model state version %d and the model cannot migrate states",
+				e.version, version)
+		}
+	case e.encoding != encoding:
+		return e, fmt.Errorf("FMU state encoding %s does not match model state encoding %s", e.encoding, encoding)
+	}
+	return e, nil
+}
+
+// unwrapState returns the state in bs for Decode, migrated to the current version of the model
+func (f *FMU) unwrapState(bs []byte) ([]byte, error) {
+	e, err := f.openState(bs)
+	if err != nil {
+		return nil, err
+	}
+	version, _ := f.stateSchema()
+	if e.version == version {
+		return e.state, nil
+	}
+	state, err := f.instance.(StateMigrator).MigrateState(e.version, e.encoding, e.state)
+	if err != nil {
+		return nil, fmt.Errorf("Error migrating FMU state from version %d to %d: %w", e.version, version, err)
+	}
+	return state, nil
+}
@@ -0,0 +1,203 @@
+package fmi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// versionedInstance encodes its state with a version and encoding
+type versionedInstance struct {
+	ModelInstance
+	version  uint32
+	encoding string
+}
+
+func (v versionedInstance) StateVersion() uint32 {
+	return v.version
+}
+
+func (v versionedInstance) StateEncoding() string {
+	return v.encoding
+}
+
+// migratingInstance migrates states from version 1 onwards by appending the version
+type migratingInstance struct {
+	versionedInstance
+}
+
+func (m migratingInstance) MigrateState(version uint32, encoding string, state []byte) ([]byte, error) {
+	if version == 0 {
+		return nil, errors.New("Version 0 cannot be migrated")
+	}
+	return []byte(fmt.Sprintf("%s from %d %s", state, version, encoding)), nil
+}
+
+// withChecksum appends the checksum of an envelope to body
+func withChecksum(body []byte) []byte {
+	sum := make([]byte, 4)
+	binary.LittleEndian.PutUint32(sum, crc32.ChecksumIEEE(body))
+	return append(body, sum...)
+}
+
+func Test_unmarshalStateEnvelope(t *testing.T) {
+	want := stateEnvelope{
+		guid:     "GUID",
+		version:  2,
+		encoding: "gob",
+		state:    []byte("state"),
+	}
+	valid := want.marshal()
+	tests := []struct {
+		name string
+		bs   []byte
+		// err is part of the error message, empty if there is no error
+		err string
+	}{
+		{
+			"Envelope is read",
+			valid,
+			"",
+		},
+		{
+			"Empty state",
+			nil,
+			"no state header",
+		},
+		{
+			"State without header",
+			[]byte("state without header"),
+			"no state header",
+		},
+		{
+			"Unknown format",
+			append([]byte(stateMagic), 2, 0, 0, 0, 0),
+			"format 2 is not supported",
+		},
+		{
+			"Corrupt state",
+			func() []byte {
+				bs := append([]byte{}, valid...)
+				bs[len(bs)-6] ^= 1
+				return bs
+			}(),
+			"checksum",
+		},
+		{
+			"Truncated state",
+			withChecksum(append([]byte(stateMagic), stateFormat, 2, 0, 0, 0, 10, 0, 'G', 'U')),
+			"truncated",
+		},
+		{
+			"Trailing bytes",
+			withChecksum(append(append([]byte{}, valid[:len(valid)-4]...), 0)),
+			"1 unexpected trailing bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unmarshalStateEnvelope(tt.bs)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("unmarshalStateEnvelope() error = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unmarshalStateEnvelope() error = %v", err)
+			}
+			if got.guid != want.guid || got.version != want.version || got.encoding != want.encoding ||
+				!bytes.Equal(got.state, want.state) {
+				t.Errorf("unmarshalStateEnvelope() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestFMU_unwrapState(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    *FMU
+		to      *FMU
+		want    string
+		wantErr string
+	}{
+		{
+			"Same version",
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "gob"}},
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "gob"}},
+			"state",
+			"",
+		},
+		{
+			"Model without version",
+			&FMU{GUID: "GUID"},
+			&FMU{GUID: "GUID"},
+			"state",
+			"",
+		},
+		{
+			"Another model",
+			&FMU{GUID: "GUID"},
+			&FMU{GUID: "Other"},
+			"",
+			"FMU state of model with GUID GUID cannot be set in model with GUID Other",
+		},
+		{
+			"Newer version",
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 2, encoding: "gob"}},
+			&FMU{GUID: "GUID", instance: migratingInstance{versionedInstance{version: 1, encoding: "gob"}}},
+			"",
+			"FMU state version 2 is newer than model state version 1",
+		},
+		{
+			"Older version without migration",
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "gob"}},
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 2, encoding: "gob"}},
+			"",
+			"FMU state version 1 is older than model state version 2 and the model cannot migrate states",
+		},
+		{
+			"Another encoding",
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "json"}},
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "gob"}},
+			"",
+			"FMU state encoding json does not match model state encoding gob",
+		},
+		{
+			"Older version is migrated",
+			&FMU{GUID: "GUID", instance: versionedInstance{version: 1, encoding: "json"}},
+			&FMU{GUID: "GUID", instance: migratingInstance{versionedInstance{version: 2, encoding: "gob"}}},
+			"state from 1 json",
+			"",
+		},
+		{
+			"Migration error",
+			&FMU{GUID: "GUID"},
+			&FMU{GUID: "GUID", instance: migratingInstance{versionedInstance{version: 2, encoding: "gob"}}},
+			"",
+			"Error migrating FMU state from version 0 to 2: Version 0 cannot be migrated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.to.unwrapState(tt.from.wrapState([]byte("state")))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("unwrapState() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unwrapState() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("unwrapState() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
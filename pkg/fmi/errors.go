@@ -0,0 +1,88 @@
+package fmi
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+ModelError is an error returned by a model instance that sets the status of the fmi2 call.
+Errors that are not a ModelError return fmi2Error. Use Warning, Discard and Fatal to create one:
+
+	if u > max {
+		return fmi.Warning(fmt.Errorf("Input %f is above %f", u, max))
+	}
+
+The error is logged in the category of its status and can be wrapped with fmt.Errorf and %w.
+*/
+type ModelError struct {
+	// Status is returned by the fmi2 function
+	Status Status
+	// Err is logged by the fmi2 function
+	Err error
+}
+
+func (e *ModelError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ModelError) Unwrap() error {
+	return e.Err
+}
+
+// Warning returns err as a ModelError with fmi2Warning, the results of the call are still used
+func Warning(err error) error {
+	return newModelError(StatusWarning, err)
+}
+
+// Discard returns err as a ModelError with fmi2Discard, for example when a step could not be completed
+func Discard(err error) error {
+	return newModelError(StatusDiscard, err)
+}
+
+// Fatal returns err as a ModelError with fmi2Fatal, afterwards only FreeInstance can be called
+func Fatal(err error) error {
+	return newModelError(StatusFatal, err)
+}
+
+func newModelError(s Status, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ModelError{Status: s, Err: err}
+}
+
+// statusOf returns the status for err returned by a model instance
+func statusOf(err error) Status {
+	if err == nil {
+		return StatusOK
+	}
+	var me *ModelError
+	if errors.As(err, &me) {
+		return me.Status
+	}
+	return StatusError
+}
+
+// modelStatus logs err returned by the model in function name in the category of its status and returns the status
+func (f *FMU) modelStatus(name string, err error) Status {
+	s := statusOf(err)
+	switch s {
+	case StatusOK:
+	case StatusWarning:
+		f.logger.Warning(fmt.Sprintf("Warning calling %s: %v", name, err))
+	case StatusDiscard:
+		f.logger.Discard(fmt.Sprintf("Discard calling %s: %v", name, err))
+	case StatusFatal:
+		f.logger.Fatal(fmt.Errorf("Fatal error calling %s: %w", name, err))
+	default:
+		f.logger.Error(fmt.Errorf("Error calling %s: %w", name, err))
+		s = StatusError
+	}
+	return s
+}
+
+// succeeded returns true if the results of a call with status s can be used
+func succeeded(s Status) bool {
+	return s == StatusOK || s == StatusWarning
+}
@@ -0,0 +1,213 @@
+package fmi_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// statusInstance returns errors from the model wrapped by status
+type statusInstance struct {
+	mockInstance
+	status func(error) error
+}
+
+func (s statusInstance) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	fs, _ := s.mockInstance.GetReal(vr)
+	return fs, s.status(errors.New("GetReal"))
+}
+
+func (s statusInstance) SetReal(fmi.ValueReference, []float64) error {
+	return s.status(errors.New("SetReal"))
+}
+
+func (s statusInstance) DoStep(float64, float64, bool) (fmi.StepResult, error) {
+	// the error is wrapped to check the status is found with errors.As
+	return fmi.StepResultSuccess, fmt.Errorf("Step failed: %w", s.status(errors.New("DoStep")))
+}
+
+func init() {
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "Warning",
+		instance: &statusInstance{status: fmi.Warning},
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "Discard",
+		instance: &statusInstance{status: fmi.Discard},
+	})
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "Fatal",
+		instance: &statusInstance{status: fmi.Fatal},
+	})
+}
+
+// categoryLogger collects the categories of logged messages
+type categoryLogger struct {
+	categories []string
+}
+
+func (l *categoryLogger) log(status fmi.Status, category, message string) {
+	l.categories = append(l.categories, category)
+}
+
+func TestModelError(t *testing.T) {
+	tests := []struct {
+		name         string
+		guid         string
+		call         func(fmi.FMUID) fmi.Status
+		want         fmi.Status
+		wantState    fmi.ModelState
+		wantCategory string
+	}{
+		{
+			"GetReal warning returns values",
+			"Warning",
+			func(id fmi.FMUID) fmi.Status {
+				fs, s := fmi.GetReal(id, fmi.ValueReference{0, 1})
+				if len(fs) != 2 {
+					return fmi.StatusError
+				}
+				return s
+			},
+			fmi.StatusWarning,
+			fmi.ModelStateStepComplete,
+			"logStatusWarning",
+		},
+		{
+			"SetReal warning",
+			"Warning",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.SetReal(id, fmi.ValueReference{0}, []float64{1})
+			},
+			fmi.StatusWarning,
+			fmi.ModelStateStepComplete,
+			"logStatusWarning",
+		},
+		{
+			"SetReal discard",
+			"Discard",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.SetReal(id, fmi.ValueReference{0}, []float64{1})
+			},
+			fmi.StatusDiscard,
+			fmi.ModelStateStepComplete,
+			"logStatusDiscard",
+		},
+		{
+			"GetReal discard returns no values",
+			"Discard",
+			func(id fmi.FMUID) fmi.Status {
+				fs, s := fmi.GetReal(id, fmi.ValueReference{0, 1})
+				if fs != nil {
+					return fmi.StatusError
+				}
+				return s
+			},
+			fmi.StatusDiscard,
+			fmi.ModelStateStepComplete,
+			"logStatusDiscard",
+		},
+		{
+			"DoStep warning",
+			"Warning",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.DoStep(id, 0, 1, false)
+			},
+			fmi.StatusWarning,
+			fmi.ModelStateStepComplete,
+			"logStatusWarning",
+		},
+		{
+			"DoStep discard moves to step failed",
+			"Discard",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.DoStep(id, 0, 1, false)
+			},
+			fmi.StatusDiscard,
+			fmi.ModelStateStepFailed,
+			"logStatusDiscard",
+		},
+		{
+			"DoStep fatal moves to fatal",
+			"Fatal",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.DoStep(id, 0, 1, false)
+			},
+			fmi.StatusFatal,
+			fmi.ModelStateFatal,
+			"logStatusFatal",
+		},
+		{
+			"SetReal fatal moves to fatal",
+			"Fatal",
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.SetReal(id, fmi.ValueReference{0}, []float64{1})
+			},
+			fmi.StatusFatal,
+			fmi.ModelStateFatal,
+			"logStatusFatal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &categoryLogger{}
+			id := instantiateState(fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, tt.guid, "", false, l.log)),
+				fmi.ModelStateStepComplete)
+			if s := fmi.SetDebugLogging(id, true, nil); s != fmi.StatusOK {
+				t.Fatalf("SetDebugLogging() = %v", s)
+			}
+			if got := tt.call(id); got != tt.want {
+				t.Errorf("call = %v, want %v", got, tt.want)
+			}
+			if len(l.categories) != 1 || l.categories[0] != tt.wantCategory {
+				t.Errorf("Logged categories %v, want [%s]", l.categories, tt.wantCategory)
+			}
+			verifyFMUStateAndCleanUp(t, id, tt.wantState)
+		})
+	}
+}
+
+func TestModelError_Constructors(t *testing.T) {
+	err := errors.New("err")
+	tests := []struct {
+		name string
+		fn   func(error) error
+		want fmi.Status
+	}{
+		{
+			"Warning",
+			fmi.Warning,
+			fmi.StatusWarning,
+		},
+		{
+			"Discard",
+			fmi.Discard,
+			fmi.StatusDiscard,
+		},
+		{
+			"Fatal",
+			fmi.Fatal,
+			fmi.StatusFatal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(nil); got != nil {
+				t.Errorf("%s(nil) = %v, want nil", tt.name, got)
+			}
+			got := tt.fn(err)
+			var me *fmi.ModelError
+			if !errors.As(got, &me) || me.Status != tt.want {
+				t.Errorf("%s() = %#v, want status %v", tt.name, got, tt.want)
+			}
+			if !errors.Is(got, err) {
+				t.Errorf("%s() does not wrap %v", tt.name, err)
+			}
+			if got.Error() != err.Error() {
+				t.Errorf("%s().Error() = %s, want %s", tt.name, got.Error(), err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+package fmi
+
+// #include <stdlib.h>
+// #include "./c/fmi2Functions.h"
+// #include "bridge.h"
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+//export fmi2EnterEventMode
+func fmi2EnterEventMode(c C.fmi2Component) C.fmi2Status {
+	return C.fmi2Status(EnterEventMode(FMUID(c)))
+}
+
+/*
+EnterEventMode makes the model enter Event Mode from Continuous-Time Mode and discrete-time equations may become active
+(and relations are not “frozen”).
+*/
+func EnterEventMode(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "EnterEventMode")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+
+	return fmu.modelStatus("EnterEventMode", mexch.EnterEventMode())
+}
+
+//export fmi2NewDiscreteStates
+func fmi2NewDiscreteStates(c C.fmi2Component, fmi2eventInfo *C.fmi2EventInfo) C.fmi2Status {
+	if fmi2eventInfo == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "fmi2eventInfo"))
+	}
+
+	info, s := NewDiscreteStates(FMUID(c))
+	if !succeeded(s) {
+		return C.fmi2Status(s)
+	}
+
+	fmi2eventInfo.newDiscreteStatesNeeded = boolFMU(info.NewDiscreteStatesNeeded)
+	fmi2eventInfo.terminateSimulation = boolFMU(info.TerminateSimulation)
+	fmi2eventInfo.nominalsOfContinuousStatesChanged = boolFMU(info.NominalsOfContinuousStatesChanged)
+	fmi2eventInfo.valuesOfContinuousStatesChanged = boolFMU(info.ValuesOfContinuousStatesChanged)
+	fmi2eventInfo.nextEventTimeDefined = boolFMU(info.NextEventTimeDefined)
+	fmi2eventInfo.nextEventTime = C.fmi2Real(info.NextEventTime)
+	return C.fmi2Status(s)
+}
+
+/*
+NewDiscreteStates is called in Event Mode to compute the discrete states (the values of
+the discrete-time and of the continuous-time equations are updated).
+The FMU has to be called again with NewDiscreteStates as long as
+EventInfo.NewDiscreteStatesNeeded is true (event iteration). If
+EventInfo.TerminateSimulation is true, the environment should call Terminate.
+*/
+func NewDiscreteStates(id FMUID) (_ EventInfo, status Status) {
+	fmu, ok := allowedState(id, "NewDiscreteStates")
+	if !ok {
+		return EventInfo{}, StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return EventInfo{}, StatusError
+	}
+
+	info, err := mexch.NewDiscreteStates()
+	s := fmu.modelStatus("NewDiscreteStates", err)
+	if !succeeded(s) {
+		return EventInfo{}, s
+	}
+
+	if info.TerminateSimulation {
+		fmu.logger.Event("Model requested to terminate simulation")
+	}
+	return info, s
+}
+
+//export fmi2EnterContinuousTimeMode
+func fmi2EnterContinuousTimeMode(c C.fmi2Component) C.fmi2Status {
+	return C.fmi2Status(EnterContinuousTimeMode(FMUID(c)))
+}
+
+/*
+EnterContinuousTimeMode makes the model enter Continuous-Time Mode and all discrete-time
+equations become inactive and all relations are “frozen”.
+This function has to be called when changing from Event Mode into Continuous-Time Mode.
+*/
+func EnterContinuousTimeMode(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "EnterContinuousTimeMode")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+
+	return fmu.modelStatus("EnterContinuousTimeMode", mexch.EnterContinuousTimeMode())
+}
+
+//export fmi2CompletedIntegratorStep
+func fmi2CompletedIntegratorStep(c C.fmi2Component, noSetFMUStatePriorToCurrentPoint C.fmi2Boolean, enterEventMode, terminateSimulation *C.fmi2Boolean) C.fmi2Status {
+	if enterEventMode == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "enterEventMode"))
+	}
+	if terminateSimulation == nil {
+		return logError(c, fmt.Errorf("Invalid argument %s = NULL", "terminateSimulation"))
+	}
+
+	enter, terminate, s := CompletedIntegratorStep(FMUID(c), fmuBool(noSetFMUStatePriorToCurrentPoint))
+	if !succeeded(s) {
+		return C.fmi2Status(s)
+	}
+
+	*enterEventMode = boolFMU(enter)
+	*terminateSimulation = boolFMU(terminate)
+	return C.fmi2Status(s)
+}
+
+/*
+CompletedIntegratorStep must be called by the environment after every completed step of the
+integrator provided the capability flag completedIntegratorStepNotNeeded = false.
+Argument noSetFMUStatePriorToCurrentPoint is true if fmi2SetFMUState will no longer be called for
+time instants prior to current time in this simulation run.
+The function returns enterEventMode to signal to the environment if the FMU shall call
+EnterEventMode, and terminateSimulation to signal if the simulation shall be terminated.
+*/
+func CompletedIntegratorStep(id FMUID, noSetFMUStatePriorToCurrentPoint bool) (enterEventMode, terminateSimulation bool, s Status) {
+	fmu, ok := allowedState(id, "CompletedIntegratorStep")
+	if !ok {
+		return false, false, StatusError
+	}
+	defer fmu.release(&s)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return false, false, StatusError
+	}
+
+	enterEventMode, terminateSimulation, err = mexch.CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint)
+	s = fmu.modelStatus("CompletedIntegratorStep", err)
+	if !succeeded(s) {
+		return false, false, s
+	}
+
+	return enterEventMode, terminateSimulation, s
+}
+
+//export fmi2SetTime
+func fmi2SetTime(c C.fmi2Component, time C.fmi2Real) C.fmi2Status {
+	return C.fmi2Status(SetTime(FMUID(c), float64(time)))
+}
+
+/*
+SetTime sets a new time instant and re-initializes caching of variables that depend on time,
+provided the newly provided time value is different to the previously set time value
+(variables that depend solely on constants or parameters need not to be newly computed in the sequel,
+but the previously computed values can be reused).
+*/
+func SetTime(id FMUID, time float64) (status Status) {
+	fmu, ok := allowedState(id, "SetTime")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+
+	return fmu.modelStatus("SetTime", mexch.SetTime(time))
+}
+
+//export fmi2SetContinuousStates
+func fmi2SetContinuousStates(c C.fmi2Component, x C.fmi2Reals_t, nx C.size_t) C.fmi2Status {
+	xs, err := fmi2Reals(x, nx)
+	if err != nil {
+		return logError(c, err)
+	}
+
+	return C.fmi2Status(SetContinuousStates(FMUID(c), xs))
+}
+
+/*
+SetContinuousStates sets a new (continuous) state vector and re-initializes caching of variables
+that depend on the states. Argument nx is the length of vector x and is provided for checking
+purposes (variables that depend solely on constants, parameters, time, and inputs do not need to be
+newly computed in the sequel, but the previously computed values can be reused).
+*/
+func SetContinuousStates(id FMUID, x []float64) (status Status) {
+	fmu, ok := allowedState(id, "SetContinuousStates")
+	if !ok {
+		return StatusError
+	}
+	defer fmu.release(&status)
+
+	if len(x) != fmu.numberOfContinuousStates {
+		fmu.logger.Error(fmt.Errorf("SetContinuousStates expected %d states but got %d", fmu.numberOfContinuousStates, len(x)))
+		return StatusError
+	}
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+
+	return fmu.modelStatus("SetContinuousStates", mexch.SetContinuousStates(x))
+}
+
+//export fmi2GetDerivatives
+func fmi2GetDerivatives(c C.fmi2Component, derivatives *C.fmi2Real, nx C.size_t) C.fmi2Status {
+	return getRealVector(c, derivatives, nx, GetDerivatives)
+}
+
+/*
+GetDerivatives computes state derivatives at the current time instant and for the current states.
+The derivatives are returned as a vector with nx elements.
+*/
+func GetDerivatives(id FMUID, nx int) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetDerivatives")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+
+	return fmu.continuousStatesVector("GetDerivatives", nx, mexch.GetDerivatives)
+}
+
+//export fmi2GetEventIndicators
+func fmi2GetEventIndicators(c C.fmi2Component, eventIndicators *C.fmi2Real, ni C.size_t) C.fmi2Status {
+	return getRealVector(c, eventIndicators, ni, GetEventIndicators)
+}
+
+/*
+GetEventIndicators computes event indicators at the current time instant and for the current states.
+The event indicators are returned as a vector with ni elements. A state event is triggered when
+the domain of an event indicator changes from zj > 0 to zj ≤ 0 or vice versa.
+*/
+func GetEventIndicators(id FMUID, ni int) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetEventIndicators")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	if ni != fmu.numberOfEventIndicators {
+		fmu.logger.Error(fmt.Errorf("GetEventIndicators expected %d event indicators but got %d", fmu.numberOfEventIndicators, ni))
+		return nil, StatusError
+	}
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+
+	zs, err := mexch.GetEventIndicators()
+	s := fmu.modelStatus("GetEventIndicators", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+
+	if len(zs) != ni {
+		fmu.logger.Error(fmt.Errorf("GetEventIndicators returned %d event indicators but expected %d", len(zs), ni))
+		return nil, StatusError
+	}
+	return zs, s
+}
+
+//export fmi2GetContinuousStates
+func fmi2GetContinuousStates(c C.fmi2Component, x *C.fmi2Real, nx C.size_t) C.fmi2Status {
+	return getRealVector(c, x, nx, GetContinuousStates)
+}
+
+/*
+GetContinuousStates returns the new (continuous) state vector x.
+*/
+func GetContinuousStates(id FMUID, nx int) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetContinuousStates")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+
+	return fmu.continuousStatesVector("GetContinuousStates", nx, mexch.GetContinuousStates)
+}
+
+//export fmi2GetNominalsOfContinuousStates
+func fmi2GetNominalsOfContinuousStates(c C.fmi2Component, x_nominal *C.fmi2Real, nx C.size_t) C.fmi2Status {
+	return getRealVector(c, x_nominal, nx, GetNominalsOfContinuousStates)
+}
+
+/*
+GetNominalsOfContinuousStates returns the nominal values of the continuous states. This function
+should always be called after calling function NewDiscreteStates if
+EventInfo.NominalsOfContinuousStatesChanged is true, since then the nominal
+values of the continuous states have changed.
+*/
+func GetNominalsOfContinuousStates(id FMUID, nx int) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetNominalsOfContinuousStates")
+	if !ok {
+		return nil, StatusError
+	}
+	defer fmu.release(&status)
+
+	mexch, err := fmu.ModelExchanger()
+	if err != nil {
+		fmu.logger.Error(err)
+		return nil, StatusError
+	}
+
+	return fmu.continuousStatesVector("GetNominalsOfContinuousStates", nx, mexch.GetNominalsOfContinuousStates)
+}
+
+// continuousStatesVector checks nx against the number of continuous states before and after calling fn
+func (f *FMU) continuousStatesVector(name string, nx int, fn func() ([]float64, error)) ([]float64, Status) {
+	if nx != f.numberOfContinuousStates {
+		f.logger.Error(fmt.Errorf("%s expected %d continuous states but got %d", name, f.numberOfContinuousStates, nx))
+		return nil, StatusError
+	}
+
+	xs, err := fn()
+	s := f.modelStatus(name, err)
+	if !succeeded(s) {
+		return nil, s
+	}
+
+	if len(xs) != nx {
+		f.logger.Error(fmt.Errorf("%s returned %d values but expected %d", name, len(xs), nx))
+		return nil, StatusError
+	}
+	return xs, s
+}
+
+// getRealVector copies vector returned by fn into the C array
+func getRealVector(c C.fmi2Component, value *C.fmi2Real, n C.size_t, fn func(FMUID, int) ([]float64, Status)) C.fmi2Status {
+	if n > 0 && value == nil {
+		return logError(c, fmt.Errorf("fmi2Real array is null but size is %d", n))
+	}
+
+	var rs []C.fmi2Real
+	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&rs), int(n))
+	fs, s := fn(FMUID(c), int(n))
+	if !succeeded(s) {
+		return C.fmi2Status(s)
+	}
+	copyRealArray(fs, rs)
+	return C.fmi2Status(s)
+}
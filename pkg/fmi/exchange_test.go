@@ -0,0 +1,450 @@
+package fmi_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+func TestEnterEventMode(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      fmi.Status
+		wantState fmi.ModelState
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(),
+			},
+			fmi.StatusError,
+			fmi.ModelStateInstantiated,
+		},
+		{
+			"FMU type should be model exchange",
+			args{
+				id: instantiateDefault(fmi.ModelStateContinuousTimeMode),
+			},
+			fmi.StatusError,
+			fmi.ModelStateContinuousTimeMode,
+		},
+		{
+			"EnterEventMode error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateContinuousTimeMode),
+			},
+			fmi.StatusError,
+			fmi.ModelStateError,
+		},
+		{
+			"EnterEventMode is called",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+			},
+			fmi.StatusOK,
+			fmi.ModelStateEventMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmi.EnterEventMode(tt.args.id); got != tt.want {
+				t.Errorf("EnterEventMode() = %v, want %v", got, tt.want)
+			}
+			verifyFMUStateAndCleanUp(t, tt.args.id, tt.wantState)
+		})
+	}
+}
+
+func TestNewDiscreteStates(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      fmi.EventInfo
+		want1     fmi.Status
+		wantState fmi.ModelState
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+			},
+			fmi.EventInfo{},
+			fmi.StatusError,
+			fmi.ModelStateContinuousTimeMode,
+		},
+		{
+			"NewDiscreteStates error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateEventMode),
+			},
+			fmi.EventInfo{},
+			fmi.StatusError,
+			fmi.ModelStateError,
+		},
+		{
+			"Event info is returned",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+			},
+			fmi.EventInfo{
+				NextEventTimeDefined: true,
+				NextEventTime:        1,
+			},
+			fmi.StatusOK,
+			fmi.ModelStateEventMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := fmi.NewDiscreteStates(tt.args.id)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewDiscreteStates() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("NewDiscreteStates() got1 = %v, want %v", got1, tt.want1)
+			}
+			verifyFMUStateAndCleanUp(t, tt.args.id, tt.wantState)
+		})
+	}
+}
+
+func TestEnterContinuousTimeMode(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name      string
+		args      args
+		want      fmi.Status
+		wantState fmi.ModelState
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateInitializationMode),
+			},
+			fmi.StatusError,
+			fmi.ModelStateInitializationMode,
+		},
+		{
+			"EnterContinuousTimeMode error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateEventMode),
+			},
+			fmi.StatusError,
+			fmi.ModelStateError,
+		},
+		{
+			"EnterContinuousTimeMode is called",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+			},
+			fmi.StatusOK,
+			fmi.ModelStateContinuousTimeMode,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmi.EnterContinuousTimeMode(tt.args.id); got != tt.want {
+				t.Errorf("EnterContinuousTimeMode() = %v, want %v", got, tt.want)
+			}
+			verifyFMUStateAndCleanUp(t, tt.args.id, tt.wantState)
+		})
+	}
+}
+
+func TestCompletedIntegratorStep(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name                    string
+		args                    args
+		wantEnterEventMode      bool
+		wantTerminateSimulation bool
+		want                    fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+			},
+			false,
+			false,
+			fmi.StatusError,
+		},
+		{
+			"CompletedIntegratorStep error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateContinuousTimeMode),
+			},
+			false,
+			false,
+			fmi.StatusError,
+		},
+		{
+			"Event mode flag is returned",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+			},
+			true,
+			false,
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEnterEventMode, gotTerminateSimulation, got := fmi.CompletedIntegratorStep(tt.args.id, false)
+			if gotEnterEventMode != tt.wantEnterEventMode {
+				t.Errorf("CompletedIntegratorStep() enterEventMode = %v, want %v", gotEnterEventMode, tt.wantEnterEventMode)
+			}
+			if gotTerminateSimulation != tt.wantTerminateSimulation {
+				t.Errorf("CompletedIntegratorStep() terminateSimulation = %v, want %v", gotTerminateSimulation, tt.wantTerminateSimulation)
+			}
+			if got != tt.want {
+				t.Errorf("CompletedIntegratorStep() = %v, want %v", got, tt.want)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
+
+func TestSetTime(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+	}
+	tests := []struct {
+		name string
+		args args
+		want fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateInitializationMode),
+			},
+			fmi.StatusError,
+		},
+		{
+			"SetTime error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateEventMode),
+			},
+			fmi.StatusError,
+		},
+		{
+			"SetTime is called",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+			},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmi.SetTime(tt.args.id, 1); got != tt.want {
+				t.Errorf("SetTime() = %v, want %v", got, tt.want)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
+
+func TestSetContinuousStates(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+		x  []float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want fmi.Status
+	}{
+		{
+			"FMU state is invalid",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+				x:  []float64{1, 2},
+			},
+			fmi.StatusError,
+		},
+		{
+			"Number of states must match model derivatives",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+				x:  []float64{1},
+			},
+			fmi.StatusError,
+		},
+		{
+			"SetContinuousStates error is returned",
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateContinuousTimeMode),
+				x:  []float64{1, 2},
+			},
+			fmi.StatusError,
+		},
+		{
+			"SetContinuousStates is called",
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+				x:  []float64{1, 2},
+			},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fmi.SetContinuousStates(tt.args.id, tt.args.x); got != tt.want {
+				t.Errorf("SetContinuousStates() = %v, want %v", got, tt.want)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
+
+func TestGetContinuousStateVectors(t *testing.T) {
+	type args struct {
+		id fmi.FMUID
+		n  int
+	}
+	type getter func(fmi.FMUID, int) ([]float64, fmi.Status)
+	tests := []struct {
+		name   string
+		getter getter
+		args   args
+		want   []float64
+		want1  fmi.Status
+	}{
+		{
+			"GetDerivatives FMU state is invalid",
+			fmi.GetDerivatives,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateInitializationMode),
+				n:  2,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetDerivatives size must match number of derivatives",
+			fmi.GetDerivatives,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+				n:  3,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetDerivatives error is returned",
+			fmi.GetDerivatives,
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateContinuousTimeMode),
+				n:  2,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetDerivatives values are returned",
+			fmi.GetDerivatives,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateContinuousTimeMode),
+				n:  2,
+			},
+			[]float64{1, 2},
+			fmi.StatusOK,
+		},
+		{
+			"GetEventIndicators size must match number of event indicators",
+			fmi.GetEventIndicators,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+				n:  2,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetEventIndicators error is returned",
+			fmi.GetEventIndicators,
+			args{
+				id: instantiateModelExchangeErrors(fmi.ModelStateEventMode),
+				n:  1,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetEventIndicators values are returned",
+			fmi.GetEventIndicators,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateInitializationMode),
+				n:  1,
+			},
+			[]float64{1},
+			fmi.StatusOK,
+		},
+		{
+			"GetContinuousStates FMU type should be model exchange",
+			fmi.GetContinuousStates,
+			args{
+				id: instantiateDefault(fmi.ModelStateEventMode),
+				n:  0,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetContinuousStates values are returned",
+			fmi.GetContinuousStates,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateEventMode),
+				n:  2,
+			},
+			[]float64{3, 4},
+			fmi.StatusOK,
+		},
+		{
+			"GetNominalsOfContinuousStates FMU state is invalid",
+			fmi.GetNominalsOfContinuousStates,
+			args{
+				id: instantiateModelExchange(fmi.ModelStateInitializationMode),
+				n:  2,
+			},
+			nil,
+			fmi.StatusError,
+		},
+		{
+			"GetNominalsOfContinuousStates values are returned",
+			fmi.GetNominalsOfContinuousStates,
+			args{
+				id: instantiateModelExchange(),
+				n:  2,
+			},
+			[]float64{1, 1},
+			fmi.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := tt.getter(tt.args.id, tt.args.n)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("got1 = %v, want %v", got1, tt.want1)
+			}
+			fmi.FreeInstance(tt.args.id)
+		})
+	}
+}
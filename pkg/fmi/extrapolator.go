@@ -0,0 +1,98 @@
+package fmi
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+InputExtrapolator extrapolates real inputs over a communication step from the values and
+derivatives set at the communication point. Inputs are evaluated as the Taylor polynomial
+
+	u(tc + h) = u(tc) + u'(tc) h + u''(tc) h^2 / 2! + ...
+
+Co-simulators that set canInterpolateInputs can delegate SetReal and SetRealInputDerivatives to
+an InputExtrapolator, then call Extrapolate inside DoStep for the input at each internal time.
+InputExtrapolator is safe for concurrent use.
+*/
+type InputExtrapolator struct {
+	mu sync.Mutex
+	// coefficients by value reference, index 0 is the value and index n the n-th derivative
+	coefficients map[uint][]float64
+}
+
+// NewInputExtrapolator returns an extrapolator with no inputs set
+func NewInputExtrapolator() *InputExtrapolator {
+	return &InputExtrapolator{
+		coefficients: map[uint][]float64{},
+	}
+}
+
+// SetReal sets input values at the communication point.
+// Derivatives previously set for the inputs are cleared.
+func (e *InputExtrapolator) SetReal(vr ValueReference, value []float64) error {
+	if len(vr) != len(value) {
+		return fmt.Errorf("Length of value references %d must be same as values %d", len(vr), len(value))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, v := range vr {
+		e.coefficients[v] = []float64{value[i]}
+	}
+	return nil
+}
+
+// SetRealInputDerivatives sets the order[i] derivative of input vr[i] at the communication point.
+// Derivatives of lower order that have not been set are zero.
+func (e *InputExtrapolator) SetRealInputDerivatives(vr ValueReference, order []int32, value []float64) error {
+	if len(vr) != len(order) || len(vr) != len(value) {
+		return fmt.Errorf("Length of value references %d must be same as orders %d and values %d",
+			len(vr), len(order), len(value))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, v := range vr {
+		n := int(order[i])
+		if n < 1 {
+			return fmt.Errorf("Derivative order %d of value reference %d must be at least 1", n, v)
+		}
+		cs := e.coefficients[v]
+		for len(cs) <= n {
+			cs = append(cs, 0)
+		}
+		cs[n] = value[i]
+		e.coefficients[v] = cs
+	}
+	return nil
+}
+
+// Extrapolate returns inputs at time h after the communication point.
+// Inputs that have not been set are zero.
+func (e *InputExtrapolator) Extrapolate(vr ValueReference, h float64) []float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	fs := make([]float64, len(vr))
+	for i, v := range vr {
+		fs[i] = taylor(e.coefficients[v], h)
+	}
+	return fs
+}
+
+// Reset clears all inputs and derivatives
+func (e *InputExtrapolator) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.coefficients = map[uint][]float64{}
+}
+
+// taylor evaluates sum cs[n] h^n / n! using Horner's method
+func taylor(cs []float64, h float64) float64 {
+	var sum float64
+	for n := len(cs) - 1; n >= 0; n-- {
+		sum = cs[n] + sum*h/float64(n+1)
+	}
+	return sum
+}
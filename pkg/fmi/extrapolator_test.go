@@ -0,0 +1,143 @@
+package fmi_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+func TestInputExtrapolator_Extrapolate(t *testing.T) {
+	type input struct {
+		vr    fmi.ValueReference
+		order []int32
+		value []float64
+	}
+	tests := []struct {
+		name        string
+		values      []float64
+		derivatives []input
+		h           float64
+		want        []float64
+	}{
+		{
+			"Inputs not set are zero",
+			nil,
+			nil,
+			1,
+			[]float64{0, 0},
+		},
+		{
+			"Values are held without derivatives",
+			[]float64{1, 2},
+			nil,
+			1,
+			[]float64{1, 2},
+		},
+		{
+			"First order derivatives are linear",
+			[]float64{1, 2},
+			[]input{
+				{fmi.ValueReference{1, 2}, []int32{1, 1}, []float64{2, -1}},
+			},
+			0.5,
+			[]float64{2, 1.5},
+		},
+		{
+			"Higher orders are Taylor polynomial",
+			[]float64{1, 0},
+			[]input{
+				{fmi.ValueReference{1}, []int32{2}, []float64{2}},
+				{fmi.ValueReference{2}, []int32{3}, []float64{6}},
+			},
+			2,
+			[]float64{5, 8},
+		},
+		{
+			"Derivatives have no effect at communication point",
+			[]float64{1, 2},
+			[]input{
+				{fmi.ValueReference{1, 2}, []int32{1, 1}, []float64{2, -1}},
+			},
+			0,
+			[]float64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fmi.NewInputExtrapolator()
+			if tt.values != nil {
+				if err := e.SetReal(fmi.ValueReference{1, 2}, tt.values); err != nil {
+					t.Fatalf("SetReal() error = %v", err)
+				}
+			}
+			for _, d := range tt.derivatives {
+				if err := e.SetRealInputDerivatives(d.vr, d.order, d.value); err != nil {
+					t.Fatalf("SetRealInputDerivatives() error = %v", err)
+				}
+			}
+			if got := e.Extrapolate(fmi.ValueReference{1, 2}, tt.h); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extrapolate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInputExtrapolator_SetRealInputDerivatives(t *testing.T) {
+	tests := []struct {
+		name    string
+		vr      fmi.ValueReference
+		order   []int32
+		value   []float64
+		wantErr bool
+	}{
+		{
+			"Lengths must match",
+			fmi.ValueReference{1},
+			[]int32{1, 2},
+			[]float64{1},
+			true,
+		},
+		{
+			"Order must be at least one",
+			fmi.ValueReference{1},
+			[]int32{0},
+			[]float64{1},
+			true,
+		},
+		{
+			"Derivatives are set",
+			fmi.ValueReference{1},
+			[]int32{4},
+			[]float64{1},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := fmi.NewInputExtrapolator()
+			if err := e.SetRealInputDerivatives(tt.vr, tt.order, tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("SetRealInputDerivatives() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInputExtrapolator_SetRealClearsDerivatives(t *testing.T) {
+	e := fmi.NewInputExtrapolator()
+	_ = e.SetReal(fmi.ValueReference{1}, []float64{1})
+	_ = e.SetRealInputDerivatives(fmi.ValueReference{1}, []int32{1}, []float64{1})
+	_ = e.SetReal(fmi.ValueReference{1}, []float64{2})
+	if got := e.Extrapolate(fmi.ValueReference{1}, 1); got[0] != 2 {
+		t.Errorf("Extrapolate() = %v, want %v", got, []float64{2})
+	}
+}
+
+func TestInputExtrapolator_Reset(t *testing.T) {
+	e := fmi.NewInputExtrapolator()
+	_ = e.SetReal(fmi.ValueReference{1}, []float64{1})
+	e.Reset()
+	if got := e.Extrapolate(fmi.ValueReference{1}, 1); got[0] != 0 {
+		t.Errorf("Extrapolate() = %v, want %v", got, []float64{0})
+	}
+}
@@ -8,26 +8,26 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"sync"
 	"unsafe"
 )
 
 var (
 	fmiVersion       = C.CString(C.fmi2Version)
 	fmiTypesPlatform = C.CString(C.fmi2TypesPlatform)
-	// fmus stores all active FMUs at runtime
-	fmus = map[FMUID]*FMU{}
-	// models stores registered models
-	models = map[string]Model{}
+	// fmus stores all active FMUs at runtime, guarded by fmusMu
+	fmus   = map[FMUID]*FMU{}
+	fmusMu sync.RWMutex
+	// models stores registered models, guarded by modelsMu
+	models   = map[string]Model{}
+	modelsMu sync.RWMutex
 )
 
 // FMUID holds a simple pointer that can be shared from this library to the calling system
 // The id is mapped internally to the actual FMU stored in Go memory
 type FMUID uintptr
 
-func (f FMUID) asFMI2Component() C.fmi2Component {
-	return C.fmi2Component(f)
-}
-
 // RegisterModel registers a model implementation and description with this FMI implementation.
 // Multiple separate models can be registered, as long as they have different GUIDs.
 // When Instantiated, the model will be looked up by GUID in the generated modelDescription.xml file in the FMI.
@@ -37,6 +37,8 @@ func RegisterModel(model Model) error {
 		return errors.New("Model description GUID cannot be empty")
 	}
 
+	modelsMu.Lock()
+	defer modelsMu.Unlock()
 	if _, got := models[desc.GUID]; got {
 		return fmt.Errorf("Model for GUID %s already registered", desc.GUID)
 	}
@@ -45,6 +47,14 @@ func RegisterModel(model Model) error {
 	return nil
 }
 
+// registeredModel looks up the model registered for guid
+func registeredModel(guid string) (Model, bool) {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+	model, ok := models[guid]
+	return model, ok
+}
+
 //export fmi2GetVersion
 func fmi2GetVersion() C.fmi2String {
 	return fmiVersion
@@ -91,12 +101,26 @@ func fmi2Instantiate(instanceName C.fmi2String, fmuType C.fmi2Type, fmuGUID C.fm
 		defer C.free(unsafe.Pointer(m))
 		C.bridge_fmi2CallbackLogger(functions.logger, functions.componentEnvironment, n, C.fmi2Status(status), c, m)
 	}
-	return Instantiate(
+	c := instantiate(
 		name,
 		FMUType(fmuType),
 		C.GoString(fmuGUID),
 		C.GoString(fmuResourceLocation),
-		fmuBool(loggingOn), logger)
+		fmuBool(loggingOn), logger, newAllocator(functions))
+	if c == nil || functions.stepFinished == nil {
+		return c
+	}
+
+	fmu, err := GetFMU(FMUID(c))
+	if err != nil {
+		return nil
+	}
+	fmu.calls.Lock()
+	fmu.stepFinished = func(status Status) {
+		C.bridge_fmi2StepFinished(functions.stepFinished, functions.componentEnvironment, C.fmi2Status(status))
+	}
+	fmu.calls.Unlock()
+	return c
 }
 
 /*
@@ -135,8 +159,9 @@ indicate the location to the "resources" directory of the unzipped FMU archive.
 needed resources from this directory, for example maps or tables used by the FMU.]
 
 Argument `functions` provides callback functions to be used from the FMU functions to
-utilize resources from the environment. Only logging is implemented here.
-Memory management callbacks will be removed in FMI v3.0.
+utilize resources from the environment. If allocateMemory and freeMemory are set they allocate
+the memory returned to the environment: the component, FMU states and strings.
+Instantiate uses the C allocator.
 
 Argument visible = fmi2False defines that the interaction with the user should be
 reduced to a minimum (no application window, no plotting, no animation, etc.). In other
@@ -151,16 +176,24 @@ debugging according to this argument. Which LogCategories the FMU sets is unspec
 */
 func Instantiate(instanceName string, fmuType FMUType, fmuGUID string,
 	fmuResourceLocation string, loggingOn bool, logFn LoggerCallback) C.fmi2Component {
-	id := FMUID(C.malloc(1))
+	return instantiate(instanceName, fmuType, fmuGUID, fmuResourceLocation, loggingOn, logFn, cAllocator{})
+}
+
+// instantiate is Instantiate with memory returned to the environment allocated by memory
+func instantiate(instanceName string, fmuType FMUType, fmuGUID string,
+	fmuResourceLocation string, loggingOn bool, logFn LoggerCallback, memory allocator) (c C.fmi2Component) {
 	fmu := &FMU{
 		Name:             instanceName,
 		Typee:            fmuType,
 		GUID:             fmuGUID,
 		ResourceLocation: fmuResourceLocation,
 		State:            ModelStateInstantiated,
+		memory:           memory,
+		strings:          stringArena{memory: memory},
+		states:           statePool{memory: memory},
 	}
-	// log errors by default
-	loggingMask := loggerCategoryError
+	// log errors and fatal errors by default
+	loggingMask := loggerCategoryError | loggerCategoryFatal
 	// loggingOn means log events
 	if loggingOn {
 		loggingMask |= loggerCategoryEvents
@@ -170,32 +203,64 @@ func Instantiate(instanceName string, fmuType FMUType, fmuGUID string,
 		fmiCallbackLogger: logFn,
 	}
 
+	handle := memory.alloc(1)
+	if handle == nil {
+		fmu.logger.Error(errors.New("Out of memory allocating instance"))
+		return nil
+	}
+	id := FMUID(uintptr(handle))
+	fmu.handle = handle
+	// a panic before the FMU is registered fails instantiation
+	defer func() {
+		if r := recover(); r != nil {
+			fmu.logger.Fatal(panicError("Instantiate", r))
+			memory.free(handle)
+			c = nil
+		}
+	}()
+
 	if fmu.Name == "" {
 		fmu.logger.Error(errors.New("Missing instance name"))
+		memory.free(handle)
 		return nil
 	}
 
 	if fmu.GUID == "" {
 		fmu.logger.Error(errors.New("Missing GUID"))
+		memory.free(handle)
 		return nil
 	}
 
-	model, ok := models[fmu.GUID]
+	model, ok := registeredModel(fmu.GUID)
 	if !ok {
 		fmu.logger.Error(fmt.Errorf("GUID %s does not match any registered model", fmu.GUID))
+		memory.free(handle)
 		return nil
 	}
 
 	instance, err := model.Instantiate(fmu.logger)
 	if err != nil {
 		fmu.logger.Error(fmt.Errorf("Error instantiating model: %w", err))
+		memory.free(handle)
 		return nil
 	}
 	fmu.instance = instance
 
+	desc := model.Description()
+	fmu.description = desc
+	if desc.ModelStructure.Derivatives != nil {
+		fmu.numberOfContinuousStates = len(*desc.ModelStructure.Derivatives)
+	}
+	fmu.numberOfEventIndicators = int(desc.NumberOfEventIndicators)
+	if desc.CoSimulation != nil {
+		fmu.asynchronous = desc.CoSimulation.CanRunAsynchronuously
+	}
+
+	fmusMu.Lock()
 	fmus[id] = fmu
+	fmusMu.Unlock()
 
-	return C.fmi2Component(id)
+	return C.fmi2Component(handle)
 }
 
 //export fmi2FreeInstance
@@ -205,22 +270,40 @@ and other resources that have been allocated by the functions of the FMU interfa
 pointer is provided for `c`, the function call is ignored (does not have an effect).
 */
 func fmi2FreeInstance(c C.fmi2Component) {
-	if c == nil {
+	FreeInstance(FMUID(c))
+}
+
+// FreeInstance is a wrapper for fmi2FreeInstance
+func FreeInstance(id FMUID) {
+	if id == 0 {
 		return
 	}
 
-	id, _, err := getFMU(c)
+	fmu, err := GetFMU(id)
 	if err != nil {
 		return
 	}
 
-	delete(fmus, id)
-	C.free(unsafe.Pointer(id))
-}
+	// wait for calls in progress on other threads before freeing
+	fmu.calls.Lock()
+	defer fmu.calls.Unlock()
+	if fmu.freed {
+		return
+	}
+	fmu.freed = true
 
-// FreeInstance is a wrapper for fmi2FreeInstance
-func FreeInstance(id FMUID) {
-	fmi2FreeInstance(C.fmi2Component(id))
+	// the model and callbacks must not be used by a pending step once the instance is freed
+	if fmu.cancelStep() {
+		fmu.logger.Event("DoStep was cancelled before freeing the instance")
+	}
+	fmu.waitStep()
+
+	fmusMu.Lock()
+	delete(fmus, id)
+	fmusMu.Unlock()
+	fmu.strings.free()
+	fmu.states.free()
+	fmu.memory.free(fmu.handle)
 }
 
 //export fmi2SetDebugLogging
@@ -248,15 +331,12 @@ none, some or all allowed values for categories for this FMU are defined in the
 modelDescription.xml file via element `fmiModelDescription.LogCategories `.
 Supported log categories are in `logger.go`.
 */
-func SetDebugLogging(id FMUID, loggingOn bool, categories []string) Status {
-	const expected = ModelStateInstantiated | ModelStateInitializationMode |
-		ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete | ModelStateStepInProgress | ModelStateStepFailed | ModelStateStepCanceled |
-		ModelStateTerminated | ModelStateError
-	fmu, ok := allowedState(id, "SetDebugLogging", expected)
+func SetDebugLogging(id FMUID, loggingOn bool, categories []string) (status Status) {
+	fmu, ok := allowedState(id, "SetDebugLogging")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 	if !loggingOn {
 		fmu.logger.setMask(loggerCategoryNone)
 		return StatusOK
@@ -319,20 +399,23 @@ return `fmi2Status = fmi2Error`. If `stopTimeDefined = fmi2False`, then no final
 of the independent variable is defined and argument stopTime is meaningless.
 */
 func SetupExperiment(id FMUID, toleranceDefined bool, tolerance float64,
-	startTime float64, stopTimeDefined bool, stopTime float64) Status {
-	const expected = ModelStateInstantiated
-	fmu, ok := allowedState(id, "SetupExperiment", expected)
+	startTime float64, stopTimeDefined bool, stopTime float64) (status Status) {
+	fmu, ok := allowedState(id, "SetupExperiment")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
-	if err := fmu.instance.SetupExperiment(
-		toleranceDefined, tolerance, startTime, stopTimeDefined, stopTime); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling SetupExperiment: %w", err))
-		return StatusError
+	s := fmu.modelStatus("SetupExperiment", fmu.instance.SetupExperiment(
+		toleranceDefined, tolerance, startTime, stopTimeDefined, stopTime))
+	if !succeeded(s) {
+		return s
 	}
 	fmu.startTime = startTime
-	return StatusOK
+	fmu.step = stepStatus{
+		lastSuccessfulTime: startTime,
+	}
+	return s
 }
 
 //export fmi2EnterInitializationMode
@@ -347,20 +430,14 @@ attribute < ScalarVariable initial = "exact" or "approx"> can be set with the
 Setting other variables is not allowed. Furthermore, fmi2SetupExperiment must be called at least once before calling
 fmi2EnterInitializationMode, in order that startTime is defined.
 */
-func EnterInitializationMode(id FMUID) Status {
-	const expected = ModelStateInstantiated
-	fmu, ok := allowedState(id, "EnterInitializationMode", expected)
+func EnterInitializationMode(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "EnterInitializationMode")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
-	if err := fmu.instance.EnterInitializationMode(); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling EnterInitializationMode: %w", err))
-		return StatusError
-	}
-	fmu.State = ModelStateInitializationMode
-
-	return StatusOK
+	return fmu.modelStatus("EnterInitializationMode", fmu.instance.EnterInitializationMode())
 }
 
 //export fmi2ExitInitializationMode
@@ -374,25 +451,14 @@ For fmuType = fmi2ModelExchange , this function switches off all initialization
 and the FMU enters Event Mode implicitly; that is, all continuous-time and active discrete-
 time equations are available.
 */
-func ExitInitializationMode(id FMUID) Status {
-	const expected = ModelStateInitializationMode
-	fmu, ok := allowedState(id, "ExitInitializationMode", expected)
+func ExitInitializationMode(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "ExitInitializationMode")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
-	if err := fmu.instance.ExitInitializationMode(); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling ExitInitializationMode: %w", err))
-		return StatusError
-	}
-
-	if fmu.Typee == FMUTypeModelExchange {
-		fmu.State = ModelStateEventMode
-	} else {
-		fmu.State = ModelStateStepComplete
-	}
-
-	return StatusOK
+	return fmu.modelStatus("ExitInitializationMode", fmu.instance.ExitInitializationMode())
 }
 
 //export fmi2Terminate
@@ -406,21 +472,14 @@ values of all variables can be inquired with the fmi2GetXXX(..) functions. It is
 to call this function after one of the functions returned with a status flag of fmi2Error or
 fmi2Fatal .
 */
-func Terminate(id FMUID) Status {
-	const expected = ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete | ModelStateStepFailed
-	fmu, ok := allowedState(id, "Terminate", expected)
+func Terminate(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "Terminate")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
-	if err := fmu.instance.Terminate(); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling Terminate: %w", err))
-		return StatusError
-	}
-
-	fmu.State = ModelStateTerminated
-	return StatusOK
+	return fmu.modelStatus("Terminate", fmu.instance.Terminate())
 }
 
 //export fmi2Reset
@@ -434,95 +493,20 @@ same state as if fmi2Instantiate would have been called. All variables have thei
 values. Before starting a new run, fmi2SetupExperiment and
 fmi2EnterInitializationMode have to be called.
 */
-func Reset(id FMUID) Status {
-	const expected = ModelStateInstantiated | ModelStateInitializationMode |
-		ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete | ModelStateStepFailed | ModelStateStepCanceled |
-		ModelStateTerminated | ModelStateError
-	fmu, ok := allowedState(id, "Reset", expected)
+func Reset(id FMUID) (status Status) {
+	fmu, ok := allowedState(id, "Reset")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
-	if err := fmu.instance.Reset(); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling Reset: %w", err))
-		return StatusError
+	s := fmu.modelStatus("Reset", fmu.instance.Reset())
+	if !succeeded(s) {
+		return s
 	}
 
-	fmu.State = ModelStateInstantiated
-	return StatusOK
-}
-
-//export fmi2EnterEventMode
-func fmi2EnterEventMode(c C.fmi2Component) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2NewDiscreteStates
-func fmi2NewDiscreteStates(c C.fmi2Component, fmi2eventInfo *C.fmi2EventInfo) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2EnterContinuousTimeMode
-func fmi2EnterContinuousTimeMode(c C.fmi2Component) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2CompletedIntegratorStep
-func fmi2CompletedIntegratorStep(c C.fmi2Component, noSetFMUStatePriorToCurrentPoint C.fmi2Boolean, enterEventMode, terminateSimulation *C.fmi2Boolean) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2SetTime
-func fmi2SetTime(c C.fmi2Component, time C.fmi2Real) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2SetContinuousStates
-func fmi2SetContinuousStates(c C.fmi2Component, x C.fmi2Reals_t, nx C.size_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetDerivatives
-func fmi2GetDerivatives(c C.fmi2Component, derivatives *C.fmi2Real, nx C.size_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetEventIndicators
-func fmi2GetEventIndicators(c C.fmi2Component, eventIndicators *C.fmi2Real, ni C.size_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetContinuousStates
-func fmi2GetContinuousStates(c C.fmi2Component, x *C.fmi2Real, nx C.size_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetNominalsOfContinuousStates
-func fmi2GetNominalsOfContinuousStates(c C.fmi2Component, x_nominal *C.fmi2Real, nx C.size_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2SetRealInputDerivatives
-func fmi2SetRealInputDerivatives(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, order C.fmi2Integers_t, value C.fmi2Reals_t) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetRealOutputDerivatives
-func fmi2GetRealOutputDerivatives(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, order C.fmi2Integers_t, value *C.fmi2Real) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
+	fmu.step = stepStatus{}
+	return s
 }
 
 //export fmi2DoStep
@@ -574,12 +558,12 @@ wait until the callback function fmi2StepFinished is called by the slave. fmi2Ca
 can be called to cancel the current computation. It is not allowed to call any other function
 during a pending DoStep.
 */
-func DoStep(id FMUID, currentCommunicationPoint, communicationStepSize float64, noSetFMUStatePriorToCurrentPoint bool) Status {
-	const expected = ModelStateStepComplete
-	fmu, ok := allowedState(id, "DoStep", expected)
+func DoStep(id FMUID, currentCommunicationPoint, communicationStepSize float64, noSetFMUStatePriorToCurrentPoint bool) (status Status) {
+	fmu, ok := allowedState(id, "DoStep")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	if communicationStepSize <= 0 {
 		fmu.logger.Error(fmt.Errorf("DoStep communication step size must be > 0 but was %f.", communicationStepSize))
@@ -592,50 +576,19 @@ func DoStep(id FMUID, currentCommunicationPoint, communicationStepSize float64,
 		return StatusError
 	}
 
+	if fmu.asynchronous {
+		return fmu.doStepAsync(cosim,
+			currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint)
+	}
+
 	res, err := cosim.DoStep(
 		currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint)
+	s := res.Status()
 	if err != nil {
-		fmu.logger.Error(fmt.Errorf("Error running DoStep: %w", err))
-		return StatusError
+		s = fmu.modelStatus("DoStep", err)
 	}
-
-	return res.Status()
-}
-
-//export fmi2CancelStep
-func fmi2CancelStep(c C.fmi2Component) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetStatus
-func fmi2GetStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Status) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetRealStatus
-func fmi2GetRealStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Real) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetIntegerStatus
-func fmi2GetIntegerStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Integer) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetBooleanStatus
-func fmi2GetBooleanStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2Boolean) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
-}
-
-//export fmi2GetStringStatus
-func fmi2GetStringStatus(c C.fmi2Component, s C.fmi2StatusKind, value *C.fmi2String) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
+	fmu.completeStep(cosim, currentCommunicationPoint+communicationStepSize, s)
+	return s
 }
 
 func getFMU(c C.fmi2Component) (id FMUID, fmu *FMU, err error) {
@@ -644,27 +597,90 @@ func getFMU(c C.fmi2Component) (id FMUID, fmu *FMU, err error) {
 	return
 }
 
+// GetFMU looks up an active FMU instance.
+// Calls to the instance are not serialised, functions of the FMI interface use allowedState instead.
 func GetFMU(id FMUID) (*FMU, error) {
+	fmusMu.RLock()
 	fmu, ok := fmus[id]
+	fmusMu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("FMU %v not found", id)
 	}
 	return fmu, nil
 }
 
-func allowedState(id FMUID, name string, expected ModelState) (*FMU, bool) {
+/*
+allowedState looks up the FMU and checks function name can be called in its state, see transitions.
+Calls to the same instance are serialised: if the call is allowed the FMU is returned locked
+and the caller must release it when done. Calls to different instances run concurrently.
+*/
+func allowedState(id FMUID, name string) (*FMU, bool) {
 	fmu, err := GetFMU(id)
 	if err != nil {
 		return nil, false
 	}
 
-	if fmu.State&expected == 0 {
-		fmu.logger.Error(fmt.Errorf("Illegal call sequence at %s", name))
+	fmu.calls.Lock()
+	if fmu.freed {
+		fmu.calls.Unlock()
 		return nil, false
 	}
+
+	fmu.mu.Lock()
+	state := fmu.State
+	fmu.mu.Unlock()
+
+	if state == ModelStateFatal {
+		fmu.logger.Error(fmt.Errorf("%s called after a fatal error, only FreeInstance is allowed", name))
+		fmu.calls.Unlock()
+		return nil, false
+	}
+	if t, ok := fmu.transitions()[name]; !ok || state&t.from == 0 {
+		fmu.logger.Error(fmt.Errorf("Illegal call sequence at %s in state %v", name, state))
+		fmu.calls.Unlock()
+		return nil, false
+	}
+	fmu.call = name
 	return fmu, true
 }
 
+/*
+release ends a call allowed by allowedState and moves the FMU to the next state for the returned status.
+It must be deferred so that a panic in the call is recovered instead of unwinding into the calling C code:
+the panic is logged with its stack trace and status is set to StatusFatal.
+*/
+func (f *FMU) release(status *Status) {
+	if r := recover(); r != nil {
+		f.logger.Fatal(panicError(f.call, r))
+		*status = StatusFatal
+	}
+	f.finish(*status)
+}
+
+// releaseC is release for fmi2 functions that return a C status
+func (f *FMU) releaseC(status *C.fmi2Status) {
+	if r := recover(); r != nil {
+		f.logger.Fatal(panicError(f.call, r))
+		*status = C.fmi2Fatal
+	}
+	f.finish(Status(*status))
+}
+
+// finish moves the FMU to the next state for status s of the current call and unlocks it
+func (f *FMU) finish(s Status) {
+	// a pending step moves state before it starts, as it can finish before the call returns
+	if s != StatusPending {
+		f.mu.Lock()
+		f.State = f.nextState(f.call, s)
+		f.mu.Unlock()
+	}
+	f.calls.Unlock()
+}
+
+func panicError(name string, r interface{}) error {
+	return fmt.Errorf("Panic in %s: %v\n%s", name, r, debug.Stack())
+}
+
 func logError(c C.fmi2Component, err error) C.fmi2Status {
 	_, fmu, e := getFMU(c)
 	if e != nil {
@@ -675,21 +691,6 @@ func logError(c C.fmi2Component, err error) C.fmi2Status {
 	return C.fmi2Error
 }
 
-func allowedGetValue(id FMUID, name string) (*FMU, bool) {
-	const expected = ModelStateInitializationMode |
-		ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete | ModelStateStepFailed | ModelStateStepCanceled |
-		ModelStateTerminated | ModelStateError
-	return allowedState(id, name, expected)
-}
-
-func allowedSetValue(id FMUID, name string) (*FMU, bool) {
-	const expected = ModelStateInstantiated | ModelStateInitializationMode |
-		ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete
-	return allowedState(id, name, expected)
-}
-
 func carrayToSlice(carray unsafe.Pointer, slice unsafe.Pointer, len int) {
 	sliceHeader := (*reflect.SliceHeader)(slice)
 	sliceHeader.Cap = len
@@ -771,8 +772,8 @@ func copyBooleanArray(vs []bool, bs []C.fmi2Boolean) {
 	}
 }
 
-func copyStringArray(vs []string, ss []C.fmi2String) {
+func copyStringArray(vs []*C.char, ss []C.fmi2String) {
 	for i, v := range vs {
-		ss[i] = C.CString(v)
+		ss[i] = v
 	}
 }
@@ -2,7 +2,10 @@ package fmi_test
 
 import (
 	"errors"
+	"runtime"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"unsafe"
 
@@ -13,9 +16,16 @@ import (
 
 type mockModel struct {
 	fmi.Model
-	guid     string
-	err      bool
-	instance fmi.ModelInstance
+	guid            string
+	err             bool
+	instance        fmi.ModelInstance
+	states          uint
+	eventIndicators uint
+	async           bool
+	derivatives     bool
+	interpolate     bool
+	outputOrder     uint
+	variables       []fmi.ScalarVariable
 }
 
 type mockInstance struct {
@@ -25,9 +35,26 @@ type mockInstance struct {
 }
 
 func (m mockModel) Description() fmi.ModelDescription {
-	return fmi.ModelDescription{
-		GUID: m.guid,
+	desc := fmi.ModelDescription{
+		GUID:                    m.guid,
+		NumberOfEventIndicators: m.eventIndicators,
+		ModelVariables:          m.variables,
 	}
+	if m.states > 0 {
+		ds := make([]fmi.Unknown, m.states)
+		desc.ModelStructure.Derivatives = &ds
+	}
+	if m.async || m.derivatives || m.interpolate || m.outputOrder > 0 {
+		desc.CoSimulation = &fmi.CoSimulation{
+			FMUShared: fmi.FMUShared{
+				ProvidesDirectionalDerivative: m.derivatives,
+			},
+			CanInterpolateInputs:     m.interpolate,
+			MaxOutputDerivativeOrder: m.outputOrder,
+			CanRunAsynchronuously:    m.async,
+		}
+	}
+	return desc
 }
 
 func (m mockModel) Instantiate(l fmi.Logger) (fmi.ModelInstance, error) {
@@ -138,6 +165,82 @@ func (m mockInstance) DoStep(
 	return m.stepResult, nil
 }
 
+// maxStepInstance computes a partial step if the step size is larger than maxStep
+type maxStepInstance struct {
+	mockInstance
+	maxStep float64
+}
+
+func (m maxStepInstance) DoStep(
+	currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
+	if communicationStepSize > m.maxStep {
+		return fmi.StepResultPartial, nil
+	}
+	return fmi.StepResultSuccess, nil
+}
+
+func (m mockInstance) EnterEventMode() error {
+	return m.errOrNil("EnterEventMode")
+}
+
+func (m mockInstance) NewDiscreteStates() (fmi.EventInfo, error) {
+	if m.err {
+		return fmi.EventInfo{}, errors.New("NewDiscreteStates")
+	}
+	return fmi.EventInfo{
+		NextEventTimeDefined: true,
+		NextEventTime:        1,
+	}, nil
+}
+
+func (m mockInstance) EnterContinuousTimeMode() error {
+	return m.errOrNil("EnterContinuousTimeMode")
+}
+
+func (m mockInstance) CompletedIntegratorStep(bool) (bool, bool, error) {
+	if m.err {
+		return false, false, errors.New("CompletedIntegratorStep")
+	}
+	return true, false, nil
+}
+
+func (m mockInstance) SetTime(float64) error {
+	return m.errOrNil("SetTime")
+}
+
+func (m mockInstance) SetContinuousStates([]float64) error {
+	return m.errOrNil("SetContinuousStates")
+}
+
+func (m mockInstance) GetDerivatives() ([]float64, error) {
+	if m.err {
+		return nil, errors.New("GetDerivatives")
+	}
+	return []float64{1, 2}, nil
+}
+
+func (m mockInstance) GetEventIndicators() ([]float64, error) {
+	if m.err {
+		return nil, errors.New("GetEventIndicators")
+	}
+	return []float64{1}, nil
+}
+
+func (m mockInstance) GetContinuousStates() ([]float64, error) {
+	if m.err {
+		return nil, errors.New("GetContinuousStates")
+	}
+	return []float64{3, 4}, nil
+}
+
+func (m mockInstance) GetNominalsOfContinuousStates() ([]float64, error) {
+	if m.err {
+		return nil, errors.New("GetNominalsOfContinuousStates")
+	}
+	return []float64{1, 1}, nil
+}
+
 func (m mockInstance) Encode() ([]byte, error) {
 	if m.err {
 		return nil, errors.New("Encode")
@@ -170,6 +273,44 @@ func init() {
 			err: true,
 		},
 	})
+	// model exchange with continuous states and event indicators
+	_ = fmi.RegisterModel(&mockModel{
+		guid:            "ModelExchange",
+		instance:        &mockInstance{},
+		states:          2,
+		eventIndicators: 1,
+	})
+	// model exchange instances return errors
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "ModelExchangeErrors",
+		instance: &mockInstance{
+			err: true,
+		},
+		states:          2,
+		eventIndicators: 1,
+	})
+	// model panics when instantiated
+	_ = fmi.RegisterModel(&panicModel{mockModel{guid: "InstantiatePanic"}})
+	// model instances panic when called
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "Panic",
+		instance: &panicInstance{},
+		states:   1,
+	})
+	// model instances compute partial steps
+	_ = fmi.RegisterModel(&mockModel{
+		guid: "PartialStep",
+		instance: &mockInstance{
+			stepResult: fmi.StepResultPartial,
+		},
+	})
+	// model instances discard steps larger than 0.5
+	_ = fmi.RegisterModel(&mockModel{
+		guid:     "MaxStep",
+		instance: &maxStepInstance{maxStep: 0.5},
+	})
+	// model creates a new instance counting steps
+	_ = fmi.RegisterModel(&counterModel{mockModel{guid: "Counter"}})
 }
 
 func instantiateDefault(state ...fmi.ModelState) fmi.FMUID {
@@ -191,6 +332,18 @@ func instantiateState(id fmi.FMUID, state ...fmi.ModelState) fmi.FMUID {
 	return id
 }
 
+func instantiateModelExchange(state ...fmi.ModelState) fmi.FMUID {
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeModelExchange, "ModelExchange", "", false, noopLogger))
+	instantiateState(id, state...)
+	return id
+}
+
+func instantiateModelExchangeErrors(state ...fmi.ModelState) fmi.FMUID {
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeModelExchange, "ModelExchangeErrors", "", false, noopLogger))
+	instantiateState(id, state...)
+	return id
+}
+
 func instantiateInstanceErrors(state ...fmi.ModelState) fmi.FMUID {
 	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "InstanceErrors", "", false, noopLogger))
 	instantiateState(id, state...)
@@ -241,6 +394,11 @@ func TestInstantiate(t *testing.T) {
 			t.Errorf("Expected category error, got %v", category)
 		}
 	}
+	loggerExpectFatal := func(status fmi.Status, category, message string) {
+		if category != "logStatusFatal" {
+			t.Errorf("Expected category fatal, got %v", category)
+		}
+	}
 	loggerExpectNothing := func(status fmi.Status, category, message string) {
 		t.Errorf("Logger shouldn't have been called with %v, %s, %s", status, category, message)
 	}
@@ -310,6 +468,19 @@ func TestInstantiate(t *testing.T) {
 			true,
 			nil,
 		},
+		{
+			"Instantiate panic is recovered",
+			args{
+				"Name",
+				fmi.FMUTypeCoSimulation,
+				"InstantiatePanic",
+				"",
+				false,
+				loggerExpectFatal,
+			},
+			true,
+			nil,
+		},
 		{
 			"Instance should be created and stored",
 			args{
@@ -544,12 +715,12 @@ func verifyFMUStateAndCleanUp(t *testing.T, id fmi.FMUID, state fmi.ModelState)
 	fmu, err := fmi.GetFMU(id)
 	defer fmi.FreeInstance(id)
 	if err != nil {
-		t.Errorf("Error getting FMU: %w", err)
+		t.Errorf("Error getting FMU: %v", err)
 		return
 	}
 
 	if fmu.State != state {
-		t.Errorf("Expected FMU state %v, got %v", fmu.State, state)
+		t.Errorf("Expected FMU state %v, got %v", state, fmu.State)
 	}
 }
 
@@ -582,7 +753,7 @@ func TestSetupExperiment(t *testing.T) {
 				id: instantiateInstanceErrors(),
 			},
 			fmi.StatusError,
-			fmi.ModelStateInstantiated,
+			fmi.ModelStateError,
 		},
 		{
 			"SetupExperiment is called",
@@ -627,7 +798,7 @@ func TestEnterInitializationMode(t *testing.T) {
 				id: instantiateInstanceErrors(),
 			},
 			fmi.StatusError,
-			fmi.ModelStateInstantiated,
+			fmi.ModelStateError,
 		},
 		{
 			"EnterInitializationMode is called",
@@ -672,7 +843,7 @@ func TestExitInitializationMode(t *testing.T) {
 				id: instantiateInstanceErrors(fmi.ModelStateInitializationMode),
 			},
 			fmi.StatusError,
-			fmi.ModelStateInitializationMode,
+			fmi.ModelStateError,
 		},
 		{
 			"ExitInitializationMode is called",
@@ -714,10 +885,10 @@ func TestTerminate(t *testing.T) {
 		{
 			"Terminate error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateContinuousTimeMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateContinuousTimeMode,
+			fmi.ModelStateError,
 		},
 		{
 			"Terminate is called",
@@ -762,12 +933,12 @@ func TestReset(t *testing.T) {
 				id: instantiateInstanceErrors(fmi.ModelStateTerminated),
 			},
 			fmi.StatusError,
-			fmi.ModelStateTerminated,
+			fmi.ModelStateError,
 		},
 		{
 			"Reset is called",
 			args{
-				id: instantiateDefault(fmi.ModelStateContinuousTimeMode),
+				id: instantiateDefault(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusOK,
 			fmi.ModelStateInstantiated,
@@ -791,9 +962,10 @@ func TestDoStep(t *testing.T) {
 		noSetFMUStatePriorToCurrentPoint bool
 	}
 	tests := []struct {
-		name string
-		args args
-		want fmi.Status
+		name  string
+		args  args
+		want  fmi.Status
+		state fmi.ModelState
 	}{
 		{
 			"Model state is invalid",
@@ -801,6 +973,7 @@ func TestDoStep(t *testing.T) {
 				id: instantiateDefault(),
 			},
 			fmi.StatusError,
+			fmi.ModelStateInstantiated,
 		},
 		{
 			"Communication step size must be positive",
@@ -809,6 +982,7 @@ func TestDoStep(t *testing.T) {
 				communicationStepSize: 0,
 			},
 			fmi.StatusError,
+			fmi.ModelStateError,
 		},
 		{
 			"FMU type should be cosimulation",
@@ -822,6 +996,7 @@ func TestDoStep(t *testing.T) {
 				communicationStepSize: 1,
 			},
 			fmi.StatusError,
+			fmi.ModelStateStepComplete,
 		},
 		{
 			"DoStep error is returned",
@@ -830,6 +1005,7 @@ func TestDoStep(t *testing.T) {
 				communicationStepSize: 1,
 			},
 			fmi.StatusError,
+			fmi.ModelStateError,
 		},
 		{
 			"Successful step result is returned",
@@ -838,6 +1014,17 @@ func TestDoStep(t *testing.T) {
 				communicationStepSize: 1,
 			},
 			fmi.StatusOK,
+			fmi.ModelStateStepComplete,
+		},
+		{
+			"Partial step result moves to step failed",
+			args{
+				id: instantiateState(fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "PartialStep", "", false, noopLogger)),
+					fmi.ModelStateStepComplete),
+				communicationStepSize: 1,
+			},
+			fmi.StatusDiscard,
+			fmi.ModelStateStepFailed,
 		},
 	}
 	for _, tt := range tests {
@@ -845,7 +1032,277 @@ func TestDoStep(t *testing.T) {
 			if got := fmi.DoStep(tt.args.id, tt.args.currentCommunicationPoint, tt.args.communicationStepSize, tt.args.noSetFMUStatePriorToCurrentPoint); got != tt.want {
 				t.Errorf("DoStep() = %v, want %v", got, tt.want)
 			}
-			fmi.FreeInstance(fmi.FMUID(tt.args.id))
+			verifyFMUStateAndCleanUp(t, tt.args.id, tt.state)
+		})
+	}
+}
+
+// counterModel creates a new counterInstance for each instance
+type counterModel struct {
+	mockModel
+}
+
+func (m counterModel) Instantiate(fmi.Logger) (fmi.ModelInstance, error) {
+	return &counterInstance{}, nil
+}
+
+// counterInstance counts steps without synchronisation, so concurrent calls to an instance race
+type counterInstance struct {
+	mockInstance
+	steps int32
+}
+
+func (c *counterInstance) DoStep(float64, float64, bool) (fmi.StepResult, error) {
+	// yield between read and write so unserialised steps are lost
+	steps := c.steps
+	runtime.Gosched()
+	c.steps = steps + 1
+	return fmi.StepResultSuccess, nil
+}
+
+func (c *counterInstance) GetInteger(fmi.ValueReference) ([]int32, error) {
+	return []int32{c.steps}, nil
+}
+
+const (
+	concurrentInstances = 16
+	concurrentSteps     = 50
+)
+
+// simulateCounter runs a co-simulation of steps on an instance, returning the step count
+func simulateCounter(id fmi.FMUID, steps int) ([]int32, fmi.Status) {
+	for _, s := range []fmi.Status{
+		fmi.SetupExperiment(id, false, 0, 0, false, 0),
+		fmi.EnterInitializationMode(id),
+		fmi.ExitInitializationMode(id),
+	} {
+		if s != fmi.StatusOK {
+			return nil, s
+		}
+	}
+	for i := 0; i < steps; i++ {
+		if s := fmi.DoStep(id, float64(i), 1, false); s != fmi.StatusOK {
+			return nil, s
+		}
+	}
+	vs, s := fmi.GetInteger(id, fmi.ValueReference{0})
+	if s != fmi.StatusOK {
+		return nil, s
+	}
+	return vs, fmi.Terminate(id)
+}
+
+func TestConcurrentInstances(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentInstances; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// registering reads and writes registered models alongside instantiation
+			if err := fmi.RegisterModel(&counterModel{mockModel{guid: "Counter"}}); err == nil {
+				t.Errorf("RegisterModel() expected error for registered GUID")
+			}
+			id := fmi.FMUID(fmi.Instantiate("name"+strconv.Itoa(i), fmi.FMUTypeCoSimulation, "Counter", "", false, noopLogger))
+			if id == 0 {
+				t.Errorf("Instantiate() returned nil")
+				return
+			}
+			defer fmi.FreeInstance(id)
+			got, s := simulateCounter(id, concurrentSteps+i)
+			if s != fmi.StatusOK {
+				t.Errorf("Instance %d returned %v", i, s)
+				return
+			}
+			if diff := cmp.Diff([]int32{int32(concurrentSteps + i)}, got); diff != "" {
+				t.Errorf("Instance %d steps mismatch (-want +got):\n%s", i, diff)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "Counter", "", false, noopLogger))
+	defer fmi.FreeInstance(id)
+	for _, s := range []fmi.Status{
+		fmi.SetupExperiment(id, false, 0, 0, false, 0),
+		fmi.EnterInitializationMode(id),
+		fmi.ExitInitializationMode(id),
+	} {
+		if s != fmi.StatusOK {
+			t.Fatalf("Initialization returned %v", s)
+		}
+	}
+
+	// start releases all goroutines together so calls overlap
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentInstances; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < concurrentSteps; j++ {
+				if s := fmi.DoStep(id, 0, 1, false); s != fmi.StatusOK {
+					t.Errorf("DoStep() = %v, want %v", s, fmi.StatusOK)
+					return
+				}
+				if _, s := fmi.GetInteger(id, fmi.ValueReference{0}); s != fmi.StatusOK {
+					t.Errorf("GetInteger() = %v, want %v", s, fmi.StatusOK)
+					return
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	got, s := fmi.GetInteger(id, fmi.ValueReference{0})
+	if s != fmi.StatusOK {
+		t.Fatalf("GetInteger() = %v, want %v", s, fmi.StatusOK)
+	}
+	if diff := cmp.Diff([]int32{concurrentInstances * concurrentSteps}, got); diff != "" {
+		t.Errorf("Steps mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConcurrentFreeInstance(t *testing.T) {
+	id := fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "Counter", "", false, noopLogger))
+	var wg sync.WaitGroup
+	for i := 0; i < concurrentInstances; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			// calls either complete before the instance is freed or are rejected
+			if _, s := simulateCounter(id, concurrentSteps); s != fmi.StatusOK && s != fmi.StatusError {
+				t.Errorf("Simulation returned %v", s)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			fmi.FreeInstance(id)
+		}()
+	}
+	wg.Wait()
+
+	if _, err := fmi.GetFMU(id); err == nil {
+		t.Errorf("GetFMU() expected error for freed instance")
+	}
+}
+
+// panicModel panics when instantiated
+type panicModel struct {
+	mockModel
+}
+
+func (m panicModel) Instantiate(fmi.Logger) (fmi.ModelInstance, error) {
+	panic("Instantiate")
+}
+
+// panicInstance panics in calls that run model code
+type panicInstance struct {
+	mockInstance
+}
+
+func (p panicInstance) DoStep(float64, float64, bool) (fmi.StepResult, error) {
+	panic("DoStep")
+}
+
+func (p panicInstance) SetReal(fmi.ValueReference, []float64) error {
+	panic("SetReal")
+}
+
+func (p panicInstance) GetDerivatives() ([]float64, error) {
+	var fs []float64
+	// index out of range
+	return []float64{fs[1]}, nil
+}
+
+// fatalLogger collects fatal messages
+type fatalLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *fatalLogger) log(status fmi.Status, category, message string) {
+	if status != fmi.StatusFatal {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, message)
+}
+
+func (l *fatalLogger) fatal() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.messages
+}
+
+func TestPanic(t *testing.T) {
+	tests := []struct {
+		name     string
+		fmuType  fmi.FMUType
+		state    fmi.ModelState
+		call     func(fmi.FMUID) fmi.Status
+		wantCall string
+	}{
+		{
+			"DoStep panic is fatal",
+			fmi.FMUTypeCoSimulation,
+			fmi.ModelStateStepComplete,
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.DoStep(id, 0, 1, false)
+			},
+			"Panic in DoStep: DoStep",
+		},
+		{
+			"SetReal panic is fatal",
+			fmi.FMUTypeCoSimulation,
+			fmi.ModelStateStepComplete,
+			func(id fmi.FMUID) fmi.Status {
+				return fmi.SetReal(id, fmi.ValueReference{1}, []float64{1})
+			},
+			"Panic in SetReal: SetReal",
+		},
+		{
+			"Runtime error is fatal",
+			fmi.FMUTypeModelExchange,
+			fmi.ModelStateContinuousTimeMode,
+			func(id fmi.FMUID) fmi.Status {
+				_, s := fmi.GetDerivatives(id, 1)
+				return s
+			},
+			"Panic in GetDerivatives: runtime error: index out of range",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &fatalLogger{}
+			id := fmi.FMUID(fmi.Instantiate("name", tt.fmuType, "Panic", "", false, l.log))
+			instantiateState(id, tt.state)
+			defer fmi.FreeInstance(id)
+
+			if got := tt.call(id); got != fmi.StatusFatal {
+				t.Errorf("Call = %v, want %v", got, fmi.StatusFatal)
+			}
+			fmu, _ := fmi.GetFMU(id)
+			if fmu.State != fmi.ModelStateFatal {
+				t.Errorf("Expected FMU state %v, got %v", fmi.ModelStateFatal, fmu.State)
+			}
+			messages := l.fatal()
+			if len(messages) != 1 || !strings.HasPrefix(messages[0], tt.wantCall) ||
+				!strings.Contains(messages[0], "goroutine") {
+				t.Errorf("Expected fatal message %q with stack trace, got %q", tt.wantCall, messages)
+			}
+
+			// only FreeInstance is allowed after a fatal error
+			if got := tt.call(id); got != fmi.StatusError {
+				t.Errorf("Call after fatal = %v, want %v", got, fmi.StatusError)
+			}
+			if got := fmi.Reset(id); got != fmi.StatusError {
+				t.Errorf("Reset() after fatal = %v, want %v", got, fmi.StatusError)
+			}
 		})
 	}
 }
@@ -1,8 +1,11 @@
 package fmi
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"unsafe"
 )
 
 const (
@@ -28,6 +31,17 @@ const (
 	StepResultAsync
 )
 
+const (
+	// StatusKindDoStep queries the status of an asynchronous DoStep
+	StatusKindDoStep StatusKind = iota
+	// StatusKindPending queries a description of a pending DoStep
+	StatusKindPending
+	// StatusKindLastSuccessfulTime queries the end time of the last successful step
+	StatusKindLastSuccessfulTime
+	// StatusKindTerminated queries whether the slave wants to terminate the simulation
+	StatusKindTerminated
+)
+
 const (
 	ModelStateStartAndEnd ModelState = 1 << iota
 	ModelStateInstantiated
@@ -53,6 +67,12 @@ type FMUType uint
 // ModelState represents state machine of model
 type ModelState uint
 
+// StatusKind is the status information requested by fmi2GetXXXStatus functions
+type StatusKind uint
+
+// StepFinishedCallback abstracts the fmi2StepFinished callback function
+type StepFinishedCallback func(status Status)
+
 // FMU represents an active FMU instance
 type FMU struct {
 	Name             string
@@ -61,14 +81,51 @@ type FMU struct {
 	ResourceLocation string
 	State            ModelState
 
+	handle    unsafe.Pointer
 	logger    Logger
 	instance  ModelInstance
 	startTime float64
+
+	// description is the registered model description for the instance GUID
+	description ModelDescription
+	// numberOfContinuousStates is taken from model structure derivatives
+	numberOfContinuousStates int
+	// numberOfEventIndicators is taken from model description
+	numberOfEventIndicators int
+
+	// asynchronous runs DoStep in the background if model description allows it
+	asynchronous bool
+	stepFinished StepFinishedCallback
+	// mu guards State and step while an asynchronous step is in progress
+	mu   sync.Mutex
+	step stepStatus
+
+	// calls serialises calls to the instance from the FMI interface, see allowedState
+	calls sync.Mutex
+	// call is the name of the function holding calls, for logging panics
+	call string
+	// freed is set by FreeInstance for calls that were waiting on calls
+	freed bool
+	// memory allocates memory returned to the environment
+	memory allocator
+	// strings holds the strings returned by fmi2GetString
+	strings stringArena
+	// states holds the FMU states returned by fmi2GetFMUstate and fmi2DeSerializeFMUstate
+	states statePool
 }
 
 // Status is return status of functions
 type Status uint
 
+var statusNames = [...]string{"fmi2OK", "fmi2Warning", "fmi2Discard", "fmi2Error", "fmi2Fatal", "fmi2Pending"}
+
+func (s Status) String() string {
+	if int(s) < len(statusNames) {
+		return statusNames[s]
+	}
+	return "unknown"
+}
+
 // ValueReference is list of indexes to model values
 type ValueReference []uint
 
@@ -79,12 +136,15 @@ type Model interface {
 	Description() ModelDescription
 
 	// Instantiate returns a new model instance.
-	// ModelInstance should be a new thread-safe instance.
+	// Calls to an instance are serialised, but separate instances are called concurrently
+	// so instances should not share state.
 	// Can return an error if the implementation needs to.
 	Instantiate(Logger) (ModelInstance, error)
 }
 
-// ModelInstance represents a live FMU that is being simulated through FMI interface
+// ModelInstance represents a live FMU that is being simulated through FMI interface.
+// Errors returned by the methods of the instance, and of the optional interfaces it implements,
+// return fmi2Error unless created with Warning, Discard or Fatal.
 type ModelInstance interface {
 	// SetupExperiment called from fmi2SetupExperiment.
 	// error can be returned if there are issues.
@@ -159,9 +219,105 @@ type CoSimulator interface {
 		noSetFMUStatePriorToCurrentPoint bool) (StepResult, error)
 }
 
+// EventInfo is returned from NewDiscreteStates and maps to fmi2EventInfo
+type EventInfo struct {
+	// NewDiscreteStatesNeeded signals that another event iteration is required
+	NewDiscreteStatesNeeded bool
+	// TerminateSimulation requests the environment to stop the simulation
+	TerminateSimulation bool
+	// NominalsOfContinuousStatesChanged signals that GetNominalsOfContinuousStates returns new values
+	NominalsOfContinuousStatesChanged bool
+	// ValuesOfContinuousStatesChanged signals that continuous states were re-initialized
+	ValuesOfContinuousStatesChanged bool
+	// NextEventTimeDefined signals that NextEventTime is the time of the next time event
+	NextEventTimeDefined bool
+	// NextEventTime is the next time event if NextEventTimeDefined is set
+	NextEventTime float64
+}
+
 // ModelExchanger implements methods for model exchange
 type ModelExchanger interface {
 	ValueGetterSetter
+
+	// EnterEventMode called from fmi2EnterEventMode
+	EnterEventMode() error
+
+	// NewDiscreteStates called from fmi2NewDiscreteStates
+	NewDiscreteStates() (EventInfo, error)
+
+	// EnterContinuousTimeMode called from fmi2EnterContinuousTimeMode
+	EnterContinuousTimeMode() error
+
+	// CompletedIntegratorStep called from fmi2CompletedIntegratorStep
+	CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint bool) (enterEventMode, terminateSimulation bool, err error)
+
+	// SetTime called from fmi2SetTime
+	SetTime(time float64) error
+
+	// SetContinuousStates called from fmi2SetContinuousStates.
+	// Slice length is checked against the number of model derivatives.
+	SetContinuousStates(x []float64) error
+
+	// GetDerivatives called from fmi2GetDerivatives.
+	// Returned slice must have the same length as the number of model derivatives.
+	GetDerivatives() ([]float64, error)
+
+	// GetEventIndicators called from fmi2GetEventIndicators.
+	// Returned slice must have length of model description NumberOfEventIndicators.
+	GetEventIndicators() ([]float64, error)
+
+	// GetContinuousStates called from fmi2GetContinuousStates.
+	// Returned slice must have the same length as the number of model derivatives.
+	GetContinuousStates() ([]float64, error)
+
+	// GetNominalsOfContinuousStates called from fmi2GetNominalsOfContinuousStates.
+	// Returned slice must have the same length as the number of model derivatives.
+	GetNominalsOfContinuousStates() ([]float64, error)
+}
+
+// AsyncCoSimulator can be implemented by co-simulators that run asynchronously.
+// The context is cancelled when fmi2CancelStep is called during a pending step.
+type AsyncCoSimulator interface {
+	// DoStepContext is called in place of DoStep when the FMU runs asynchronously
+	DoStepContext(ctx context.Context,
+		currentCommunicationPoint, communicationStepSize float64,
+		noSetFMUStatePriorToCurrentPoint bool) (StepResult, error)
+}
+
+// StepStatusReporter can be implemented by co-simulators to report on discarded steps.
+// Used by fmi2GetRealStatus and fmi2GetBooleanStatus.
+type StepStatusReporter interface {
+	// LastSuccessfulTime is the time up to which the last step was computed
+	LastSuccessfulTime() float64
+
+	// Terminated returns true if the slave wants to terminate the simulation
+	Terminated() bool
+}
+
+// DirectionalDeriver can be implemented by models that compute directional derivatives analytically.
+// Models that do not implement it use a finite difference approximation.
+// Used by fmi2GetDirectionalDerivative.
+type DirectionalDeriver interface {
+	// GetDirectionalDerivative returns the Jacobian-vector product dvUnknown = J * dvKnown,
+	// where J is the partial derivative of the unknowns with respect to the knowns.
+	GetDirectionalDerivative(vUnknown, vKnown ValueReference, dvKnown []float64) ([]float64, error)
+}
+
+// InputDerivativeSetter can be implemented by co-simulators that interpolate real inputs over a step.
+// InputExtrapolator provides a default implementation.
+// Used by fmi2SetRealInputDerivatives.
+type InputDerivativeSetter interface {
+	// SetRealInputDerivatives sets the order[i] derivative of input vr[i] at the current communication point.
+	// Value references and orders are checked against the model description.
+	SetRealInputDerivatives(vr ValueReference, order []int32, value []float64) error
+}
+
+// OutputDerivativeGetter can be implemented by co-simulators that provide derivatives of real outputs.
+// Used by fmi2GetRealOutputDerivatives.
+type OutputDerivativeGetter interface {
+	// GetRealOutputDerivatives returns the order[i] derivative of output vr[i] at the current communication point.
+	// Value references and orders are checked against the model description.
+	GetRealOutputDerivatives(vr ValueReference, order []int32) ([]float64, error)
 }
 
 type ValueGetterSetter interface {
@@ -209,6 +365,16 @@ func (f *FMU) cosimulator() (CoSimulator, error) {
 	return cosim, nil
 }
 
+// ModelExchanger gets model exchange instance for the FMU.
+// Returns an error if the fmu type is not model exchange and implements the interface.
+func (f *FMU) ModelExchanger() (ModelExchanger, error) {
+	if f.Typee != FMUTypeModelExchange {
+		return nil, errors.New("FMU type is not set to model exchange")
+	}
+
+	return f.modelExchanger()
+}
+
 func (f *FMU) modelExchanger() (ModelExchanger, error) {
 	mexch, ok := f.instance.(ModelExchanger)
 	if !ok {
@@ -227,6 +393,31 @@ func (f *FMU) valueGetterSetter() (ValueGetterSetter, error) {
 	return nil, fmt.Errorf("Unknown FMU type %v", f.Typee)
 }
 
+// capabilities returns the capability flags in the model description for the FMU type.
+// Returns nil if the model description does not declare the FMU type.
+func (f *FMU) capabilities() *FMUShared {
+	switch f.Typee {
+	case FMUTypeCoSimulation:
+		if f.description.CoSimulation != nil {
+			return &f.description.CoSimulation.FMUShared
+		}
+	case FMUTypeModelExchange:
+		if f.description.ModelExchange != nil {
+			return &f.description.ModelExchange.FMUShared
+		}
+	}
+	return nil
+}
+
+// coSimulation returns the co-simulation capabilities in the model description.
+// Returns an error if the model description does not declare co-simulation.
+func (f *FMU) coSimulation() (*CoSimulation, error) {
+	if f.description.CoSimulation == nil {
+		return nil, errors.New("Model description does not define CoSimulation")
+	}
+	return f.description.CoSimulation, nil
+}
+
 func (f *FMU) StateEncoder() (StateEncoder, error) {
 	se, ok := f.instance.(StateEncoder)
 	if !ok {
@@ -0,0 +1,58 @@
+package fmi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// RegisteredModels returns the GUIDs of all models registered with RegisterModel in sorted order
+func RegisteredModels() []string {
+	modelsMu.RLock()
+	guids := make([]string, 0, len(models))
+	for guid := range models {
+		guids = append(guids, guid)
+	}
+	modelsMu.RUnlock()
+	sort.Strings(guids)
+	return guids
+}
+
+// ModelDescriptionXML marshals the description of the model registered with guid to modelDescription.xml
+func ModelDescriptionXML(guid string) ([]byte, error) {
+	model, ok := registeredModel(guid)
+	if !ok {
+		return nil, fmt.Errorf("GUID %s does not match any registered model", guid)
+	}
+	return model.Description().MarshallIndent()
+}
+
+/*
+WriteModelDescription writes modelDescription.xml for the model registered by an FMU package.
+An FMU shared library contains a single model, so an error is returned if no model or more
+than one model is registered. FMU packages can call this from main to generate the XML with
+
+	go run ./examples/BouncingBall > modelDescription.xml
+
+so the GUID and variables in the XML always match the Go model.
+*/
+func WriteModelDescription(w io.Writer) error {
+	guids := RegisteredModels()
+	switch len(guids) {
+	case 0:
+		return errors.New("No model is registered")
+	case 1:
+	default:
+		return fmt.Errorf("Expected a single registered model, got %d", len(guids))
+	}
+
+	bs, err := ModelDescriptionXML(guids[0])
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(bs); err != nil {
+		return fmt.Errorf("Error writing model description: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package fmi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type descriptionModel struct {
+	Model
+	desc ModelDescription
+}
+
+func (m descriptionModel) Description() ModelDescription {
+	return m.desc
+}
+
+// withModels replaces registered models for the duration of a test
+func withModels(t *testing.T, ms ...Model) {
+	registered := models
+	models = map[string]Model{}
+	for _, m := range ms {
+		if err := RegisterModel(m); err != nil {
+			t.Fatalf("RegisterModel() error = %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		models = registered
+	})
+}
+
+func TestRegisteredModels(t *testing.T) {
+	withModels(t,
+		descriptionModel{desc: ModelDescription{GUID: "b"}},
+		descriptionModel{desc: ModelDescription{GUID: "a"}},
+	)
+	got := RegisteredModels()
+	if len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("RegisteredModels() = %v, want %v", got, []string{"a", "b"})
+	}
+}
+
+func TestModelDescriptionXML(t *testing.T) {
+	withModels(t, descriptionModel{desc: ModelDescription{GUID: "{guid}", Name: "Model"}})
+
+	tests := []struct {
+		name     string
+		guid     string
+		contains string
+		wantErr  bool
+	}{
+		{
+			"GUID must be registered",
+			"unknown",
+			"",
+			true,
+		},
+		{
+			"Registered model is marshalled",
+			"{guid}",
+			`modelName="Model" guid="{guid}"`,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ModelDescriptionXML(tt.guid)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ModelDescriptionXML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(string(got), tt.contains) {
+				t.Errorf("ModelDescriptionXML() = %s, want to contain %s", got, tt.contains)
+			}
+		})
+	}
+}
+
+func TestWriteModelDescription(t *testing.T) {
+	tests := []struct {
+		name     string
+		models   []Model
+		contains string
+		wantErr  bool
+	}{
+		{
+			"No model is registered",
+			nil,
+			"",
+			true,
+		},
+		{
+			"Multiple models are registered",
+			[]Model{
+				descriptionModel{desc: ModelDescription{GUID: "a"}},
+				descriptionModel{desc: ModelDescription{GUID: "b"}},
+			},
+			"",
+			true,
+		},
+		{
+			"Single model is written",
+			[]Model{
+				descriptionModel{desc: ModelDescription{GUID: "a"}},
+			},
+			`<?xml version="1.0" encoding="UTF-8"?>`,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withModels(t, tt.models...)
+			w := &bytes.Buffer{}
+			if err := WriteModelDescription(w); (err != nil) != tt.wantErr {
+				t.Fatalf("WriteModelDescription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(w.String(), tt.contains) {
+				t.Errorf("WriteModelDescription() = %s, want to contain %s", w.String(), tt.contains)
+			}
+		})
+	}
+}
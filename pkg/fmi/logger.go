@@ -3,6 +3,7 @@ package fmi
 import (
 	"errors"
 	"fmt"
+	"sync"
 )
 
 const (
@@ -60,6 +61,8 @@ func (l loggerCategory) String() string {
 }
 
 type logger struct {
+	// mu guards mask, which can be set while an asynchronous step is logging
+	mu   sync.RWMutex
 	mask loggerCategory
 
 	fmiCallbackLogger LoggerCallback
@@ -82,36 +85,41 @@ func newLogger(categories []string, callback LoggerCallback) (Logger, error) {
 	}, nil
 }
 
-func (l logger) Error(err error) {
+func (l *logger) Error(err error) {
 	l.logMessage(StatusError, loggerCategoryError, err.Error())
 }
 
-func (l logger) Fatal(err error) {
+func (l *logger) Fatal(err error) {
 	l.logMessage(StatusFatal, loggerCategoryFatal, err.Error())
 }
 
-func (l logger) Warning(msg string) {
+func (l *logger) Warning(msg string) {
 	l.logMessage(StatusWarning, loggerCategoryWarning, msg)
 }
 
-func (l logger) Discard(msg string) {
+func (l *logger) Discard(msg string) {
 	l.logMessage(StatusDiscard, loggerCategoryDiscard, msg)
 }
 
-func (l logger) Event(msg string) {
+func (l *logger) Event(msg string) {
 	l.logMessage(StatusOK, loggerCategoryEvents, msg)
 }
 
-func (l logger) Info(msg string) {
+func (l *logger) Info(msg string) {
 	l.logMessage(StatusOK, loggerCategoryAll, msg)
 }
 
 func (l *logger) setMask(mask loggerCategory) {
+	l.mu.Lock()
 	l.mask = mask
+	l.mu.Unlock()
 }
 
-func (l logger) logMessage(status Status, category loggerCategory, message string) {
-	if l.mask&category == 0 {
+func (l *logger) logMessage(status Status, category loggerCategory, message string) {
+	l.mu.RLock()
+	mask := l.mask
+	l.mu.RUnlock()
+	if mask&category == 0 {
 		return
 	}
 
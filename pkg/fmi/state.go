@@ -1,7 +1,5 @@
 package fmi
 
-// #include <stdlib.h>
-// #include <string.h>
 // #include "./c/fmi2Functions.h"
 // #include "bridge.h"
 import "C"
@@ -17,16 +15,16 @@ func fmi2GetFMUstate(c C.fmi2Component, FMUstate *C.fmi2FMUstate) C.fmi2Status {
 	if s != StatusOK {
 		return C.fmi2Status(s)
 	}
-
-	bsize := len(bs)
-	ms := (*C.ModelState)(C.malloc(C.ulong(C.sizeof_ModelState + bsize)))
-	ms.size = C.ulong(bsize)
-	var cs []C.char
-	carrayToSlice(unsafe.Pointer(&ms.data[0]), unsafe.Pointer(&cs), bsize)
-	for i, b := range bs {
-		cs[i] = C.char(b)
+	fmu, err := GetFMU(FMUID(c))
+	if err != nil {
+		return logError(c, err)
 	}
 
+	// a previous state is reused, see GetFMUState
+	ms, err := fmu.states.get((*C.ModelState)(*FMUstate), bs)
+	if err != nil {
+		return logError(c, err)
+	}
 	*FMUstate = C.fmi2FMUstate(ms)
 	return C.fmi2Status(s)
 }
@@ -38,12 +36,14 @@ NULL , then *FMUstate points to a previously returned FMUstate that has not been
 since. In particular, fmi2FreeFMUstate had not been called with this FMUstate as an
 argument. [Function fmi2GetFMUstate typically reuses the memory of this FMUstate in this
 case and returns the same pointer to it, but with the actual FMUstate .]
+The state encoded by the model is wrapped with the model GUID, state version, encoding and a checksum.
 */
-func GetFMUState(id FMUID) ([]byte, Status) {
-	fmu, ok := allowedSerialize(id, "GetFMUState")
+func GetFMUState(id FMUID) (_ []byte, status Status) {
+	fmu, ok := allowedState(id, "GetFMUstate")
 	if !ok {
 		return nil, StatusError
 	}
+	defer fmu.release(&status)
 
 	se, err := fmu.StateEncoder()
 	if err != nil {
@@ -57,17 +57,18 @@ func GetFMUState(id FMUID) ([]byte, Status) {
 		return nil, StatusError
 	}
 
-	return bs, StatusOK
+	return fmu.wrapState(bs), StatusOK
 }
 
 //export fmi2SetFMUstate
 func fmi2SetFMUstate(c C.fmi2Component, FMUState C.fmi2FMUstate) C.fmi2Status {
-	ms := (*C.ModelState)(FMUState)
-	var cs []C.char
-	carrayToSlice(unsafe.Pointer(&ms.data[0]), unsafe.Pointer(&cs), int(ms.size))
-	bs := make([]byte, int(ms.size))
-	for i, c := range cs {
-		bs[i] = byte(c)
+	fmu, err := GetFMU(FMUID(c))
+	if err != nil {
+		return logError(c, err)
+	}
+	bs, err := fmu.states.bytes((*C.ModelState)(FMUState))
+	if err != nil {
+		return logError(c, err)
 	}
 
 	return C.fmi2Status(SetFMUState(FMUID(c), bs))
@@ -76,12 +77,15 @@ func fmi2SetFMUstate(c C.fmi2Component, FMUState C.fmi2FMUstate) C.fmi2Status {
 /*
 SetFMUstate copies the content of the previously copied FMUstate back and uses it as
 actual new FMU state. The FMUstate copy still exists.
+States of another model, a newer state version or another encoding are rejected,
+and states of an older version are migrated if the model implements StateMigrator.
 */
-func SetFMUState(id FMUID, bs []byte) Status {
-	fmu, ok := allowedSerialize(id, "GetFMUState")
+func SetFMUState(id FMUID, bs []byte) (status Status) {
+	fmu, ok := allowedState(id, "SetFMUstate")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	se, err := fmu.StateDecoder()
 	if err != nil {
@@ -89,7 +93,13 @@ func SetFMUState(id FMUID, bs []byte) Status {
 		return StatusError
 	}
 
-	if err := se.Decode(bs); err != nil {
+	state, err := fmu.unwrapState(bs)
+	if err != nil {
+		fmu.logger.Error(err)
+		return StatusError
+	}
+
+	if err := se.Decode(state); err != nil {
 		fmu.logger.Error(fmt.Errorf("Error decoding state: %w", err))
 		return StatusError
 	}
@@ -104,15 +114,16 @@ fmi2GetFMUstate call for this FMUstate . The input argument to this function is
 to be freed. If a null pointer is provided, the call is ignored. The function returns a null pointer in
 argument FMUstate
 */
-func fmi2FreeFMUstate(c C.fmi2Component, FMUState *C.fmi2FMUstate) C.fmi2Status {
-	if _, ok := allowedSerialize(FMUID(c), "FreeFMUState"); !ok {
+func fmi2FreeFMUstate(c C.fmi2Component, FMUState *C.fmi2FMUstate) (status C.fmi2Status) {
+	fmu, ok := allowedState(FMUID(c), "FreeFMUstate")
+	if !ok {
 		return C.fmi2Error
 	}
-	ms := (*C.ModelState)(*FMUState)
-	if ms == nil {
-		return C.fmi2OK
+	defer fmu.releaseC(&status)
+	if err := fmu.states.remove((*C.ModelState)(*FMUState)); err != nil {
+		fmu.logger.Error(err)
+		return C.fmi2Error
 	}
-	C.free(unsafe.Pointer(ms))
 	*FMUState = nil
 	return C.fmi2OK
 }
@@ -123,17 +134,18 @@ fmi2SerializedFMUstateSize returns the size of the byte vector, in order that FM
 can be stored in it. With this information, the environment has to allocate an fmi2Byte vector of
 the required length size.
 */
-func fmi2SerializedFMUstateSize(c C.fmi2Component, FMUState C.fmi2FMUstate, size *C.size_t) C.fmi2Status {
-	fmu, ok := allowedSerialize(FMUID(c), "SerializedFMUstateSize")
+func fmi2SerializedFMUstateSize(c C.fmi2Component, FMUState C.fmi2FMUstate, size *C.size_t) (status C.fmi2Status) {
+	fmu, ok := allowedState(FMUID(c), "SerializedFMUstateSize")
 	if !ok {
 		return C.fmi2Error
 	}
-	ms := (*C.ModelState)(FMUState)
-	if ms == nil {
-		fmu.logger.Error(fmt.Errorf("Invalid argument %s = NULL", "FMUState"))
+	defer fmu.releaseC(&status)
+	bs, err := fmu.states.bytes((*C.ModelState)(FMUState))
+	if err != nil {
+		fmu.logger.Error(err)
 		return C.fmi2Error
 	}
-	*size = ms.size
+	*size = C.size_t(len(bs))
 	return C.fmi2OK
 }
 
@@ -143,29 +155,26 @@ fmi2SerializeFMUstate serializes the data which is referenced by pointer FMUstat
 copies this data in to the byte vector serializedState of length size, that must be provided
 by the environment.
 */
-func fmi2SerializeFMUstate(c C.fmi2Component, FMUstate C.fmi2FMUstate, serializedState *C.fmi2Byte, size C.size_t) C.fmi2Status {
-	fmu, ok := allowedSerialize(FMUID(c), "SerializeFMUstate")
+func fmi2SerializeFMUstate(c C.fmi2Component, FMUstate C.fmi2FMUstate, serializedState *C.fmi2Byte, size C.size_t) (status C.fmi2Status) {
+	fmu, ok := allowedState(FMUID(c), "SerializeFMUstate")
 	if !ok {
 		return C.fmi2Error
 	}
-	ms := (*C.ModelState)(FMUstate)
-	if ms == nil {
-		fmu.logger.Error(fmt.Errorf("Invalid argument %s = NULL", "FMUState"))
+	defer fmu.releaseC(&status)
+	bs, err := fmu.states.bytes((*C.ModelState)(FMUstate))
+	if err != nil {
+		fmu.logger.Error(err)
 		return C.fmi2Error
 	}
 
-	if ms.size != size {
-		fmu.logger.Error(fmt.Errorf("Model state size argument %d does not match %d", size, ms.size))
+	if len(bs) != int(size) {
+		fmu.logger.Error(fmt.Errorf("Model state size argument %d does not match %d", size, len(bs)))
 		return C.fmi2Error
 	}
 
-	var bs []byte
-	carrayToSlice(unsafe.Pointer(serializedState), unsafe.Pointer(&bs), int(size))
-	var cs []C.char
-	carrayToSlice(unsafe.Pointer(&ms.data[0]), unsafe.Pointer(&cs), int(ms.size))
-	for i, c := range cs {
-		bs[i] = byte(c)
-	}
+	var ss []byte
+	carrayToSlice(unsafe.Pointer(serializedState), unsafe.Pointer(&ss), int(size))
+	copy(ss, bs)
 	return C.fmi2OK
 }
 
@@ -174,22 +183,21 @@ func fmi2SerializeFMUstate(c C.fmi2Component, FMUstate C.fmi2FMUstate, serialize
 fmi2DeSerializeFMUstate deserializes the byte vector serializedState of length size,
 constructs a copy of the FMU state and returns FMUstate, the pointer to this copy.
 */
-func fmi2DeSerializeFMUstate(c C.fmi2Component, serializedState C.serializedState_t, size C.size_t, FMUstate *C.fmi2FMUstate) C.fmi2Status {
-	_, ok := allowedSerialize(FMUID(c), "DeSerializeFMUstate")
+func fmi2DeSerializeFMUstate(c C.fmi2Component, serializedState C.serializedState_t, size C.size_t, FMUstate *C.fmi2FMUstate) (status C.fmi2Status) {
+	fmu, ok := allowedState(FMUID(c), "DeSerializeFMUstate")
 	if !ok {
 		return C.fmi2Error
 	}
-	ms := (*C.ModelState)(C.malloc(C.ulong(C.sizeof_ModelState + size)))
-	ms.size = C.ulong(size)
-	C.memcpy(unsafe.Pointer(&ms.data[0]), unsafe.Pointer(serializedState), size)
+	defer fmu.releaseC(&status)
+	if _, err := fmu.openState(C.GoBytes(unsafe.Pointer(serializedState), C.int(size))); err != nil {
+		fmu.logger.Error(fmt.Errorf("Error deserializing FMU state: %w", err))
+		return C.fmi2Error
+	}
+	ms, err := fmu.states.copy(unsafe.Pointer(serializedState), int(size))
+	if err != nil {
+		fmu.logger.Error(err)
+		return C.fmi2Error
+	}
 	*FMUstate = C.fmi2FMUstate(ms)
 	return C.fmi2OK
 }
-
-func allowedSerialize(id FMUID, name string) (*FMU, bool) {
-	const expected = ModelStateInstantiated | ModelStateInitializationMode |
-		ModelStateEventMode | ModelStateContinuousTimeMode |
-		ModelStateStepComplete | ModelStateStepFailed | ModelStateStepCanceled |
-		ModelStateTerminated | ModelStateError
-	return allowedState(id, name, expected)
-}
@@ -1,7 +1,6 @@
 package fmi_test
 
 import (
-	"reflect"
 	"testing"
 
 	"github.com/tanenbaum/go-fmi/pkg/fmi"
@@ -14,7 +13,6 @@ func TestGetFMUState(t *testing.T) {
 	tests := []struct {
 		name  string
 		args  args
-		want  []byte
 		want1 fmi.Status
 	}{
 		{
@@ -22,7 +20,6 @@ func TestGetFMUState(t *testing.T) {
 			args{
 				instantiateDefault(fmi.ModelStateStartAndEnd),
 			},
-			nil,
 			fmi.StatusError,
 		},
 		{
@@ -30,27 +27,30 @@ func TestGetFMUState(t *testing.T) {
 			args{
 				instantiateInstanceErrors(),
 			},
-			nil,
 			fmi.StatusError,
 		},
 		{
-			"State is encoded to bytes",
+			"State is encoded to bytes that can be set",
 			args{
 				instantiateDefault(),
 			},
-			[]byte("foo"),
 			fmi.StatusOK,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, got1 := fmi.GetFMUState(tt.args.id)
-			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("GetFMUState() got = %v, want %v", got, tt.want)
-			}
 			if got1 != tt.want1 {
 				t.Errorf("GetFMUState() got1 = %v, want %v", got1, tt.want1)
 			}
+			if got1 != fmi.StatusOK && got != nil {
+				t.Errorf("GetFMUState() got = %v, want nil", got)
+			}
+			if got1 == fmi.StatusOK {
+				if s := fmi.SetFMUState(tt.args.id, got); s != fmi.StatusOK {
+					t.Errorf("SetFMUState() of got = %v, want %v", s, fmi.StatusOK)
+				}
+			}
 			fmi.FreeInstance(fmi.FMUID(tt.args.id))
 		})
 	}
@@ -84,16 +84,104 @@ func TestSetFMUState(t *testing.T) {
 			"Decode is called successfully",
 			args{
 				id: instantiateDefault(),
-				bs: []byte("foo"),
+				bs: fmuState(instantiateDefault()),
 			},
 			fmi.StatusOK,
 		},
+		{
+			"State without header is rejected",
+			args{
+				id: instantiateDefault(),
+				bs: []byte("foo"),
+			},
+			fmi.StatusError,
+		},
+		{
+			"State of another model is rejected",
+			args{
+				id: instantiateDefault(),
+				bs: fmuState(instantiateModelExchange()),
+			},
+			fmi.StatusError,
+		},
+		{
+			"Corrupt state is rejected",
+			args{
+				id: instantiateDefault(),
+				bs: func() []byte {
+					bs := fmuState(instantiateDefault())
+					bs[len(bs)-5] ^= 1
+					return bs
+				}(),
+			},
+			fmi.StatusError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			if got := fmi.SetFMUState(tt.args.id, tt.args.bs); got != tt.want {
 				t.Errorf("SetFMUState() = %v, want %v", got, tt.want)
 			}
+			fmi.FreeInstance(tt.args.id)
 		})
 	}
 }
+
+func TestSetFMUState_Rollback(t *testing.T) {
+	tests := []struct {
+		name string
+		// state is the state of the instance when its FMU state is copied and set
+		state fmi.ModelState
+		// step is the step size tried before the state is set
+		step float64
+		want fmi.ModelState
+	}{
+		{
+			"Discarded step is rolled back",
+			fmi.ModelStateStepComplete,
+			1,
+			fmi.ModelStateStepComplete,
+		},
+		{
+			"Completed step is rolled back",
+			fmi.ModelStateStepComplete,
+			0.5,
+			fmi.ModelStateStepComplete,
+		},
+		{
+			"Instantiated state is kept",
+			fmi.ModelStateInstantiated,
+			0,
+			fmi.ModelStateInstantiated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := instantiateState(fmi.FMUID(fmi.Instantiate("name", fmi.FMUTypeCoSimulation, "MaxStep", "", false, noopLogger)),
+				tt.state)
+			bs, s := fmi.GetFMUState(id)
+			if s != fmi.StatusOK {
+				t.Fatalf("GetFMUState() = %v", s)
+			}
+			if tt.step > 0 {
+				fmi.DoStep(id, 0, tt.step, false)
+			}
+			if s := fmi.SetFMUState(id, bs); s != fmi.StatusOK {
+				t.Fatalf("SetFMUState() = %v", s)
+			}
+			if tt.want == fmi.ModelStateStepComplete {
+				if s := fmi.DoStep(id, 0, 0.5, false); s != fmi.StatusOK {
+					t.Errorf("DoStep() after SetFMUState = %v, want %v", s, fmi.StatusOK)
+				}
+			}
+			verifyFMUStateAndCleanUp(t, id, tt.want)
+		})
+	}
+}
+
+// fmuState returns the state of instance id and frees it
+func fmuState(id fmi.FMUID) []byte {
+	defer fmi.FreeInstance(id)
+	bs, _ := fmi.GetFMUState(id)
+	return bs
+}
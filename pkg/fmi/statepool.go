@@ -0,0 +1,154 @@
+package fmi
+
+// #include <string.h>
+// #include "./c/fmi2Functions.h"
+// #include "bridge.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+/*
+statePool owns the FMU states returned to the environment by fmi2GetFMUstate and fmi2DeSerializeFMUstate.
+A state passed back to fmi2GetFMUstate is overwritten in place when its buffer is large enough,
+so an environment saving the state at every step does not allocate once the size is stable.
+States are only accepted by the instance that returned them and are freed in fmi2FreeInstance.
+*/
+type statePool struct {
+	memory allocator
+	// mu guards the states as fmi2GetFMUstate copies the state after the call to the instance returned
+	mu     sync.Mutex
+	states map[*C.ModelState]struct{}
+	// freed is set once the states are released by FreeInstance
+	freed bool
+}
+
+// get copies bs into ms if it is a state of the pool, or into a new state if ms is nil
+func (p *statePool) get(ms *C.ModelState, bs []byte) (*C.ModelState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.check(ms); err != nil {
+		return nil, err
+	}
+
+	n := len(bs)
+	if ms == nil || int(ms.capacity) < n {
+		c := n
+		if ms != nil && 2*int(ms.capacity) > c {
+			c = 2 * int(ms.capacity)
+		}
+		// the previous state is overwritten so the buffer is not copied
+		next, err := p.alloc(c)
+		if err != nil {
+			return nil, err
+		}
+		p.release(ms)
+		ms = next
+	}
+	ms.size = C.size_t(n)
+	if n > 0 {
+		C.memcpy(unsafe.Pointer(&ms.data[0]), unsafe.Pointer(&bs[0]), C.size_t(n))
+	}
+	return ms, nil
+}
+
+// copy returns a new state with the serialized state of size bytes at data
+func (p *statePool) copy(data unsafe.Pointer, size int) (*C.ModelState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.check(nil); err != nil {
+		return nil, err
+	}
+	ms, err := p.alloc(size)
+	if err != nil {
+		return nil, err
+	}
+	ms.size = C.size_t(size)
+	if size > 0 {
+		C.memcpy(unsafe.Pointer(&ms.data[0]), data, C.size_t(size))
+	}
+	return ms, nil
+}
+
+// bytes returns a copy of the data of state ms
+func (p *statePool) bytes(ms *C.ModelState) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ms == nil {
+		return nil, fmt.Errorf("Invalid argument %s = NULL", "FMUState")
+	}
+	if err := p.check(ms); err != nil {
+		return nil, err
+	}
+	return C.GoBytes(unsafe.Pointer(&ms.data[0]), C.int(ms.size)), nil
+}
+
+// remove frees state ms, nil is ignored
+func (p *statePool) remove(ms *C.ModelState) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.check(ms); err != nil {
+		return err
+	}
+	p.release(ms)
+	return nil
+}
+
+// allocated returns the number of states held by the pool
+func (p *statePool) allocated() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.states)
+}
+
+// free releases the states that were not freed by the environment, states cannot be returned afterwards
+func (p *statePool) free() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ms := range p.states {
+		p.memory.free(unsafe.Pointer(ms))
+	}
+	p.states = nil
+	p.freed = true
+}
+
+// check returns an error unless ms is nil or a state of the pool
+func (p *statePool) check(ms *C.ModelState) error {
+	if p.freed {
+		return errors.New("FMU states of the FMU have been freed")
+	}
+	if ms == nil {
+		return nil
+	}
+	if _, ok := p.states[ms]; !ok {
+		return fmt.Errorf("FMU state %p was not returned by this instance or has been freed", ms)
+	}
+	return nil
+}
+
+// alloc adds a state with room for capacity bytes
+func (p *statePool) alloc(capacity int) (*C.ModelState, error) {
+	ms := (*C.ModelState)(p.memory.alloc(C.sizeof_ModelState + capacity))
+	if ms == nil {
+		return nil, errors.New("Out of memory allocating FMU state")
+	}
+	ms.capacity = C.size_t(capacity)
+	if p.states == nil {
+		p.states = make(map[*C.ModelState]struct{})
+	}
+	p.states[ms] = struct{}{}
+	return ms, nil
+}
+
+// release frees state ms of the pool, nil is ignored
+func (p *statePool) release(ms *C.ModelState) {
+	if ms == nil {
+		return
+	}
+	delete(p.states, ms)
+	p.memory.free(unsafe.Pointer(ms))
+}
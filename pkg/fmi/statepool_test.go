@@ -0,0 +1,128 @@
+package fmi
+
+import (
+	"bytes"
+	"testing"
+	"unsafe"
+)
+
+func Test_statePool_get(t *testing.T) {
+	tests := []struct {
+		name string
+		// calls are the states of successive fmi2GetFMUstate calls reusing the previous state
+		calls [][]byte
+		// reused are the indexes of calls that return the state of the previous call
+		reused   []int
+		capacity int
+	}{
+		{
+			"Empty state",
+			[][]byte{{}},
+			nil,
+			0,
+		},
+		{
+			"State is copied",
+			[][]byte{[]byte("abc")},
+			nil,
+			3,
+		},
+		{
+			"Same size is reused",
+			[][]byte{[]byte("abc"), []byte("def"), []byte("ghi")},
+			[]int{1, 2},
+			3,
+		},
+		{
+			"Smaller state is reused",
+			[][]byte{[]byte("abcd"), []byte("e")},
+			[]int{1},
+			4,
+		},
+		{
+			"Larger state grows",
+			[][]byte{[]byte("ab"), []byte("cde"), []byte("f")},
+			[]int{2},
+			4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := statePool{memory: cAllocator{}}
+			defer p.free()
+			ms, err := p.get(nil, tt.calls[0])
+			if err != nil {
+				t.Fatalf("get() error = %v", err)
+			}
+			for i, bs := range tt.calls {
+				prev := ms
+				if i > 0 {
+					if ms, err = p.get(prev, bs); err != nil {
+						t.Fatalf("get() error = %v", err)
+					}
+				}
+				if got, err := p.bytes(ms); err != nil || !bytes.Equal(got, bs) {
+					t.Errorf("bytes() = %q, %v, want %q", got, err, bs)
+				}
+				reused := false
+				for _, r := range tt.reused {
+					reused = reused || r == i
+				}
+				if i > 0 && (ms == prev) != reused {
+					t.Errorf("Call %d reused state = %v, want %v", i, ms == prev, reused)
+				}
+			}
+			if got := p.allocated(); got != 1 {
+				t.Errorf("allocated() = %d, want 1", got)
+			}
+			if got := int(ms.capacity); got != tt.capacity {
+				t.Errorf("capacity = %d, want %d", got, tt.capacity)
+			}
+		})
+	}
+}
+
+func Test_statePool_check(t *testing.T) {
+	p := statePool{memory: cAllocator{}}
+	other := statePool{memory: cAllocator{}}
+	defer other.free()
+
+	ms, err := p.get(nil, []byte("abc"))
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	foreign, err := other.copy(unsafe.Pointer(&[]byte("def")[0]), 3)
+	if err != nil {
+		t.Fatalf("copy() error = %v", err)
+	}
+	if _, err := p.get(foreign, []byte("abc")); err == nil {
+		t.Errorf("get() expected error for state of another pool")
+	}
+	if _, err := p.bytes(foreign); err == nil {
+		t.Errorf("bytes() expected error for state of another pool")
+	}
+	if _, err := p.bytes(nil); err == nil {
+		t.Errorf("bytes() expected error for NULL state")
+	}
+
+	if err := p.remove(ms); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if err := p.remove(ms); err == nil {
+		t.Errorf("remove() expected error for freed state")
+	}
+	if err := p.remove(nil); err != nil {
+		t.Errorf("remove(nil) error = %v", err)
+	}
+
+	if _, err := p.get(nil, []byte("abc")); err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	p.free()
+	if n := p.allocated(); n != 0 {
+		t.Errorf("allocated() after free = %d, want 0", n)
+	}
+	if _, err := p.get(nil, []byte("abc")); err == nil {
+		t.Errorf("get() expected error after free")
+	}
+}
@@ -0,0 +1,188 @@
+package fmi
+
+import "strings"
+
+/*
+transition is a row of the state transition table for a function of the FMI interface.
+The function can only be called in one of the from states. The state after the call depends
+on the returned status: fmi2Error moves to ModelStateError and fmi2Fatal moves to ModelStateFatal
+for every function, other statuses move to the state in the row or stay in the current state if
+the row state is zero.
+*/
+type transition struct {
+	// from are the states the function can be called in
+	from ModelState
+	// to is the state after fmi2OK or fmi2Warning
+	to ModelState
+	// toFrom are the from states that move to the to state, all from states if zero
+	toFrom ModelState
+	// discard is the state after fmi2Discard
+	discard ModelState
+	// pending is the state after fmi2Pending
+	pending ModelState
+}
+
+// next returns the state after the function returned s in state
+func (t transition) next(state ModelState, s Status) ModelState {
+	var to ModelState
+	switch s {
+	case StatusOK, StatusWarning:
+		if t.toFrom == 0 || state&t.toFrom != 0 {
+			to = t.to
+		}
+	case StatusDiscard:
+		to = t.discard
+	case StatusPending:
+		to = t.pending
+	case StatusError:
+		to = ModelStateError
+	default:
+		to = ModelStateFatal
+	}
+	if to == 0 {
+		return state
+	}
+	return to
+}
+
+const (
+	// meStates are all model exchange states of an instance
+	meStates = ModelStateInstantiated | ModelStateInitializationMode | ModelStateEventMode |
+		ModelStateContinuousTimeMode | ModelStateTerminated | ModelStateError
+	// meGetStates are the model exchange states values can be read in
+	meGetStates = meStates &^ ModelStateInstantiated
+
+	// csStates are all co-simulation states of an instance
+	csStates = ModelStateInstantiated | ModelStateInitializationMode | ModelStateStepComplete |
+		ModelStateStepInProgress | ModelStateStepFailed | ModelStateStepCanceled |
+		ModelStateTerminated | ModelStateError
+	// csIdleStates are the co-simulation states without a pending step
+	csIdleStates = csStates &^ ModelStateStepInProgress
+	// csGetStates are the co-simulation states values can be read in
+	csGetStates = csIdleStates &^ ModelStateInstantiated
+	// csSetStates are the co-simulation states inputs can be set in
+	csSetStates = ModelStateInstantiated | ModelStateInitializationMode | ModelStateStepComplete
+	// csStatusStates are the co-simulation states the fmi2GetXXXStatus functions can be called in
+	csStatusStates = ModelStateStepComplete | ModelStateStepInProgress | ModelStateStepFailed |
+		ModelStateStepCanceled | ModelStateTerminated
+)
+
+// modelExchangeTransitions is the state machine of model exchange, see section 3.2.3 of the FMI 2.0 standard
+var modelExchangeTransitions = map[string]transition{
+	"SetDebugLogging":         {from: meStates},
+	"SetupExperiment":         {from: ModelStateInstantiated},
+	"EnterInitializationMode": {from: ModelStateInstantiated, to: ModelStateInitializationMode},
+	"ExitInitializationMode":  {from: ModelStateInitializationMode, to: ModelStateEventMode},
+	"Terminate":               {from: ModelStateEventMode | ModelStateContinuousTimeMode, to: ModelStateTerminated},
+	"Reset":                   {from: meStates, to: ModelStateInstantiated},
+
+	"GetReal":    {from: meGetStates},
+	"GetInteger": {from: meGetStates},
+	"GetBoolean": {from: meGetStates},
+	"GetString":  {from: meGetStates},
+	"SetReal": {from: ModelStateInstantiated | ModelStateInitializationMode |
+		ModelStateEventMode | ModelStateContinuousTimeMode},
+	// discrete variables can only change at events
+	"SetInteger": {from: ModelStateInstantiated | ModelStateInitializationMode | ModelStateEventMode},
+	"SetBoolean": {from: ModelStateInstantiated | ModelStateInitializationMode | ModelStateEventMode},
+	"SetString":  {from: ModelStateInstantiated | ModelStateInitializationMode | ModelStateEventMode},
+
+	"GetFMUstate":              {from: meStates},
+	"SetFMUstate":              {from: meStates},
+	"FreeFMUstate":             {from: meStates},
+	"SerializedFMUstateSize":   {from: meStates},
+	"SerializeFMUstate":        {from: meStates},
+	"DeSerializeFMUstate":      {from: meStates},
+	"GetDirectionalDerivative": {from: meGetStates},
+
+	"EnterEventMode":          {from: ModelStateEventMode | ModelStateContinuousTimeMode, to: ModelStateEventMode},
+	"NewDiscreteStates":       {from: ModelStateEventMode},
+	"EnterContinuousTimeMode": {from: ModelStateEventMode, to: ModelStateContinuousTimeMode},
+	"CompletedIntegratorStep": {from: ModelStateContinuousTimeMode},
+	"SetTime":                 {from: ModelStateEventMode | ModelStateContinuousTimeMode},
+	"SetContinuousStates":     {from: ModelStateContinuousTimeMode},
+	"GetDerivatives":          {from: meGetStates &^ ModelStateInitializationMode},
+	"GetEventIndicators":      {from: meGetStates},
+	"GetContinuousStates":     {from: meGetStates},
+	"GetNominalsOfContinuousStates": {from: ModelStateInstantiated | ModelStateEventMode |
+		ModelStateContinuousTimeMode | ModelStateTerminated | ModelStateError},
+}
+
+// coSimulationTransitions is the state machine of co-simulation, see section 4.2.4 of the FMI 2.0 standard
+var coSimulationTransitions = map[string]transition{
+	"SetDebugLogging":         {from: csStates},
+	"SetupExperiment":         {from: ModelStateInstantiated},
+	"EnterInitializationMode": {from: ModelStateInstantiated, to: ModelStateInitializationMode},
+	"ExitInitializationMode":  {from: ModelStateInitializationMode, to: ModelStateStepComplete},
+	"Terminate":               {from: ModelStateStepComplete | ModelStateStepFailed, to: ModelStateTerminated},
+	"Reset":                   {from: csIdleStates, to: ModelStateInstantiated},
+
+	"GetReal":    {from: csGetStates},
+	"GetInteger": {from: csGetStates},
+	"GetBoolean": {from: csGetStates},
+	"GetString":  {from: csGetStates},
+	"SetReal":    {from: csSetStates},
+	"SetInteger": {from: csSetStates},
+	"SetBoolean": {from: csSetStates},
+	"SetString":  {from: csSetStates},
+
+	"GetFMUstate": {from: csIdleStates},
+	// restoring a state rolls back a discarded step so the slave can step again
+	"SetFMUstate": {from: csIdleStates, to: ModelStateStepComplete,
+		toFrom: ModelStateStepComplete | ModelStateStepFailed},
+	"FreeFMUstate":           {from: csIdleStates},
+	"SerializedFMUstateSize": {from: csIdleStates},
+	"SerializeFMUstate":      {from: csIdleStates},
+	// deserializing only copies a state, the instance state changes when the copy is set
+	"DeSerializeFMUstate":      {from: csIdleStates},
+	"GetDirectionalDerivative": {from: csGetStates},
+
+	"SetRealInputDerivatives":  {from: csSetStates},
+	"GetRealOutputDerivatives": {from: csGetStates &^ ModelStateInitializationMode},
+	"DoStep": {from: ModelStateStepComplete, to: ModelStateStepComplete,
+		discard: ModelStateStepFailed, pending: ModelStateStepInProgress},
+	"CancelStep":       {from: ModelStateStepInProgress, to: ModelStateStepCanceled},
+	"GetStatus":        {from: csStatusStates},
+	"GetRealStatus":    {from: csStatusStates},
+	"GetIntegerStatus": {from: csStatusStates},
+	"GetBooleanStatus": {from: csStatusStates},
+	"GetStringStatus":  {from: csStatusStates},
+}
+
+// transitions returns the state transition table for the FMU type
+func (f *FMU) transitions() map[string]transition {
+	if f.Typee == FMUTypeModelExchange {
+		return modelExchangeTransitions
+	}
+	return coSimulationTransitions
+}
+
+// nextState returns the state after function name returned s, f.mu must be held
+func (f *FMU) nextState(name string, s Status) ModelState {
+	return f.transitions()[name].next(f.State, s)
+}
+
+// CurrentState returns the state of the FMU, it can be called while an asynchronous step is running
+func (f *FMU) CurrentState() ModelState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.State
+}
+
+var modelStateNames = [...]string{"StartAndEnd", "Instantiated", "InitializationMode", "EventMode",
+	"ContinuousTimeMode", "StepComplete", "StepInProgress", "StepFailed", "StepCanceled",
+	"Terminated", "Error", "Fatal"}
+
+// String returns the names of the states in s joined by |
+func (s ModelState) String() string {
+	var names []string
+	for i, name := range modelStateNames {
+		if s&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
@@ -0,0 +1,301 @@
+package fmi_test
+
+import (
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// states are all states an instance can be in
+var states = []fmi.ModelState{
+	fmi.ModelStateStartAndEnd,
+	fmi.ModelStateInstantiated,
+	fmi.ModelStateInitializationMode,
+	fmi.ModelStateEventMode,
+	fmi.ModelStateContinuousTimeMode,
+	fmi.ModelStateStepComplete,
+	fmi.ModelStateStepInProgress,
+	fmi.ModelStateStepFailed,
+	fmi.ModelStateStepCanceled,
+	fmi.ModelStateTerminated,
+	fmi.ModelStateError,
+	fmi.ModelStateFatal,
+}
+
+// call is a function of the FMI interface with fixed arguments
+type call struct {
+	name string
+	call func(id fmi.FMUID) fmi.Status
+	// allowed are the states the function can be called in
+	allowed fmi.ModelState
+}
+
+func commonCalls(all, get, set fmi.ModelState) []call {
+	vr := fmi.ValueReference{0}
+	return []call{
+		{"SetDebugLogging", func(id fmi.FMUID) fmi.Status { return fmi.SetDebugLogging(id, false, nil) }, all},
+		{"SetupExperiment", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetupExperiment(id, false, 0, 0, false, 0)
+		}, fmi.ModelStateInstantiated},
+		{"EnterInitializationMode", fmi.EnterInitializationMode, fmi.ModelStateInstantiated},
+		{"ExitInitializationMode", fmi.ExitInitializationMode, fmi.ModelStateInitializationMode},
+		{"GetReal", func(id fmi.FMUID) fmi.Status { _, s := fmi.GetReal(id, vr); return s }, get},
+		{"GetInteger", func(id fmi.FMUID) fmi.Status { _, s := fmi.GetInteger(id, vr); return s }, get},
+		{"GetBoolean", func(id fmi.FMUID) fmi.Status { _, s := fmi.GetBoolean(id, vr); return s }, get},
+		{"GetString", func(id fmi.FMUID) fmi.Status { _, s := fmi.GetString(id, vr); return s }, get},
+		{"SetInteger", func(id fmi.FMUID) fmi.Status { return fmi.SetInteger(id, vr, []int32{1}) }, set},
+		{"SetBoolean", func(id fmi.FMUID) fmi.Status { return fmi.SetBoolean(id, vr, []bool{true}) }, set},
+		{"SetString", func(id fmi.FMUID) fmi.Status { return fmi.SetString(id, vr, []string{"a"}) }, set},
+		{"GetFMUstate", func(id fmi.FMUID) fmi.Status { _, s := fmi.GetFMUState(id); return s }, all},
+		{"SetFMUstate", func(id fmi.FMUID) fmi.Status { return fmi.SetFMUState(id, nil) }, all},
+		{"GetDirectionalDerivative", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetDirectionalDerivative(id, vr, vr, []float64{1})
+			return s
+		}, get},
+	}
+}
+
+func modelExchangeCalls() []call {
+	all := fmi.ModelStateInstantiated | fmi.ModelStateInitializationMode | fmi.ModelStateEventMode |
+		fmi.ModelStateContinuousTimeMode | fmi.ModelStateTerminated | fmi.ModelStateError
+	get := all &^ fmi.ModelStateInstantiated
+	set := fmi.ModelStateInstantiated | fmi.ModelStateInitializationMode | fmi.ModelStateEventMode
+	return append(commonCalls(all, get, set),
+		call{"Terminate", fmi.Terminate, fmi.ModelStateEventMode | fmi.ModelStateContinuousTimeMode},
+		call{"Reset", fmi.Reset, all},
+		call{"SetReal", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetReal(id, fmi.ValueReference{0}, []float64{1})
+		}, set | fmi.ModelStateContinuousTimeMode},
+		call{"EnterEventMode", fmi.EnterEventMode, fmi.ModelStateEventMode | fmi.ModelStateContinuousTimeMode},
+		call{"NewDiscreteStates", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.NewDiscreteStates(id)
+			return s
+		}, fmi.ModelStateEventMode},
+		call{"EnterContinuousTimeMode", fmi.EnterContinuousTimeMode, fmi.ModelStateEventMode},
+		call{"CompletedIntegratorStep", func(id fmi.FMUID) fmi.Status {
+			_, _, s := fmi.CompletedIntegratorStep(id, false)
+			return s
+		}, fmi.ModelStateContinuousTimeMode},
+		call{"SetTime", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetTime(id, 0)
+		}, fmi.ModelStateEventMode | fmi.ModelStateContinuousTimeMode},
+		call{"SetContinuousStates", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetContinuousStates(id, []float64{1, 2})
+		}, fmi.ModelStateContinuousTimeMode},
+		call{"GetDerivatives", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetDerivatives(id, 2)
+			return s
+		}, get &^ fmi.ModelStateInitializationMode},
+		call{"GetEventIndicators", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetEventIndicators(id, 1)
+			return s
+		}, get},
+		call{"GetContinuousStates", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetContinuousStates(id, 2)
+			return s
+		}, get},
+		call{"GetNominalsOfContinuousStates", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetNominalsOfContinuousStates(id, 2)
+			return s
+		}, all &^ fmi.ModelStateInitializationMode},
+	)
+}
+
+func coSimulationCalls() []call {
+	all := fmi.ModelStateInstantiated | fmi.ModelStateInitializationMode | fmi.ModelStateStepComplete |
+		fmi.ModelStateStepInProgress | fmi.ModelStateStepFailed | fmi.ModelStateStepCanceled |
+		fmi.ModelStateTerminated | fmi.ModelStateError
+	idle := all &^ fmi.ModelStateStepInProgress
+	get := idle &^ fmi.ModelStateInstantiated
+	set := fmi.ModelStateInstantiated | fmi.ModelStateInitializationMode | fmi.ModelStateStepComplete
+	status := fmi.ModelStateStepComplete | fmi.ModelStateStepInProgress | fmi.ModelStateStepFailed |
+		fmi.ModelStateStepCanceled | fmi.ModelStateTerminated
+	calls := commonCalls(all, get, set)
+	// state functions can not be called while a step is pending
+	for i, c := range calls {
+		if c.name == "GetFMUstate" || c.name == "SetFMUstate" {
+			calls[i].allowed = idle
+		}
+	}
+	return append(calls,
+		call{"Terminate", fmi.Terminate, fmi.ModelStateStepComplete | fmi.ModelStateStepFailed},
+		call{"Reset", fmi.Reset, idle},
+		call{"SetReal", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetReal(id, fmi.ValueReference{0}, []float64{1})
+		}, set},
+		call{"SetRealInputDerivatives", func(id fmi.FMUID) fmi.Status {
+			return fmi.SetRealInputDerivatives(id, fmi.ValueReference{0}, []int32{1}, []float64{1})
+		}, set},
+		call{"GetRealOutputDerivatives", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetRealOutputDerivatives(id, fmi.ValueReference{0}, []int32{1})
+			return s
+		}, get &^ fmi.ModelStateInitializationMode},
+		call{"DoStep", func(id fmi.FMUID) fmi.Status {
+			return fmi.DoStep(id, 0, 1, false)
+		}, fmi.ModelStateStepComplete},
+		call{"CancelStep", fmi.CancelStep, fmi.ModelStateStepInProgress},
+		call{"GetStatus", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetStatus(id, fmi.StatusKindDoStep)
+			return s
+		}, status},
+		call{"GetRealStatus", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetRealStatus(id, fmi.StatusKindLastSuccessfulTime)
+			return s
+		}, status},
+		call{"GetIntegerStatus", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetIntegerStatus(id, fmi.StatusKindDoStep)
+			return s
+		}, status},
+		call{"GetBooleanStatus", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetBooleanStatus(id, fmi.StatusKindTerminated)
+			return s
+		}, status},
+		call{"GetStringStatus", func(id fmi.FMUID) fmi.Status {
+			_, s := fmi.GetStringStatus(id, fmi.StatusKindPending)
+			return s
+		}, status},
+	)
+}
+
+func TestIllegalCallSequences(t *testing.T) {
+	tests := []struct {
+		name        string
+		instantiate func(state ...fmi.ModelState) fmi.FMUID
+		calls       []call
+	}{
+		{
+			"Model exchange",
+			instantiateModelExchange,
+			modelExchangeCalls(),
+		},
+		{
+			"Co-simulation",
+			instantiateDefault,
+			coSimulationCalls(),
+		},
+	}
+	for _, tt := range tests {
+		for _, c := range tt.calls {
+			for _, state := range states {
+				if state&c.allowed != 0 {
+					continue
+				}
+				c, state := c, state
+				t.Run(tt.name+"/"+c.name+"/"+state.String(), func(t *testing.T) {
+					id := tt.instantiate(state)
+					if got := c.call(id); got != fmi.StatusError {
+						t.Errorf("%s() = %v, want %v", c.name, got, fmi.StatusError)
+					}
+					verifyFMUStateAndCleanUp(t, id, state)
+				})
+			}
+		}
+	}
+}
+
+func TestFMU_CurrentState(t *testing.T) {
+	tests := []struct {
+		name  string
+		id    fmi.FMUID
+		calls []func(id fmi.FMUID) fmi.Status
+		want  fmi.ModelState
+	}{
+		{
+			"Instantiated",
+			instantiateDefault(),
+			nil,
+			fmi.ModelStateInstantiated,
+		},
+		{
+			"Co-simulation initialized",
+			instantiateDefault(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode, fmi.ExitInitializationMode},
+			fmi.ModelStateStepComplete,
+		},
+		{
+			"Model exchange initialized",
+			instantiateModelExchange(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode, fmi.ExitInitializationMode},
+			fmi.ModelStateEventMode,
+		},
+		{
+			"Model exchange continuous time mode",
+			instantiateModelExchange(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode, fmi.ExitInitializationMode,
+				fmi.EnterContinuousTimeMode},
+			fmi.ModelStateContinuousTimeMode,
+		},
+		{
+			"Terminated",
+			instantiateDefault(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode, fmi.ExitInitializationMode,
+				fmi.Terminate},
+			fmi.ModelStateTerminated,
+		},
+		{
+			"Reset after terminate",
+			instantiateDefault(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode, fmi.ExitInitializationMode,
+				fmi.Terminate, fmi.Reset},
+			fmi.ModelStateInstantiated,
+		},
+		{
+			"Error state after model error",
+			instantiateInstanceErrors(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.EnterInitializationMode},
+			fmi.ModelStateError,
+		},
+		{
+			"Illegal call keeps state",
+			instantiateDefault(),
+			[]func(id fmi.FMUID) fmi.Status{fmi.ExitInitializationMode},
+			fmi.ModelStateInstantiated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer fmi.FreeInstance(tt.id)
+			for _, c := range tt.calls {
+				c(tt.id)
+			}
+			fmu, err := fmi.GetFMU(tt.id)
+			if err != nil {
+				t.Fatalf("Error getting FMU: %v", err)
+			}
+			if got := fmu.CurrentState(); got != tt.want {
+				t.Errorf("CurrentState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelState_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		state fmi.ModelState
+		want  string
+	}{
+		{
+			"None",
+			0,
+			"none",
+		},
+		{
+			"Single state",
+			fmi.ModelStateStepComplete,
+			"StepComplete",
+		},
+		{
+			"Multiple states",
+			fmi.ModelStateInstantiated | fmi.ModelStateFatal,
+			"Instantiated|Fatal",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.state.String(); got != tt.want {
+				t.Errorf("ModelState.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
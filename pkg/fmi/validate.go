@@ -0,0 +1,499 @@
+package fmi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// allowedVariabilities lists the variabilities allowed for each causality
+var allowedVariabilities = map[VariableCausality][]VariableVariability{
+	VariableCausalityParameter:           {VariableVariabilityFixed, VariableVariabilityTunable},
+	VariableCausalityCalculatedParameter: {VariableVariabilityFixed, VariableVariabilityTunable},
+	VariableCausalityInput:               {VariableVariabilityDiscrete, VariableVariabilityContinuous},
+	VariableCausalityOutput:              {VariableVariabilityConstant, VariableVariabilityDiscrete, VariableVariabilityContinuous},
+	VariableCausalityLocal: {VariableVariabilityConstant, VariableVariabilityFixed, VariableVariabilityTunable,
+		VariableVariabilityDiscrete, VariableVariabilityContinuous},
+	VariableCausalityIndependent: {VariableVariabilityContinuous},
+}
+
+// modelDescriptionValidator collects errors while checking a model description
+type modelDescriptionValidator struct {
+	desc  *ModelDescription
+	units map[string]bool
+	types map[string]SimpleType
+	errs  []error
+}
+
+/*
+Validate checks the model description against the FMI 2.0 rules documented on ScalarVariable,
+RealVariable, ModelStructure and Unknown. All errors found are returned, errors for a variable
+name the ScalarVariable index (the first variable has index 1). A valid model description returns nil.
+*/
+func (m ModelDescription) Validate() []error {
+	v := &modelDescriptionValidator{
+		desc:  &m,
+		units: map[string]bool{},
+		types: map[string]SimpleType{},
+	}
+	if m.GUID == "" {
+		v.errorf("Model description GUID cannot be empty")
+	}
+	if m.ModelExchange == nil && m.CoSimulation == nil {
+		v.errorf("Model description must define ModelExchange or CoSimulation")
+	}
+	v.validateDefinitions()
+	v.validateVariables()
+	v.validateModelStructure()
+	return v.errs
+}
+
+func (v *modelDescriptionValidator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// variableErrorf adds an error for the ScalarVariable at 1-based index
+func (v *modelDescriptionValidator) variableErrorf(index int, format string, args ...interface{}) {
+	sv := v.desc.ModelVariables[index-1]
+	v.errs = append(v.errs, fmt.Errorf("ScalarVariable %d (%s): %s", index, sv.Name, fmt.Sprintf(format, args...)))
+}
+
+func (v *modelDescriptionValidator) validateDefinitions() {
+	if v.desc.UnitDefinitions != nil {
+		for _, u := range *v.desc.UnitDefinitions {
+			if v.units[u.Name] {
+				v.errorf("Unit %s is defined more than once", u.Name)
+			}
+			v.units[u.Name] = true
+		}
+	}
+
+	if v.desc.TypeDefinitions != nil {
+		for _, t := range *v.desc.TypeDefinitions {
+			if _, ok := v.types[t.Name]; ok {
+				v.errorf("SimpleType %s is defined more than once", t.Name)
+			}
+			v.types[t.Name] = t
+			if t.Real != nil {
+				if err := v.checkUnits(t.Real.Unit, t.Real.DisplayUnit); err != nil {
+					v.errorf("SimpleType %s: %v", t.Name, err)
+				}
+			}
+		}
+	}
+}
+
+func (v *modelDescriptionValidator) validateVariables() {
+	names := map[string]int{}
+	independent := 0
+	for i := range v.desc.ModelVariables {
+		index := i + 1
+		sv := v.desc.ModelVariables[i]
+
+		if sv.Name == "" {
+			v.variableErrorf(index, "Name cannot be empty")
+		} else if first, ok := names[sv.Name]; ok {
+			v.variableErrorf(index, "Name is already used by ScalarVariable %d", first)
+		} else {
+			names[sv.Name] = index
+		}
+
+		causality, variability := sv.causality(), sv.variability()
+		if causality == VariableCausalityIndependent {
+			independent++
+			if independent > 1 {
+				v.variableErrorf(index, "At most one variable can have causality independent")
+			}
+		}
+		if !containsVariability(allowedVariabilities[causality], variability) {
+			v.variableErrorf(index, "Causality %s cannot have variability %s",
+				variableCausalityEnum[causality], variableVariabilityEnum[variability])
+		}
+
+		v.validateInitial(index, sv)
+
+		if sv.CanHandleMultipleSetPerTimeInstant {
+			if causality != VariableCausalityInput {
+				v.variableErrorf(index, "CanHandleMultipleSetPerTimeInstant is only allowed for inputs")
+			}
+			if v.desc.ModelExchange == nil {
+				v.variableErrorf(index, "CanHandleMultipleSetPerTimeInstant is only allowed for ModelExchange")
+			}
+		}
+
+		if err := sv.ScalarVariableType.validateType(); err != nil {
+			v.variableErrorf(index, "%v", err)
+			continue
+		}
+		if variability == VariableVariabilityContinuous && sv.Real == nil {
+			v.variableErrorf(index, "Only Real variables can have variability continuous")
+		}
+		v.validateVariableType(index, sv)
+	}
+}
+
+// validateInitial checks initial against causality and variability and whether a start value is required
+func (v *modelDescriptionValidator) validateInitial(index int, sv ScalarVariable) {
+	causality, variability := sv.causality(), sv.variability()
+	def, allowed := initialRule(causality, variability)
+	initial := def
+	if sv.Initial != nil {
+		initial = *sv.Initial
+		if allowed == nil {
+			v.variableErrorf(index, "Initial cannot be set for causality %s", variableCausalityEnum[causality])
+		} else if !containsInitial(allowed, initial) {
+			v.variableErrorf(index, "Initial %s is not allowed for causality %s and variability %s",
+				variableInitialEnum[initial], variableCausalityEnum[causality], variableVariabilityEnum[variability])
+		}
+	}
+
+	if sv.ScalarVariableType == nil {
+		return
+	}
+	hasStart := sv.ScalarVariableType.hasStart()
+	switch {
+	case causality == VariableCausalityIndependent && hasStart:
+		v.variableErrorf(index, "Start value is not allowed for causality independent")
+	case allowed != nil && initial == VariableInitialCalculated && hasStart:
+		v.variableErrorf(index, "Start value is not allowed for initial calculated")
+	case !hasStart && (causality == VariableCausalityInput || causality == VariableCausalityParameter):
+		v.variableErrorf(index, "Start value is required for causality %s", variableCausalityEnum[causality])
+	case !hasStart && variability == VariableVariabilityConstant:
+		v.variableErrorf(index, "Start value is required for variability constant")
+	case !hasStart && allowed != nil && initial != VariableInitialCalculated:
+		v.variableErrorf(index, "Start value is required for initial %s", variableInitialEnum[initial])
+	}
+}
+
+func (v *modelDescriptionValidator) validateVariableType(index int, sv ScalarVariable) {
+	t := sv.ScalarVariableType
+	var declared string
+	var kind string
+	switch {
+	case t.Real != nil:
+		declared, kind = t.Real.DeclaredType.DeclaredType, "Real"
+		unit := t.Real.Unit
+		if st, ok := v.types[declared]; ok && unit == "" && st.Real != nil {
+			unit = st.Real.Unit
+		}
+		if err := v.checkUnits(unit, t.Real.DisplayUnit); err != nil {
+			v.variableErrorf(index, "%v", err)
+		}
+		if t.Real.Derivative != nil {
+			d := *t.Real.Derivative
+			if d < 1 || d != math.Trunc(d) || !v.inRange(uint(d)) {
+				v.variableErrorf(index, "Derivative %v is not a ScalarVariable index", d)
+			} else if uint(d) == uint(index) {
+				v.variableErrorf(index, "Variable cannot be a derivative of itself")
+			}
+		}
+		if t.Real.Reinit {
+			if v.desc.ModelExchange == nil {
+				v.variableErrorf(index, "Reinit is only allowed for ModelExchange")
+			}
+			if !v.isState(uint(index)) {
+				v.variableErrorf(index, "Reinit is only allowed for continuous-time states")
+			}
+		}
+	case t.Integer != nil:
+		declared, kind = t.Integer.DeclaredType.DeclaredType, "Integer"
+	case t.Boolean != nil:
+		declared, kind = t.Boolean.DeclaredType.DeclaredType, "Boolean"
+	case t.String != nil:
+		declared, kind = t.String.DeclaredType.DeclaredType, "String"
+	case t.Enumeration != nil:
+		declared, kind = t.Enumeration.DeclaredType.DeclaredType, "Enumeration"
+		if declared == "" {
+			v.variableErrorf(index, "Enumeration must have a declaredType")
+			return
+		}
+	}
+
+	if declared == "" {
+		return
+	}
+	st, ok := v.types[declared]
+	if !ok {
+		v.variableErrorf(index, "DeclaredType %s is not defined in TypeDefinitions", declared)
+		return
+	}
+	if simpleTypeKind(st) != kind {
+		v.variableErrorf(index, "DeclaredType %s is not a %s type", declared, kind)
+	}
+}
+
+func (v *modelDescriptionValidator) validateModelStructure() {
+	ms := v.desc.ModelStructure
+
+	outputs := map[uint]bool{}
+	if ms.Outputs != nil {
+		for _, u := range *ms.Outputs {
+			if !v.validateUnknown("Outputs", u, false) {
+				continue
+			}
+			outputs[u.Index] = true
+			if v.desc.ModelVariables[u.Index-1].causality() != VariableCausalityOutput {
+				v.variableErrorf(int(u.Index), "Listed in ModelStructure Outputs but causality is not output")
+			}
+		}
+	}
+	for i, sv := range v.desc.ModelVariables {
+		if sv.causality() == VariableCausalityOutput && !outputs[uint(i+1)] {
+			v.variableErrorf(i+1, "Causality output must be listed in ModelStructure Outputs")
+		}
+	}
+
+	if ms.Derivatives != nil {
+		for _, u := range *ms.Derivatives {
+			if !v.validateUnknown("Derivatives", u, false) {
+				continue
+			}
+			sv := v.desc.ModelVariables[u.Index-1]
+			if sv.ScalarVariableType == nil || sv.Real == nil || sv.Real.Derivative == nil {
+				v.variableErrorf(int(u.Index), "Listed in ModelStructure Derivatives but is not a Real with derivative")
+			}
+		}
+	}
+
+	if ms.InitialUnknowns != nil {
+		var previous uint
+		for _, u := range *ms.InitialUnknowns {
+			if !v.validateUnknown("InitialUnknowns", u, true) {
+				continue
+			}
+			if u.Index <= previous {
+				v.variableErrorf(int(u.Index), "ModelStructure InitialUnknowns must be ordered by index without duplicates")
+			}
+			previous = u.Index
+		}
+	}
+	v.validateRequiredInitialUnknowns()
+}
+
+// validateUnknown checks the unknown index and dependencies, returning false if the index is invalid
+func (v *modelDescriptionValidator) validateUnknown(list string, u Unknown, initial bool) bool {
+	if !v.inRange(u.Index) {
+		v.errorf("ModelStructure %s index %d is not a ScalarVariable index", list, u.Index)
+		return false
+	}
+
+	var previous uint
+	for _, d := range u.Dependencies {
+		if !v.inRange(d) {
+			v.variableErrorf(int(u.Index), "ModelStructure %s dependency %d is not a ScalarVariable index", list, d)
+		} else if d <= previous {
+			v.variableErrorf(int(u.Index), "ModelStructure %s dependencies must be ordered by index without duplicates", list)
+		}
+		previous = d
+	}
+
+	if len(u.DependenciesKind) == 0 {
+		return true
+	}
+	if len(u.DependenciesKind) != len(u.Dependencies) {
+		v.variableErrorf(int(u.Index), "ModelStructure %s dependenciesKind has %d elements but dependencies has %d",
+			list, len(u.DependenciesKind), len(u.Dependencies))
+	}
+	sv := v.desc.ModelVariables[u.Index-1]
+	isReal := sv.ScalarVariableType != nil && sv.Real != nil
+	for _, k := range u.DependenciesKind {
+		switch k {
+		case "dependent":
+		case "constant":
+			if !isReal {
+				v.variableErrorf(int(u.Index), "ModelStructure %s dependenciesKind %s is only allowed for Real unknowns", list, k)
+			}
+		case "fixed", "tunable", "discrete":
+			if !isReal || initial {
+				v.variableErrorf(int(u.Index), "ModelStructure %s dependenciesKind %s is only allowed for Real unknowns outside InitialUnknowns", list, k)
+			}
+		default:
+			v.variableErrorf(int(u.Index), "ModelStructure %s dependenciesKind %s is not valid", list, k)
+		}
+	}
+	return true
+}
+
+// validateRequiredInitialUnknowns checks variables that must be listed in ModelStructure InitialUnknowns
+func (v *modelDescriptionValidator) validateRequiredInitialUnknowns() {
+	listed := map[uint]bool{}
+	if v.desc.ModelStructure.InitialUnknowns != nil {
+		for _, u := range *v.desc.ModelStructure.InitialUnknowns {
+			listed[u.Index] = true
+		}
+	}
+	states := map[uint]bool{}
+	for _, i := range v.stateIndices() {
+		states[i] = true
+	}
+	if v.desc.ModelStructure.Derivatives != nil {
+		for _, u := range *v.desc.ModelStructure.Derivatives {
+			states[u.Index] = true
+		}
+	}
+
+	for i, sv := range v.desc.ModelVariables {
+		index := uint(i + 1)
+		causality := sv.causality()
+		def, allowed := initialRule(causality, sv.variability())
+		initial := def
+		if sv.Initial != nil {
+			initial = *sv.Initial
+		}
+		calculated := allowed != nil && (initial == VariableInitialApprox || initial == VariableInitialCalculated)
+
+		required := causality == VariableCausalityCalculatedParameter ||
+			(causality == VariableCausalityOutput && calculated) ||
+			(states[index] && calculated)
+		if required && !listed[index] {
+			v.variableErrorf(int(index), "Must be listed in ModelStructure InitialUnknowns")
+		}
+	}
+}
+
+// checkUnits checks displayUnit requires unit and unit is defined in UnitDefinitions.
+// A displayUnit that is not defined for the unit is ignored by importers so is not checked.
+func (v *modelDescriptionValidator) checkUnits(unit, displayUnit string) error {
+	if displayUnit != "" && unit == "" {
+		return fmt.Errorf("DisplayUnit %s is defined without unit", displayUnit)
+	}
+	if unit != "" && !v.units[unit] {
+		return fmt.Errorf("Unit %s is not defined in UnitDefinitions", unit)
+	}
+	return nil
+}
+
+func (v *modelDescriptionValidator) inRange(index uint) bool {
+	return index >= 1 && index <= uint(len(v.desc.ModelVariables))
+}
+
+// stateIndices returns indices of variables that are the derivative target of a listed state derivative
+func (v *modelDescriptionValidator) stateIndices() []uint {
+	ms := v.desc.ModelStructure
+	if ms.Derivatives == nil {
+		return nil
+	}
+	var is []uint
+	for _, u := range *ms.Derivatives {
+		if !v.inRange(u.Index) {
+			continue
+		}
+		sv := v.desc.ModelVariables[u.Index-1]
+		if sv.ScalarVariableType != nil && sv.Real != nil && sv.Real.Derivative != nil {
+			is = append(is, uint(*sv.Real.Derivative))
+		}
+	}
+	return is
+}
+
+func (v *modelDescriptionValidator) isState(index uint) bool {
+	for _, i := range v.stateIndices() {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+initialRule returns the default initial and allowed initial values for causality and variability.
+Allowed is nil if initial must not be set.
+*/
+func initialRule(c VariableCausality, v VariableVariability) (VariableInitial, []VariableInitial) {
+	switch c {
+	case VariableCausalityParameter:
+		return VariableInitialExact, []VariableInitial{VariableInitialExact}
+	case VariableCausalityCalculatedParameter:
+		return VariableInitialCalculated, []VariableInitial{VariableInitialApprox, VariableInitialCalculated}
+	case VariableCausalityOutput, VariableCausalityLocal:
+		switch v {
+		case VariableVariabilityConstant:
+			return VariableInitialExact, []VariableInitial{VariableInitialExact}
+		case VariableVariabilityFixed, VariableVariabilityTunable:
+			return VariableInitialCalculated, []VariableInitial{VariableInitialApprox, VariableInitialCalculated}
+		}
+		return VariableInitialCalculated, []VariableInitial{VariableInitialExact, VariableInitialApprox, VariableInitialCalculated}
+	}
+	return 0, nil
+}
+
+func (sv ScalarVariable) causality() VariableCausality {
+	if sv.Causality == nil {
+		return VariableCausalityLocal
+	}
+	return *sv.Causality
+}
+
+func (sv ScalarVariable) variability() VariableVariability {
+	if sv.Variability == nil {
+		return VariableVariabilityContinuous
+	}
+	return *sv.Variability
+}
+
+// validateType checks exactly one variable type is set
+func (v *ScalarVariableType) validateType() error {
+	if v == nil {
+		return errors.New("Variable type must be set")
+	}
+	n := 0
+	for _, set := range []bool{v.Real != nil, v.Integer != nil, v.Boolean != nil, v.String != nil, v.Enumeration != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("Exactly one variable type must be set, got %d", n)
+	}
+	return nil
+}
+
+func (v *ScalarVariableType) hasStart() bool {
+	switch {
+	case v.Real != nil:
+		return v.Real.Start != nil
+	case v.Integer != nil:
+		return v.Integer.Start != nil
+	case v.Boolean != nil:
+		return v.Boolean.Start != nil
+	case v.String != nil:
+		return v.String.Start != ""
+	case v.Enumeration != nil:
+		return v.Enumeration.Start != nil
+	}
+	return false
+}
+
+func simpleTypeKind(t SimpleType) string {
+	switch {
+	case t.Real != nil:
+		return "Real"
+	case t.Integer != nil:
+		return "Integer"
+	case t.Boolean != nil:
+		return "Boolean"
+	case t.String != nil:
+		return "String"
+	case t.Enumeration != nil:
+		return "Enumeration"
+	}
+	return ""
+}
+
+func containsVariability(vs []VariableVariability, v VariableVariability) bool {
+	for _, x := range vs {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInitial(is []VariableInitial, i VariableInitial) bool {
+	for _, x := range is {
+		if x == i {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+package fmi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validModelDescription() ModelDescription {
+	causality := func(c VariableCausality) *VariableCausality { return &c }
+	variability := func(v VariableVariability) *VariableVariability { return &v }
+	float := func(f float64) *float64 { return &f }
+	return ModelDescription{
+		GUID: "guid",
+		ModelExchange: &ModelExchange{
+			FMUShared: FMUShared{ModelIdentifier: "id"},
+		},
+		UnitDefinitions: &[]Unit{
+			{Name: "m"},
+		},
+		TypeDefinitions: &[]SimpleType{
+			{Name: "Position", Real: &RealType{Unit: "m"}},
+			{Name: "Mode", Enumeration: &EnumerationType{}},
+		},
+		ModelVariables: []ScalarVariable{
+			{
+				Name:        "x",
+				Causality:   causality(VariableCausalityOutput),
+				Variability: variability(VariableVariabilityContinuous),
+				ScalarVariableType: &ScalarVariableType{
+					Real: &RealVariable{
+						DeclaredType: DeclaredType{DeclaredType: "Position"},
+						Start:        float(1),
+						Reinit:       true,
+					},
+				},
+				Initial: func() *VariableInitial { i := VariableInitialExact; return &i }(),
+			},
+			{
+				Name: "der(x)",
+				ScalarVariableType: &ScalarVariableType{
+					Real: &RealVariable{
+						Derivative: float(1),
+					},
+				},
+			},
+			{
+				Name:        "k",
+				Causality:   causality(VariableCausalityParameter),
+				Variability: variability(VariableVariabilityFixed),
+				ScalarVariableType: &ScalarVariableType{
+					Real: &RealVariable{
+						Start: float(2),
+					},
+				},
+			},
+			{
+				Name:        "mode",
+				Causality:   causality(VariableCausalityInput),
+				Variability: variability(VariableVariabilityDiscrete),
+				ScalarVariableType: &ScalarVariableType{
+					Enumeration: &EnumerationVariable{
+						DeclaredType: DeclaredType{DeclaredType: "Mode"},
+						Start:        func() *int32 { i := int32(1); return &i }(),
+					},
+				},
+			},
+		},
+		ModelStructure: ModelStructure{
+			Outputs: &[]Unknown{
+				{Index: 1},
+			},
+			Derivatives: &[]Unknown{
+				{Index: 2, Dependencies: UintAttributeList{1}, DependenciesKind: StringAttributeList{"fixed"}},
+			},
+			InitialUnknowns: &[]Unknown{
+				{Index: 2},
+			},
+		},
+	}
+}
+
+func TestModelDescription_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(m *ModelDescription)
+		want   []string
+	}{
+		{
+			"Valid model description",
+			func(m *ModelDescription) {},
+			nil,
+		},
+		{
+			"GUID and FMU type are required",
+			func(m *ModelDescription) {
+				m.GUID = ""
+				m.ModelExchange = nil
+				m.ModelVariables[0].Real.Reinit = false
+			},
+			[]string{
+				"Model description GUID cannot be empty",
+				"Model description must define ModelExchange or CoSimulation",
+			},
+		},
+		{
+			"Illegal causality and variability combination",
+			func(m *ModelDescription) {
+				v := VariableVariabilityContinuous
+				m.ModelVariables[2].Variability = &v
+			},
+			[]string{
+				"ScalarVariable 3 (k): Causality parameter cannot have variability continuous",
+			},
+		},
+		{
+			"Only Real variables can be continuous",
+			func(m *ModelDescription) {
+				m.ModelVariables[3].Variability = nil
+			},
+			[]string{
+				"ScalarVariable 4 (mode): Only Real variables can have variability continuous",
+			},
+		},
+		{
+			"Initial is not allowed for inputs",
+			func(m *ModelDescription) {
+				i := VariableInitialExact
+				m.ModelVariables[3].Initial = &i
+			},
+			[]string{
+				"ScalarVariable 4 (mode): Initial cannot be set for causality input",
+			},
+		},
+		{
+			"Illegal initial for parameter",
+			func(m *ModelDescription) {
+				i := VariableInitialCalculated
+				m.ModelVariables[2].Initial = &i
+			},
+			[]string{
+				"ScalarVariable 3 (k): Initial calculated is not allowed for causality parameter and variability fixed",
+				"ScalarVariable 3 (k): Start value is not allowed for initial calculated",
+			},
+		},
+		{
+			"Start value on calculated variable",
+			func(m *ModelDescription) {
+				f := 1.0
+				m.ModelVariables[1].Real.Start = &f
+			},
+			[]string{
+				"ScalarVariable 2 (der(x)): Start value is not allowed for initial calculated",
+			},
+		},
+		{
+			"Start value is required for parameters",
+			func(m *ModelDescription) {
+				m.ModelVariables[2].Real.Start = nil
+			},
+			[]string{
+				"ScalarVariable 3 (k): Start value is required for causality parameter",
+			},
+		},
+		{
+			"Start value is required for initial exact",
+			func(m *ModelDescription) {
+				m.ModelVariables[0].Real.Start = nil
+			},
+			[]string{
+				"ScalarVariable 1 (x): Start value is required for initial exact",
+			},
+		},
+		{
+			"Enumeration requires declared type",
+			func(m *ModelDescription) {
+				m.ModelVariables[3].Enumeration.DeclaredType.DeclaredType = ""
+			},
+			[]string{
+				"ScalarVariable 4 (mode): Enumeration must have a declaredType",
+			},
+		},
+		{
+			"Declared type must be defined with matching type",
+			func(m *ModelDescription) {
+				m.ModelVariables[3].Enumeration.DeclaredType.DeclaredType = "Position"
+				m.ModelVariables[2].Real.DeclaredType.DeclaredType = "Missing"
+			},
+			[]string{
+				"ScalarVariable 3 (k): DeclaredType Missing is not defined in TypeDefinitions",
+				"ScalarVariable 4 (mode): DeclaredType Position is not a Enumeration type",
+			},
+		},
+		{
+			"Display unit requires unit",
+			func(m *ModelDescription) {
+				m.ModelVariables[2].Real.DisplayUnit = "mm"
+				(*m.TypeDefinitions)[0].Real.DisplayUnit = "mm"
+				(*m.TypeDefinitions)[0].Real.Unit = ""
+			},
+			[]string{
+				"SimpleType Position: DisplayUnit mm is defined without unit",
+				"ScalarVariable 3 (k): DisplayUnit mm is defined without unit",
+			},
+		},
+		{
+			"Unit must be defined",
+			func(m *ModelDescription) {
+				m.ModelVariables[2].Real.Unit = "s"
+			},
+			[]string{
+				"ScalarVariable 3 (k): Unit s is not defined in UnitDefinitions",
+			},
+		},
+		{
+			"Display unit uses unit of declared type",
+			func(m *ModelDescription) {
+				m.ModelVariables[0].Real.DisplayUnit = "mm"
+			},
+			nil,
+		},
+		{
+			"Outputs must be listed in model structure",
+			func(m *ModelDescription) {
+				m.ModelStructure.Outputs = nil
+			},
+			[]string{
+				"ScalarVariable 1 (x): Causality output must be listed in ModelStructure Outputs",
+			},
+		},
+		{
+			"Model structure outputs must have causality output",
+			func(m *ModelDescription) {
+				*m.ModelStructure.Outputs = append(*m.ModelStructure.Outputs, Unknown{Index: 3}, Unknown{Index: 5})
+			},
+			[]string{
+				"ScalarVariable 3 (k): Listed in ModelStructure Outputs but causality is not output",
+				"ModelStructure Outputs index 5 is not a ScalarVariable index",
+			},
+		},
+		{
+			"Derivatives must have derivative attribute",
+			func(m *ModelDescription) {
+				*m.ModelStructure.Derivatives = append(*m.ModelStructure.Derivatives, Unknown{Index: 3})
+			},
+			[]string{
+				"ScalarVariable 3 (k): Listed in ModelStructure Derivatives but is not a Real with derivative",
+			},
+		},
+		{
+			"Reinit is only allowed for states",
+			func(m *ModelDescription) {
+				m.ModelVariables[2].Real.Reinit = true
+			},
+			[]string{
+				"ScalarVariable 3 (k): Reinit is only allowed for continuous-time states",
+			},
+		},
+		{
+			"Derivative must be a variable index",
+			func(m *ModelDescription) {
+				f := 9.0
+				m.ModelVariables[1].Real.Derivative = &f
+				m.ModelVariables[0].Real.Reinit = false
+			},
+			[]string{
+				"ScalarVariable 2 (der(x)): Derivative 9 is not a ScalarVariable index",
+			},
+		},
+		{
+			"Unknown dependencies are checked",
+			func(m *ModelDescription) {
+				(*m.ModelStructure.Derivatives)[0].Dependencies = UintAttributeList{3, 1, 7}
+				(*m.ModelStructure.Derivatives)[0].DependenciesKind = StringAttributeList{"constant", "wrong"}
+			},
+			[]string{
+				"ScalarVariable 2 (der(x)): ModelStructure Derivatives dependencies must be ordered by index without duplicates",
+				"ScalarVariable 2 (der(x)): ModelStructure Derivatives dependency 7 is not a ScalarVariable index",
+				"ScalarVariable 2 (der(x)): ModelStructure Derivatives dependenciesKind has 2 elements but dependencies has 3",
+				"ScalarVariable 2 (der(x)): ModelStructure Derivatives dependenciesKind wrong is not valid",
+			},
+		},
+		{
+			"Fixed dependencies are not allowed for initial unknowns",
+			func(m *ModelDescription) {
+				(*m.ModelStructure.InitialUnknowns)[0].Dependencies = UintAttributeList{1}
+				(*m.ModelStructure.InitialUnknowns)[0].DependenciesKind = StringAttributeList{"fixed"}
+			},
+			[]string{
+				"ScalarVariable 2 (der(x)): ModelStructure InitialUnknowns dependenciesKind fixed is only allowed for Real unknowns outside InitialUnknowns",
+			},
+		},
+		{
+			"Calculated state derivatives must be initial unknowns",
+			func(m *ModelDescription) {
+				m.ModelStructure.InitialUnknowns = nil
+			},
+			[]string{
+				"ScalarVariable 2 (der(x)): Must be listed in ModelStructure InitialUnknowns",
+			},
+		},
+		{
+			"Initial unknowns must be ordered",
+			func(m *ModelDescription) {
+				*m.ModelStructure.InitialUnknowns = append(*m.ModelStructure.InitialUnknowns, Unknown{Index: 1})
+			},
+			[]string{
+				"ScalarVariable 1 (x): ModelStructure InitialUnknowns must be ordered by index without duplicates",
+			},
+		},
+		{
+			"Variable names must be unique and types set",
+			func(m *ModelDescription) {
+				m.ModelVariables = append(m.ModelVariables, ScalarVariable{Name: "k"})
+			},
+			[]string{
+				"ScalarVariable 5 (k): Name is already used by ScalarVariable 3",
+				"ScalarVariable 5 (k): Variable type must be set",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := validModelDescription()
+			tt.modify(&m)
+			var got []string
+			for _, err := range m.Validate() {
+				got = append(got, err.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
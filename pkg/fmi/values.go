@@ -19,19 +19,20 @@ func fmi2GetReal(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, value
 	var rs []C.fmi2Real
 	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&rs), int(nvr))
 	fs, s := GetReal(FMUID(c), vs)
-	if s != StatusOK {
+	if !succeeded(s) {
 		return C.fmi2Status(s)
 	}
 	copyRealArray(fs, rs)
-	return C.fmi2OK
+	return C.fmi2Status(s)
 }
 
 // GetReal gets real values by value reference
-func GetReal(id FMUID, vr ValueReference) ([]float64, Status) {
-	fmu, ok := allowedGetValue(id, "GetReal")
+func GetReal(id FMUID, vr ValueReference) (_ []float64, status Status) {
+	fmu, ok := allowedState(id, "GetReal")
 	if !ok {
 		return nil, StatusError
 	}
+	defer fmu.release(&status)
 
 	vg, err := fmu.ValueGetter()
 	if err != nil {
@@ -40,11 +41,15 @@ func GetReal(id FMUID, vr ValueReference) ([]float64, Status) {
 	}
 
 	fs, err := vg.GetReal(vr)
-	if err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling GetReal: %w", err))
+	s := fmu.modelStatus("GetReal", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+	if err := checkValues("GetReal", vr, len(fs)); err != nil {
+		fmu.logger.Error(err)
 		return nil, StatusError
 	}
-	return fs, StatusOK
+	return fs, s
 }
 
 //export fmi2GetInteger
@@ -56,19 +61,20 @@ func fmi2GetInteger(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, val
 	var is []C.fmi2Integer
 	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&is), int(nvr))
 	ints, s := GetInteger(FMUID(c), vs)
-	if s != StatusOK {
+	if !succeeded(s) {
 		return C.fmi2Status(s)
 	}
 	copyIntegerArray(ints, is)
-	return C.fmi2OK
+	return C.fmi2Status(s)
 }
 
 // GetInteger gets integer values by value reference
-func GetInteger(id FMUID, vr ValueReference) ([]int32, Status) {
-	fmu, ok := allowedGetValue(id, "GetInteger")
+func GetInteger(id FMUID, vr ValueReference) (_ []int32, status Status) {
+	fmu, ok := allowedState(id, "GetInteger")
 	if !ok {
 		return nil, StatusError
 	}
+	defer fmu.release(&status)
 
 	vg, err := fmu.ValueGetter()
 	if err != nil {
@@ -77,11 +83,15 @@ func GetInteger(id FMUID, vr ValueReference) ([]int32, Status) {
 	}
 
 	is, err := vg.GetInteger(vr)
-	if err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling GetInteger: %w", err))
+	s := fmu.modelStatus("GetInteger", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+	if err := checkValues("GetInteger", vr, len(is)); err != nil {
+		fmu.logger.Error(err)
 		return nil, StatusError
 	}
-	return is, StatusOK
+	return is, s
 }
 
 //export fmi2GetBoolean
@@ -93,19 +103,20 @@ func fmi2GetBoolean(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, val
 	var bs []C.fmi2Boolean
 	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&bs), int(nvr))
 	bools, s := GetBoolean(FMUID(c), vs)
-	if s != StatusOK {
+	if !succeeded(s) {
 		return C.fmi2Status(s)
 	}
 	copyBooleanArray(bools, bs)
-	return C.fmi2OK
+	return C.fmi2Status(s)
 }
 
 // GetBoolean gets boolean values by value reference
-func GetBoolean(id FMUID, vr ValueReference) ([]bool, Status) {
-	fmu, ok := allowedGetValue(id, "GetBoolean")
+func GetBoolean(id FMUID, vr ValueReference) (_ []bool, status Status) {
+	fmu, ok := allowedState(id, "GetBoolean")
 	if !ok {
 		return nil, StatusError
 	}
+	defer fmu.release(&status)
 
 	vg, err := fmu.ValueGetter()
 	if err != nil {
@@ -114,12 +125,16 @@ func GetBoolean(id FMUID, vr ValueReference) ([]bool, Status) {
 	}
 
 	bs, err := vg.GetBoolean(vr)
-	if err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling GetBoolean: %w", err))
+	s := fmu.modelStatus("GetBoolean", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+	if err := checkValues("GetBoolean", vr, len(bs)); err != nil {
+		fmu.logger.Error(err)
 		return nil, StatusError
 	}
 
-	return bs, StatusOK
+	return bs, s
 }
 
 //export fmi2GetString
@@ -130,20 +145,26 @@ func fmi2GetString(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, valu
 	}
 	var ss []C.fmi2String
 	carrayToSlice(unsafe.Pointer(value), unsafe.Pointer(&ss), int(nvr))
-	strs, s := GetString(FMUID(c), vs)
-	if s != StatusOK {
-		return C.fmi2Status(s)
-	}
-	copyStringArray(strs, ss)
-	return C.fmi2OK
+	_, s := getString(FMUID(c), vs, ss)
+	return C.fmi2Status(s)
 }
 
 // GetString gets string values by value reference
 func GetString(id FMUID, vr ValueReference) ([]string, Status) {
-	fmu, ok := allowedGetValue(id, "GetString")
+	return getString(id, vr, nil)
+}
+
+/*
+getString gets string values by value reference and, if value is not nil, copies them to value
+as C strings owned by the FMU until the next call. The strings are copied before the instance is released
+so that a concurrent call or fmi2FreeInstance cannot change them first.
+*/
+func getString(id FMUID, vr ValueReference, value []C.fmi2String) (_ []string, status Status) {
+	fmu, ok := allowedState(id, "GetString")
 	if !ok {
 		return nil, StatusError
 	}
+	defer fmu.release(&status)
 
 	vg, err := fmu.ValueGetter()
 	if err != nil {
@@ -152,12 +173,24 @@ func GetString(id FMUID, vr ValueReference) ([]string, Status) {
 	}
 
 	ss, err := vg.GetString(vr)
-	if err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling GetString: %w", err))
+	s := fmu.modelStatus("GetString", err)
+	if !succeeded(s) {
+		return nil, s
+	}
+	if err := checkValues("GetString", vr, len(ss)); err != nil {
+		fmu.logger.Error(err)
 		return nil, StatusError
 	}
+	if value != nil {
+		cs, err := fmu.strings.strings(ss)
+		if err != nil {
+			fmu.logger.Error(err)
+			return nil, StatusError
+		}
+		copyStringArray(cs, value)
+	}
 
-	return ss, StatusOK
+	return ss, s
 }
 
 //export fmi2SetReal
@@ -175,11 +208,12 @@ func fmi2SetReal(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, value
 }
 
 // SetReal sets floats by value references
-func SetReal(id FMUID, vr ValueReference, fs []float64) Status {
-	fmu, ok := allowedSetValue(id, "SetReal")
+func SetReal(id FMUID, vr ValueReference, fs []float64) (status Status) {
+	fmu, ok := allowedState(id, "SetReal")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	vs, err := fmu.ValueSetter()
 	if err != nil {
@@ -187,12 +221,7 @@ func SetReal(id FMUID, vr ValueReference, fs []float64) Status {
 		return StatusError
 	}
 
-	if err := vs.SetReal(vr, fs); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling SetReal: %w", err))
-		return StatusError
-	}
-
-	return StatusOK
+	return fmu.modelStatus("SetReal", vs.SetReal(vr, fs))
 }
 
 //export fmi2SetInteger
@@ -210,11 +239,12 @@ func fmi2SetInteger(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, val
 }
 
 // SetInteger sets ints by value references
-func SetInteger(id FMUID, vr ValueReference, is []int32) Status {
-	fmu, ok := allowedSetValue(id, "SetInteger")
+func SetInteger(id FMUID, vr ValueReference, is []int32) (status Status) {
+	fmu, ok := allowedState(id, "SetInteger")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	vs, err := fmu.ValueSetter()
 	if err != nil {
@@ -222,12 +252,7 @@ func SetInteger(id FMUID, vr ValueReference, is []int32) Status {
 		return StatusError
 	}
 
-	if err := vs.SetInteger(vr, is); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling SetInteger: %w", err))
-		return StatusError
-	}
-
-	return StatusOK
+	return fmu.modelStatus("SetInteger", vs.SetInteger(vr, is))
 }
 
 //export fmi2SetBoolean
@@ -245,11 +270,12 @@ func fmi2SetBoolean(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, val
 }
 
 // SetBoolean sets bools by value references
-func SetBoolean(id FMUID, vr ValueReference, bs []bool) Status {
-	fmu, ok := allowedSetValue(id, "SetBoolean")
+func SetBoolean(id FMUID, vr ValueReference, bs []bool) (status Status) {
+	fmu, ok := allowedState(id, "SetBoolean")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	vs, err := fmu.ValueSetter()
 	if err != nil {
@@ -257,12 +283,7 @@ func SetBoolean(id FMUID, vr ValueReference, bs []bool) Status {
 		return StatusError
 	}
 
-	if err := vs.SetBoolean(vr, bs); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling SetBoolean: %w", err))
-		return StatusError
-	}
-
-	return StatusOK
+	return fmu.modelStatus("SetBoolean", vs.SetBoolean(vr, bs))
 }
 
 //export fmi2SetString
@@ -280,11 +301,12 @@ func fmi2SetString(c C.fmi2Component, vr C.valueReferences_t, nvr C.size_t, valu
 }
 
 // SetString sets strings by value references
-func SetString(id FMUID, vr ValueReference, ss []string) Status {
-	fmu, ok := allowedSetValue(id, "SetString")
+func SetString(id FMUID, vr ValueReference, ss []string) (status Status) {
+	fmu, ok := allowedState(id, "SetString")
 	if !ok {
 		return StatusError
 	}
+	defer fmu.release(&status)
 
 	vs, err := fmu.ValueSetter()
 	if err != nil {
@@ -292,20 +314,7 @@ func SetString(id FMUID, vr ValueReference, ss []string) Status {
 		return StatusError
 	}
 
-	if err := vs.SetString(vr, ss); err != nil {
-		fmu.logger.Error(fmt.Errorf("Error calling SetString: %w", err))
-		return StatusError
-	}
-
-	return StatusOK
-}
-
-//export fmi2GetDirectionalDerivative
-func fmi2GetDirectionalDerivative(c C.fmi2Component, vUnknown_ref C.valueReferences_t, nUnknown C.size_t,
-	vKnown_ref C.valueReferences_t, nKnown C.size_t,
-	dvKnown C.fmi2Reals_t, dvUnknown *C.fmi2Real) C.fmi2Status {
-	// TODO: implement
-	return C.fmi2OK
+	return fmu.modelStatus("SetString", vs.SetString(vr, ss))
 }
 
 func valueReferences(vr C.valueReferences_t, nvr C.size_t) (ValueReference, error) {
@@ -325,3 +334,11 @@ func valueReferences(vr C.valueReferences_t, nvr C.size_t) (ValueReference, erro
 	}
 	return vrs, nil
 }
+
+// checkValues returns an error if a model returned n values for vr, which would overrun the array of the caller
+func checkValues(name string, vr ValueReference, n int) error {
+	if n != len(vr) {
+		return fmt.Errorf("%s returned %d values for %d value references", name, n, len(vr))
+	}
+	return nil
+}
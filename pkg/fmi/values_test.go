@@ -31,10 +31,10 @@ func TestGetReal(t *testing.T) {
 		{
 			"GetReal error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateContinuousTimeMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateContinuousTimeMode,
+			fmi.ModelStateError,
 			nil,
 		},
 		{
@@ -95,10 +95,10 @@ func TestGetInteger(t *testing.T) {
 		{
 			"GetInteger error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateContinuousTimeMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateContinuousTimeMode,
+			fmi.ModelStateError,
 			nil,
 		},
 		{
@@ -159,10 +159,10 @@ func TestGetBoolean(t *testing.T) {
 		{
 			"GetBoolean error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateContinuousTimeMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateContinuousTimeMode,
+			fmi.ModelStateError,
 			nil,
 		},
 		{
@@ -223,10 +223,10 @@ func TestGetString(t *testing.T) {
 		{
 			"GetString error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateContinuousTimeMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateContinuousTimeMode,
+			fmi.ModelStateError,
 			nil,
 		},
 		{
@@ -286,20 +286,20 @@ func TestSetReal(t *testing.T) {
 		{
 			"SetReal error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateEventMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateError,
 		},
 		{
 			"SetReal called without error",
 			args{
-				instantiateDefault(fmi.ModelStateEventMode),
+				instantiateDefault(fmi.ModelStateStepComplete),
 				fmi.ValueReference{0, 1},
 				[]float64{1.2, 1.3},
 			},
 			fmi.StatusOK,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateStepComplete,
 		},
 	}
 	for _, tt := range tests {
@@ -335,20 +335,20 @@ func TestSetInteger(t *testing.T) {
 		{
 			"SetInteger error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateEventMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateError,
 		},
 		{
 			"SetInteger called without error",
 			args{
-				instantiateDefault(fmi.ModelStateEventMode),
+				instantiateDefault(fmi.ModelStateStepComplete),
 				fmi.ValueReference{0, 1},
 				[]int32{0, 1},
 			},
 			fmi.StatusOK,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateStepComplete,
 		},
 	}
 	for _, tt := range tests {
@@ -384,20 +384,20 @@ func TestSetBoolean(t *testing.T) {
 		{
 			"SetBoolean error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateEventMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateError,
 		},
 		{
 			"SetBoolean called without error",
 			args{
-				instantiateDefault(fmi.ModelStateEventMode),
+				instantiateDefault(fmi.ModelStateStepComplete),
 				fmi.ValueReference{0, 1},
 				[]bool{true, false},
 			},
 			fmi.StatusOK,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateStepComplete,
 		},
 	}
 	for _, tt := range tests {
@@ -433,20 +433,20 @@ func TestSetString(t *testing.T) {
 		{
 			"SetString error is returned",
 			args{
-				id: instantiateInstanceErrors(fmi.ModelStateEventMode),
+				id: instantiateInstanceErrors(fmi.ModelStateStepComplete),
 			},
 			fmi.StatusError,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateError,
 		},
 		{
 			"SetString called without error",
 			args{
-				instantiateDefault(fmi.ModelStateEventMode),
+				instantiateDefault(fmi.ModelStateStepComplete),
 				fmi.ValueReference{0, 1},
 				[]string{"a", "b"},
 			},
 			fmi.StatusOK,
-			fmi.ModelStateEventMode,
+			fmi.ModelStateStepComplete,
 		},
 	}
 	for _, tt := range tests {
@@ -1,9 +1,7 @@
 package fmi
 
 import (
-	"bytes"
 	"encoding"
-	"encoding/gob"
 	"errors"
 	"fmt"
 	"reflect"
@@ -16,6 +14,7 @@ type ModelVariables interface {
 	ValueGetterSetter
 	StateEncoder
 	StateDecoder
+	StateEncodingNamer
 
 	// Variables returns scalar variables to be used in model description
 	Variables() []ScalarVariable
@@ -24,16 +23,33 @@ type ModelVariables interface {
 type modelVariables struct {
 	model   interface{}
 	scalars []ScalarVariable
+	// codec encodes the state, gob if nil
+	codec StateCodec
 }
 
 // NewModelVariables reflects the provided value to create a model variables list.
-// The type should be a struct with all exported fields.
+// The type should be a struct, or a pointer to a struct, with all exported fields.
+// Variables are set in the struct pointed to, or in a copy of a struct value.
 // Struct field tags are used to annotate the fields so we can infer model structure.
-// This implementation uses gob encoding to handle state transmission from library.
-func NewModelVariables(model interface{}) (ModelVariables, error) {
-	st := reflect.TypeOf(model)
-	if st.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("Requires struct kind, got %s", st.Kind())
+// The state is gob encoded unless another codec is set with WithStateCodec.
+func NewModelVariables(model interface{}, options ...ModelVariablesOption) (ModelVariables, error) {
+	v := reflect.ValueOf(model)
+	switch {
+	case v.Kind() == reflect.Struct:
+		// values are set and decoded into an addressable copy
+		p := reflect.New(v.Type())
+		p.Elem().Set(v)
+		model = p.Interface()
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct:
+		if v.IsNil() {
+			return nil, errors.New("Model struct pointer is nil")
+		}
+	default:
+		return nil, fmt.Errorf("Requires struct kind, got %s", v.Kind())
+	}
+	st := v.Type()
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
 	}
 	nf := st.NumField()
 	if nf == 0 {
@@ -48,31 +64,38 @@ func NewModelVariables(model interface{}) (ModelVariables, error) {
 		}
 		svs[i] = v
 	}
-	return &modelVariables{
+	m := &modelVariables{
 		model:   model,
 		scalars: svs,
-	}, nil
+	}
+	for _, o := range options {
+		o(m)
+	}
+	return m, nil
 }
 
 func (m modelVariables) Variables() []ScalarVariable {
 	return m.scalars
 }
 
-func (m modelVariables) Encode() ([]byte, error) {
-	bs := &bytes.Buffer{}
-	enc := gob.NewEncoder(bs)
-	if err := enc.Encode(m.model); err != nil {
-		return nil, fmt.Errorf("Error gob encoding model variable state: %w", err)
+func (m modelVariables) stateCodec() StateCodec {
+	if m.codec == nil {
+		return GobStateCodec
 	}
-	return bs.Bytes(), nil
+	return m.codec
+}
+
+// StateEncoding names the encoding of the state returned by Encode
+func (m modelVariables) StateEncoding() string {
+	return m.stateCodec().Name()
+}
+
+func (m modelVariables) Encode() ([]byte, error) {
+	return m.stateCodec().Encode(m.model)
 }
 
 func (m *modelVariables) Decode(rs []byte) error {
-	dec := gob.NewDecoder(bytes.NewBuffer(rs))
-	if err := dec.Decode(m.model); err != nil {
-		return err
-	}
-	return nil
+	return m.stateCodec().Decode(rs, m.model)
 }
 
 func (m modelVariables) GetReal(vr ValueReference) (fs []float64, err error) {
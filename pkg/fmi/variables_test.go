@@ -1,6 +1,8 @@
 package fmi
 
 import (
+	"bytes"
+	"encoding/gob"
 	"reflect"
 	"testing"
 
@@ -25,6 +27,38 @@ func TestNewModelVariables(t *testing.T) {
 			nil,
 			true,
 		},
+		{
+			"Returns error if nil struct pointer",
+			args{
+				(*struct{ A float64 })(nil),
+			},
+			nil,
+			true,
+		},
+		{
+			"Struct pointer is used for variables",
+			args{
+				&struct{ A float64 }{
+					41.2,
+				},
+			},
+			&modelVariables{
+				model: &struct{ A float64 }{
+					41.2,
+				},
+				scalars: []ScalarVariable{
+					{
+						ScalarVariableType: &ScalarVariableType{
+							variableType: VariableTypeReal,
+							Real:         &RealVariable{},
+						},
+						Name:           "A",
+						ValueReference: 1,
+					},
+				},
+			},
+			false,
+		},
 		{
 			"Struct must have at least one field",
 			args{
@@ -59,7 +93,7 @@ func TestNewModelVariables(t *testing.T) {
 				},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A float64
 					B int32
 					C bool
@@ -139,7 +173,7 @@ func TestNewModelVariables(t *testing.T) {
 				},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A float64 `description:"foo" causality:"parameter" variability:"tunable" initial:"approx" canhandlemultiplesetpertimeinstant:"true"`
 				}{
 					42,
@@ -209,7 +243,7 @@ func TestNewModelVariables(t *testing.T) {
 				}{},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A float64 `declaredtype:"foo" start:"1" derivative:"0.2" reinit:"true" quantity:"angle" unit:"kg" displayunit:"kilograms" relativequantity:"true" min:"0.01" max:"3" nominal:"1" unbounded:"true"`
 				}{},
 				scalars: []ScalarVariable{
@@ -287,7 +321,7 @@ func TestNewModelVariables(t *testing.T) {
 				}{},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A int32 `declaredtype:"foo" start:"1"  quantity:"angle" min:"1" max:"3"`
 				}{},
 				scalars: []ScalarVariable{
@@ -352,7 +386,7 @@ func TestNewModelVariables(t *testing.T) {
 				}{},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A string `declaredtype:"foo" start:"potato" quantity:"bar"`
 				}{},
 				scalars: []ScalarVariable{
@@ -386,7 +420,7 @@ func TestNewModelVariables(t *testing.T) {
 				}{},
 			},
 			&modelVariables{
-				model: struct {
+				model: &struct {
 					A bool `declaredtype:"foo" start:"true" quantity:"bar"`
 				}{},
 				scalars: []ScalarVariable{
@@ -493,7 +527,11 @@ func Test_modelVariables_Encode(t *testing.T) {
 					A: 42,
 				},
 			},
-			[]byte{18, 255, 129, 3, 1, 2, 255, 130, 0, 1, 1, 1, 1, 65, 1, 8, 0, 0, 0, 7, 255, 130, 1, 254, 69, 64, 0},
+			gobEncode(&struct {
+				A float64
+			}{
+				A: 42,
+			}),
 			false,
 		},
 		{
@@ -527,6 +565,16 @@ func Test_modelVariables_Encode(t *testing.T) {
 	}
 }
 
+// gobEncode encodes the value with a fresh encoder.
+// gob type ids are not stable across Go releases, so expected bytes are built at runtime.
+func gobEncode(v interface{}) []byte {
+	bs := &bytes.Buffer{}
+	if err := gob.NewEncoder(bs).Encode(v); err != nil {
+		panic(err)
+	}
+	return bs.Bytes()
+}
+
 func Test_modelVariables_Decode(t *testing.T) {
 	type fields struct {
 		model   interface{}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+const modelDescriptionFile = "modelDescription.xml"
+
+// Archive is an opened .fmu file
+type Archive struct {
+	// ModelDescription is parsed from modelDescription.xml in the archive
+	ModelDescription fmi.ModelDescription
+
+	zr *zip.ReadCloser
+}
+
+// OpenArchive opens the .fmu file at path and parses its model description
+func OpenArchive(path string) (*Archive, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening FMU %s: %w", path, err)
+	}
+	a := &Archive{zr: zr}
+
+	f := a.file(modelDescriptionFile)
+	if f == nil {
+		zr.Close()
+		return nil, fmt.Errorf("FMU %s does not contain %s", path, modelDescriptionFile)
+	}
+	r, err := f.Open()
+	if err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("Error reading %s: %w", modelDescriptionFile, err)
+	}
+	defer r.Close()
+	a.ModelDescription, err = fmi.ParseModelDescription(r)
+	if err != nil {
+		zr.Close()
+		return nil, err
+	}
+	return a, nil
+}
+
+// Close closes the archive file
+func (a *Archive) Close() error {
+	return a.zr.Close()
+}
+
+// Platforms returns the platforms in binaries/ in sorted order, for example linux64
+func (a *Archive) Platforms() []string {
+	var platforms []string
+	seen := map[string]bool{}
+	for _, f := range a.zr.File {
+		ps := strings.Split(f.Name, "/")
+		if len(ps) < 3 || ps[0] != "binaries" || ps[2] == "" || seen[ps[1]] {
+			continue
+		}
+		seen[ps[1]] = true
+		platforms = append(platforms, ps[1])
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// Resources returns the files in resources/, relative to that directory
+func (a *Archive) Resources() []string {
+	return a.files("resources/")
+}
+
+// Documentation returns the files in documentation/, relative to that directory
+func (a *Archive) Documentation() []string {
+	return a.files("documentation/")
+}
+
+func (a *Archive) files(prefix string) []string {
+	var names []string
+	for _, f := range a.zr.File {
+		if strings.HasPrefix(f.Name, prefix) && !strings.HasSuffix(f.Name, "/") {
+			names = append(names, strings.TrimPrefix(f.Name, prefix))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *Archive) file(name string) *zip.File {
+	for _, f := range a.zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Extract writes all files in the archive to dir. Entries outside dir are rejected.
+func (a *Archive) Extract(dir string) error {
+	for _, f := range a.zr.File {
+		name := path.Clean(f.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("Archive entry %s is outside the FMU directory", f.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("Error extracting %s: %w", f.Name, err)
+			}
+			continue
+		}
+		if err := extractFile(f, target); err != nil {
+			return fmt.Errorf("Error extracting %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// ExtractTemp extracts the archive to a new temporary directory and returns it.
+// The caller should remove the directory when the FMU is no longer used.
+func (a *Archive) ExtractTemp() (string, error) {
+	dir, err := ioutil.TempDir("", "fmu")
+	if err != nil {
+		return "", fmt.Errorf("Error creating FMU directory: %w", err)
+	}
+	if err := a.Extract(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	mode := os.FileMode(0644)
+	if f.Mode()&0111 != 0 {
+		mode = 0755
+	}
+	w, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ResourceLocation returns the file URI of the resources directory in an extracted FMU directory,
+// as passed to fmi2Instantiate
+func ResourceLocation(dir string) (string, error) {
+	if dir == "" {
+		return "", errors.New("FMU directory cannot be empty")
+	}
+	abs, err := filepath.Abs(filepath.Join(dir, "resources"))
+	if err != nil {
+		return "", fmt.Errorf("Error resolving resources directory: %w", err)
+	}
+	p := filepath.ToSlash(abs)
+	// Windows paths such as C:/fmu need a leading slash to form file:///C:/fmu
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return u.String(), nil
+}
@@ -0,0 +1,202 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// writeFMU writes a zip archive with files to a new .fmu in a temporary directory
+func writeFMU(t *testing.T, files map[string][]byte) string {
+	p := filepath.Join(t.TempDir(), "test.fmu")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func readFile(t *testing.T, name string) []byte {
+	bs, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+// bouncingBallFMU writes an FMU archive containing the BouncingBall library built in TestMain
+func bouncingBallFMU(t *testing.T) string {
+	platform, err := importer.Platform()
+	if err != nil {
+		t.Skip(err)
+	}
+	return writeFMU(t, map[string][]byte{
+		"modelDescription.xml": readFile(t, "../fmi/testdata/BouncingBall.xml"),
+		"binaries/" + platform + "/" + filepath.Base(bouncingBall): readFile(t, bouncingBall),
+		"binaries/win64/BouncingBall.dll":                          []byte("dll"),
+		"resources/table.csv":                                      []byte("t,h"),
+		"resources/sub/":                                           nil,
+		"documentation/index.html":                                 []byte("<html></html>"),
+	})
+}
+
+func TestOpenArchive(t *testing.T) {
+	platform, err := importer.Platform()
+	if err != nil {
+		t.Skip(err)
+	}
+	a, err := importer.OpenArchive(bouncingBallFMU(t))
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer a.Close()
+
+	if a.ModelDescription.GUID != guid {
+		t.Errorf("ModelDescription.GUID = %s, want %s", a.ModelDescription.GUID, guid)
+	}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Platforms", a.Platforms(), []string{platform, "win64"}},
+		{"Resources", a.Resources(), []string{"table.csv"}},
+		{"Documentation", a.Documentation(), []string{"index.html"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s() = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestOpenArchive_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{
+			"Missing file",
+			func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.fmu") },
+		},
+		{
+			"Missing model description",
+			func(t *testing.T) string {
+				return writeFMU(t, map[string][]byte{"resources/a.txt": []byte("a")})
+			},
+		},
+		{
+			"Invalid model description",
+			func(t *testing.T) string {
+				return writeFMU(t, map[string][]byte{"modelDescription.xml": []byte("<fmiModelDescription>")})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := importer.OpenArchive(tt.path(t)); err == nil {
+				t.Errorf("OpenArchive() expected error")
+			}
+		})
+	}
+}
+
+func TestArchive_ExtractTemp(t *testing.T) {
+	a, err := importer.OpenArchive(bouncingBallFMU(t))
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer a.Close()
+
+	dir, err := a.ExtractTemp()
+	if err != nil {
+		t.Fatalf("ExtractTemp() error = %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if got := string(readFile(t, filepath.Join(dir, "resources", "table.csv"))); got != "t,h" {
+		t.Errorf("Extracted resource = %s, want t,h", got)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "resources", "sub")); err != nil || !fi.IsDir() {
+		t.Errorf("Expected resources/sub directory, got %v", err)
+	}
+
+	resources, err := importer.ResourceLocation(dir)
+	if err != nil {
+		t.Fatalf("ResourceLocation() error = %v", err)
+	}
+	want := "file://" + filepath.ToSlash(filepath.Join(dir, "resources"))
+	if resources != want {
+		t.Errorf("ResourceLocation() = %s, want %s", resources, want)
+	}
+
+	id := a.ModelDescription.CoSimulation.ModelIdentifier
+	p, err := importer.BinaryPath(dir, id)
+	if err != nil {
+		t.Fatalf("BinaryPath() error = %v", err)
+	}
+	lib, err := importer.Load(p)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer lib.Close()
+	inst, err := lib.Instantiate(id, fmi.FMUTypeCoSimulation, a.ModelDescription.GUID, resources, importer.Callbacks{}, false, false)
+	if err != nil {
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	inst.FreeInstance()
+}
+
+func TestArchive_Extract_OutsideDirectory(t *testing.T) {
+	a, err := importer.OpenArchive(writeFMU(t, map[string][]byte{
+		"modelDescription.xml": readFile(t, "../fmi/testdata/BouncingBall.xml"),
+		"../escape.txt":        []byte("escape"),
+	}))
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer a.Close()
+
+	dir := t.TempDir()
+	err = a.Extract(filepath.Join(dir, "fmu"))
+	if err == nil || !strings.Contains(err.Error(), "outside the FMU directory") {
+		t.Errorf("Extract() error = %v, want entry outside directory error", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected escape.txt not to be extracted")
+	}
+}
+
+func TestResourceLocation(t *testing.T) {
+	if _, err := importer.ResourceLocation(""); err == nil {
+		t.Errorf("ResourceLocation() expected error for empty directory")
+	}
+	got, err := importer.ResourceLocation("/tmp/my fmu")
+	if err != nil {
+		t.Fatalf("ResourceLocation() error = %v", err)
+	}
+	if got != "file:///tmp/my%20fmu/resources" {
+		t.Errorf("ResourceLocation() = %s", got)
+	}
+}
@@ -0,0 +1,326 @@
+#include <dlfcn.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include "bridge.h"
+#include "_cgo_export.h"
+
+#define BRIDGE_LOAD(name) f->name = (name##TYPE*)dlsym(handle, #name);
+#define BRIDGE_MISSING(name) if (f->name == NULL) return #name;
+
+void bridge_load(void* handle, bridge_functions* f)
+{
+    BRIDGE_COMMON_FUNCTIONS(BRIDGE_LOAD)
+    BRIDGE_MODEL_EXCHANGE_FUNCTIONS(BRIDGE_LOAD)
+    BRIDGE_CO_SIMULATION_FUNCTIONS(BRIDGE_LOAD)
+}
+
+const char* bridge_missing(const bridge_functions* f, int fmuType)
+{
+    BRIDGE_COMMON_FUNCTIONS(BRIDGE_MISSING)
+    if (fmuType == fmi2ModelExchange) {
+        BRIDGE_MODEL_EXCHANGE_FUNCTIONS(BRIDGE_MISSING)
+    }
+    if (fmuType == fmi2CoSimulation) {
+        BRIDGE_CO_SIMULATION_FUNCTIONS(BRIDGE_MISSING)
+    }
+    return NULL;
+}
+
+// bridge_logger formats the message and forwards it to the Go logger
+static void bridge_logger(fmi2ComponentEnvironment componentEnvironment,
+    fmi2String instanceName,
+    fmi2Status status,
+    fmi2String category,
+    fmi2String message, ...)
+{
+    va_list args, size;
+    va_start(args, message);
+    va_copy(size, args);
+    int n = vsnprintf(NULL, 0, message, size);
+    va_end(size);
+    if (n < 0) {
+        va_end(args);
+        return;
+    }
+    char* buf = malloc(n + 1);
+    if (buf == NULL) {
+        va_end(args);
+        return;
+    }
+    vsnprintf(buf, n + 1, message, args);
+    va_end(args);
+    importerLogger(componentEnvironment, (char*)instanceName, status, (char*)category, buf);
+    free(buf);
+}
+
+static void bridge_stepFinished(fmi2ComponentEnvironment componentEnvironment, fmi2Status status)
+{
+    importerStepFinished(componentEnvironment, status);
+}
+
+// allocations counts the memory allocated by FMUs with allocateMemory that has not been freed
+static long allocations;
+
+static void* bridge_allocateMemory(size_t nobj, size_t size)
+{
+    void* p = calloc(nobj, size);
+    if (p != NULL) {
+        __atomic_add_fetch(&allocations, 1, __ATOMIC_SEQ_CST);
+    }
+    return p;
+}
+
+static void bridge_freeMemory(void* obj)
+{
+    if (obj != NULL) {
+        __atomic_sub_fetch(&allocations, 1, __ATOMIC_SEQ_CST);
+    }
+    free(obj);
+}
+
+long bridge_allocations(void)
+{
+    return __atomic_load_n(&allocations, __ATOMIC_SEQ_CST);
+}
+
+fmi2CallbackFunctions* bridge_callbacks(fmi2ComponentEnvironment env, int stepFinished)
+{
+    fmi2CallbackFunctions* functions = malloc(sizeof(fmi2CallbackFunctions));
+    if (functions == NULL) {
+        return NULL;
+    }
+    functions->logger = bridge_logger;
+    functions->allocateMemory = bridge_allocateMemory;
+    functions->freeMemory = bridge_freeMemory;
+    functions->stepFinished = stepFinished ? bridge_stepFinished : NULL;
+    functions->componentEnvironment = env;
+    return functions;
+}
+
+const char* bridge_fmi2GetTypesPlatform(const bridge_functions* f)
+{
+    return f->fmi2GetTypesPlatform();
+}
+
+const char* bridge_fmi2GetVersion(const bridge_functions* f)
+{
+    return f->fmi2GetVersion();
+}
+
+fmi2Status bridge_fmi2SetDebugLogging(const bridge_functions* f, fmi2Component c, fmi2Boolean loggingOn, size_t nCategories, const fmi2String categories[])
+{
+    return f->fmi2SetDebugLogging(c, loggingOn, nCategories, categories);
+}
+
+fmi2Component bridge_fmi2Instantiate(const bridge_functions* f, fmi2String instanceName, fmi2Type fmuType, fmi2String fmuGUID,
+    fmi2String fmuResourceLocation, const fmi2CallbackFunctions* functions, fmi2Boolean visible, fmi2Boolean loggingOn)
+{
+    return f->fmi2Instantiate(instanceName, fmuType, fmuGUID, fmuResourceLocation, functions, visible, loggingOn);
+}
+
+void bridge_fmi2FreeInstance(const bridge_functions* f, fmi2Component c)
+{
+    f->fmi2FreeInstance(c);
+}
+
+fmi2Status bridge_fmi2SetupExperiment(const bridge_functions* f, fmi2Component c, fmi2Boolean toleranceDefined, fmi2Real tolerance,
+    fmi2Real startTime, fmi2Boolean stopTimeDefined, fmi2Real stopTime)
+{
+    return f->fmi2SetupExperiment(c, toleranceDefined, tolerance, startTime, stopTimeDefined, stopTime);
+}
+
+fmi2Status bridge_fmi2EnterInitializationMode(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2EnterInitializationMode(c);
+}
+
+fmi2Status bridge_fmi2ExitInitializationMode(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2ExitInitializationMode(c);
+}
+
+fmi2Status bridge_fmi2Terminate(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2Terminate(c);
+}
+
+fmi2Status bridge_fmi2Reset(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2Reset(c);
+}
+
+fmi2Status bridge_fmi2GetReal(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Real value[])
+{
+    return f->fmi2GetReal(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2GetInteger(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Integer value[])
+{
+    return f->fmi2GetInteger(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2GetBoolean(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Boolean value[])
+{
+    return f->fmi2GetBoolean(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2GetString(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2String value[])
+{
+    return f->fmi2GetString(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2SetReal(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Real value[])
+{
+    return f->fmi2SetReal(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2SetInteger(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Integer value[])
+{
+    return f->fmi2SetInteger(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2SetBoolean(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Boolean value[])
+{
+    return f->fmi2SetBoolean(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2SetString(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2String value[])
+{
+    return f->fmi2SetString(c, vr, nvr, value);
+}
+
+fmi2Status bridge_fmi2GetFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate* FMUstate)
+{
+    return f->fmi2GetFMUstate(c, FMUstate);
+}
+
+fmi2Status bridge_fmi2SetFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate)
+{
+    return f->fmi2SetFMUstate(c, FMUstate);
+}
+
+fmi2Status bridge_fmi2FreeFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate* FMUstate)
+{
+    return f->fmi2FreeFMUstate(c, FMUstate);
+}
+
+fmi2Status bridge_fmi2SerializedFMUstateSize(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate, size_t* size)
+{
+    return f->fmi2SerializedFMUstateSize(c, FMUstate, size);
+}
+
+fmi2Status bridge_fmi2SerializeFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate, fmi2Byte serializedState[], size_t size)
+{
+    return f->fmi2SerializeFMUstate(c, FMUstate, serializedState, size);
+}
+
+fmi2Status bridge_fmi2DeSerializeFMUstate(const bridge_functions* f, fmi2Component c, const fmi2Byte serializedState[], size_t size, fmi2FMUstate* FMUstate)
+{
+    return f->fmi2DeSerializeFMUstate(c, serializedState, size, FMUstate);
+}
+
+fmi2Status bridge_fmi2GetDirectionalDerivative(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vUnknown_ref[], size_t nUnknown,
+    const fmi2ValueReference vKnown_ref[], size_t nKnown, const fmi2Real dvKnown[], fmi2Real dvUnknown[])
+{
+    return f->fmi2GetDirectionalDerivative(c, vUnknown_ref, nUnknown, vKnown_ref, nKnown, dvKnown, dvUnknown);
+}
+
+fmi2Status bridge_fmi2EnterEventMode(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2EnterEventMode(c);
+}
+
+fmi2Status bridge_fmi2NewDiscreteStates(const bridge_functions* f, fmi2Component c, fmi2EventInfo* eventInfo)
+{
+    return f->fmi2NewDiscreteStates(c, eventInfo);
+}
+
+fmi2Status bridge_fmi2EnterContinuousTimeMode(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2EnterContinuousTimeMode(c);
+}
+
+fmi2Status bridge_fmi2CompletedIntegratorStep(const bridge_functions* f, fmi2Component c, fmi2Boolean noSetFMUStatePriorToCurrentPoint,
+    fmi2Boolean* enterEventMode, fmi2Boolean* terminateSimulation)
+{
+    return f->fmi2CompletedIntegratorStep(c, noSetFMUStatePriorToCurrentPoint, enterEventMode, terminateSimulation);
+}
+
+fmi2Status bridge_fmi2SetTime(const bridge_functions* f, fmi2Component c, fmi2Real time)
+{
+    return f->fmi2SetTime(c, time);
+}
+
+fmi2Status bridge_fmi2SetContinuousStates(const bridge_functions* f, fmi2Component c, const fmi2Real x[], size_t nx)
+{
+    return f->fmi2SetContinuousStates(c, x, nx);
+}
+
+fmi2Status bridge_fmi2GetDerivatives(const bridge_functions* f, fmi2Component c, fmi2Real derivatives[], size_t nx)
+{
+    return f->fmi2GetDerivatives(c, derivatives, nx);
+}
+
+fmi2Status bridge_fmi2GetEventIndicators(const bridge_functions* f, fmi2Component c, fmi2Real eventIndicators[], size_t ni)
+{
+    return f->fmi2GetEventIndicators(c, eventIndicators, ni);
+}
+
+fmi2Status bridge_fmi2GetContinuousStates(const bridge_functions* f, fmi2Component c, fmi2Real x[], size_t nx)
+{
+    return f->fmi2GetContinuousStates(c, x, nx);
+}
+
+fmi2Status bridge_fmi2GetNominalsOfContinuousStates(const bridge_functions* f, fmi2Component c, fmi2Real x_nominal[], size_t nx)
+{
+    return f->fmi2GetNominalsOfContinuousStates(c, x_nominal, nx);
+}
+
+fmi2Status bridge_fmi2SetRealInputDerivatives(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr,
+    const fmi2Integer order[], const fmi2Real value[])
+{
+    return f->fmi2SetRealInputDerivatives(c, vr, nvr, order, value);
+}
+
+fmi2Status bridge_fmi2GetRealOutputDerivatives(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr,
+    const fmi2Integer order[], fmi2Real value[])
+{
+    return f->fmi2GetRealOutputDerivatives(c, vr, nvr, order, value);
+}
+
+fmi2Status bridge_fmi2DoStep(const bridge_functions* f, fmi2Component c, fmi2Real currentCommunicationPoint,
+    fmi2Real communicationStepSize, fmi2Boolean noSetFMUStatePriorToCurrentPoint)
+{
+    return f->fmi2DoStep(c, currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint);
+}
+
+fmi2Status bridge_fmi2CancelStep(const bridge_functions* f, fmi2Component c)
+{
+    return f->fmi2CancelStep(c);
+}
+
+fmi2Status bridge_fmi2GetStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Status* value)
+{
+    return f->fmi2GetStatus(c, s, value);
+}
+
+fmi2Status bridge_fmi2GetRealStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Real* value)
+{
+    return f->fmi2GetRealStatus(c, s, value);
+}
+
+fmi2Status bridge_fmi2GetIntegerStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Integer* value)
+{
+    return f->fmi2GetIntegerStatus(c, s, value);
+}
+
+fmi2Status bridge_fmi2GetBooleanStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Boolean* value)
+{
+    return f->fmi2GetBooleanStatus(c, s, value);
+}
+
+fmi2Status bridge_fmi2GetStringStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2String* value)
+{
+    return f->fmi2GetStringStatus(c, s, value);
+}
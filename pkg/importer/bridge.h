@@ -0,0 +1,140 @@
+/*
+    Bridge functions for calling a dynamically loaded FMU from cgo
+*/
+
+#ifndef bridge_h
+#define bridge_h
+
+#include "fmi2FunctionTypes.h"
+
+// X macros listing the functions of each FMU type, used to declare and resolve them
+#define BRIDGE_COMMON_FUNCTIONS(X) \
+    X(fmi2GetTypesPlatform) \
+    X(fmi2GetVersion) \
+    X(fmi2SetDebugLogging) \
+    X(fmi2Instantiate) \
+    X(fmi2FreeInstance) \
+    X(fmi2SetupExperiment) \
+    X(fmi2EnterInitializationMode) \
+    X(fmi2ExitInitializationMode) \
+    X(fmi2Terminate) \
+    X(fmi2Reset) \
+    X(fmi2GetReal) \
+    X(fmi2GetInteger) \
+    X(fmi2GetBoolean) \
+    X(fmi2GetString) \
+    X(fmi2SetReal) \
+    X(fmi2SetInteger) \
+    X(fmi2SetBoolean) \
+    X(fmi2SetString) \
+    X(fmi2GetFMUstate) \
+    X(fmi2SetFMUstate) \
+    X(fmi2FreeFMUstate) \
+    X(fmi2SerializedFMUstateSize) \
+    X(fmi2SerializeFMUstate) \
+    X(fmi2DeSerializeFMUstate) \
+    X(fmi2GetDirectionalDerivative)
+
+#define BRIDGE_MODEL_EXCHANGE_FUNCTIONS(X) \
+    X(fmi2EnterEventMode) \
+    X(fmi2NewDiscreteStates) \
+    X(fmi2EnterContinuousTimeMode) \
+    X(fmi2CompletedIntegratorStep) \
+    X(fmi2SetTime) \
+    X(fmi2SetContinuousStates) \
+    X(fmi2GetDerivatives) \
+    X(fmi2GetEventIndicators) \
+    X(fmi2GetContinuousStates) \
+    X(fmi2GetNominalsOfContinuousStates)
+
+#define BRIDGE_CO_SIMULATION_FUNCTIONS(X) \
+    X(fmi2SetRealInputDerivatives) \
+    X(fmi2GetRealOutputDerivatives) \
+    X(fmi2DoStep) \
+    X(fmi2CancelStep) \
+    X(fmi2GetStatus) \
+    X(fmi2GetRealStatus) \
+    X(fmi2GetIntegerStatus) \
+    X(fmi2GetBooleanStatus) \
+    X(fmi2GetStringStatus)
+
+#define BRIDGE_FIELD(name) name##TYPE* name;
+
+// bridge_functions holds every fmi2 function resolved from the shared library, NULL if missing
+typedef struct {
+    BRIDGE_COMMON_FUNCTIONS(BRIDGE_FIELD)
+    BRIDGE_MODEL_EXCHANGE_FUNCTIONS(BRIDGE_FIELD)
+    BRIDGE_CO_SIMULATION_FUNCTIONS(BRIDGE_FIELD)
+} bridge_functions;
+
+// bridge_load resolves all functions from a dlopen handle
+void bridge_load(void* handle, bridge_functions* f);
+
+// bridge_missing returns the name of the first function missing for fmuType, or NULL if all are present.
+// fmuType -1 only checks common functions.
+const char* bridge_missing(const bridge_functions* f, int fmuType);
+
+// bridge_callbacks allocates callback functions that forward to Go for the component environment
+fmi2CallbackFunctions* bridge_callbacks(fmi2ComponentEnvironment env, int stepFinished);
+
+// bridge_allocations returns the number of blocks allocated with allocateMemory that have not been freed
+long bridge_allocations(void);
+
+const char* bridge_fmi2GetTypesPlatform(const bridge_functions* f);
+const char* bridge_fmi2GetVersion(const bridge_functions* f);
+fmi2Status bridge_fmi2SetDebugLogging(const bridge_functions* f, fmi2Component c, fmi2Boolean loggingOn, size_t nCategories, const fmi2String categories[]);
+fmi2Component bridge_fmi2Instantiate(const bridge_functions* f, fmi2String instanceName, fmi2Type fmuType, fmi2String fmuGUID,
+    fmi2String fmuResourceLocation, const fmi2CallbackFunctions* functions, fmi2Boolean visible, fmi2Boolean loggingOn);
+void bridge_fmi2FreeInstance(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2SetupExperiment(const bridge_functions* f, fmi2Component c, fmi2Boolean toleranceDefined, fmi2Real tolerance,
+    fmi2Real startTime, fmi2Boolean stopTimeDefined, fmi2Real stopTime);
+fmi2Status bridge_fmi2EnterInitializationMode(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2ExitInitializationMode(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2Terminate(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2Reset(const bridge_functions* f, fmi2Component c);
+
+fmi2Status bridge_fmi2GetReal(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Real value[]);
+fmi2Status bridge_fmi2GetInteger(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Integer value[]);
+fmi2Status bridge_fmi2GetBoolean(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2Boolean value[]);
+fmi2Status bridge_fmi2GetString(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, fmi2String value[]);
+fmi2Status bridge_fmi2SetReal(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Real value[]);
+fmi2Status bridge_fmi2SetInteger(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Integer value[]);
+fmi2Status bridge_fmi2SetBoolean(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2Boolean value[]);
+fmi2Status bridge_fmi2SetString(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr, const fmi2String value[]);
+
+fmi2Status bridge_fmi2GetFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate* FMUstate);
+fmi2Status bridge_fmi2SetFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate);
+fmi2Status bridge_fmi2FreeFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate* FMUstate);
+fmi2Status bridge_fmi2SerializedFMUstateSize(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate, size_t* size);
+fmi2Status bridge_fmi2SerializeFMUstate(const bridge_functions* f, fmi2Component c, fmi2FMUstate FMUstate, fmi2Byte serializedState[], size_t size);
+fmi2Status bridge_fmi2DeSerializeFMUstate(const bridge_functions* f, fmi2Component c, const fmi2Byte serializedState[], size_t size, fmi2FMUstate* FMUstate);
+
+fmi2Status bridge_fmi2GetDirectionalDerivative(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vUnknown_ref[], size_t nUnknown,
+    const fmi2ValueReference vKnown_ref[], size_t nKnown, const fmi2Real dvKnown[], fmi2Real dvUnknown[]);
+
+fmi2Status bridge_fmi2EnterEventMode(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2NewDiscreteStates(const bridge_functions* f, fmi2Component c, fmi2EventInfo* eventInfo);
+fmi2Status bridge_fmi2EnterContinuousTimeMode(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2CompletedIntegratorStep(const bridge_functions* f, fmi2Component c, fmi2Boolean noSetFMUStatePriorToCurrentPoint,
+    fmi2Boolean* enterEventMode, fmi2Boolean* terminateSimulation);
+fmi2Status bridge_fmi2SetTime(const bridge_functions* f, fmi2Component c, fmi2Real time);
+fmi2Status bridge_fmi2SetContinuousStates(const bridge_functions* f, fmi2Component c, const fmi2Real x[], size_t nx);
+fmi2Status bridge_fmi2GetDerivatives(const bridge_functions* f, fmi2Component c, fmi2Real derivatives[], size_t nx);
+fmi2Status bridge_fmi2GetEventIndicators(const bridge_functions* f, fmi2Component c, fmi2Real eventIndicators[], size_t ni);
+fmi2Status bridge_fmi2GetContinuousStates(const bridge_functions* f, fmi2Component c, fmi2Real x[], size_t nx);
+fmi2Status bridge_fmi2GetNominalsOfContinuousStates(const bridge_functions* f, fmi2Component c, fmi2Real x_nominal[], size_t nx);
+
+fmi2Status bridge_fmi2SetRealInputDerivatives(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr,
+    const fmi2Integer order[], const fmi2Real value[]);
+fmi2Status bridge_fmi2GetRealOutputDerivatives(const bridge_functions* f, fmi2Component c, const fmi2ValueReference vr[], size_t nvr,
+    const fmi2Integer order[], fmi2Real value[]);
+fmi2Status bridge_fmi2DoStep(const bridge_functions* f, fmi2Component c, fmi2Real currentCommunicationPoint,
+    fmi2Real communicationStepSize, fmi2Boolean noSetFMUStatePriorToCurrentPoint);
+fmi2Status bridge_fmi2CancelStep(const bridge_functions* f, fmi2Component c);
+fmi2Status bridge_fmi2GetStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Status* value);
+fmi2Status bridge_fmi2GetRealStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Real* value);
+fmi2Status bridge_fmi2GetIntegerStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Integer* value);
+fmi2Status bridge_fmi2GetBooleanStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2Boolean* value);
+fmi2Status bridge_fmi2GetStringStatus(const bridge_functions* f, fmi2Component c, fmi2StatusKind s, fmi2String* value);
+
+#endif  /* bridge_h */
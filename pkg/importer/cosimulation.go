@@ -0,0 +1,106 @@
+package importer
+
+// #include "bridge.h"
+import "C"
+import (
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// SetRealInputDerivatives calls fmi2SetRealInputDerivatives
+func (i *Instance) SetRealInputDerivatives(vr fmi.ValueReference, order []int32, values []float64) error {
+	if err := checkLength(vr, len(order)); err != nil {
+		return err
+	}
+	if err := checkLength(vr, len(values)); err != nil {
+		return err
+	}
+	vrs := cValueReferences(vr)
+	orders := cIntegers(order)
+	return statusError("fmi2SetRealInputDerivatives",
+		C.bridge_fmi2SetRealInputDerivatives(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)),
+			integersPtr(orders), realsPtr(values)))
+}
+
+// GetRealOutputDerivatives calls fmi2GetRealOutputDerivatives
+func (i *Instance) GetRealOutputDerivatives(vr fmi.ValueReference, order []int32) ([]float64, error) {
+	if err := checkLength(vr, len(order)); err != nil {
+		return nil, err
+	}
+	values := make([]float64, len(vr))
+	vrs := cValueReferences(vr)
+	orders := cIntegers(order)
+	s := C.bridge_fmi2GetRealOutputDerivatives(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)),
+		integersPtr(orders), realsPtr(values))
+	if err := statusError("fmi2GetRealOutputDerivatives", s); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+/*
+DoStep calls fmi2DoStep. A StatusError with fmi2Discard is returned if the slave only computed part of the step,
+and fmi2Pending if the step runs asynchronously and Callbacks.StepFinished will be called.
+*/
+func (i *Instance) DoStep(currentCommunicationPoint, communicationStepSize float64, noSetFMUStatePriorToCurrentPoint bool) error {
+	return statusError("fmi2DoStep",
+		C.bridge_fmi2DoStep(i.fns(), i.component, C.fmi2Real(currentCommunicationPoint),
+			C.fmi2Real(communicationStepSize), boolFMU(noSetFMUStatePriorToCurrentPoint)))
+}
+
+// CancelStep calls fmi2CancelStep
+func (i *Instance) CancelStep() error {
+	return statusError("fmi2CancelStep", C.bridge_fmi2CancelStep(i.fns(), i.component))
+}
+
+// GetStatus calls fmi2GetStatus
+func (i *Instance) GetStatus(kind fmi.StatusKind) (fmi.Status, error) {
+	var value C.fmi2Status
+	if err := statusError("fmi2GetStatus", C.bridge_fmi2GetStatus(i.fns(), i.component, C.fmi2StatusKind(kind), &value)); err != nil {
+		return 0, err
+	}
+	return fmi.Status(value), nil
+}
+
+// GetRealStatus calls fmi2GetRealStatus
+func (i *Instance) GetRealStatus(kind fmi.StatusKind) (float64, error) {
+	var value C.fmi2Real
+	if err := statusError("fmi2GetRealStatus", C.bridge_fmi2GetRealStatus(i.fns(), i.component, C.fmi2StatusKind(kind), &value)); err != nil {
+		return 0, err
+	}
+	return float64(value), nil
+}
+
+// GetIntegerStatus calls fmi2GetIntegerStatus
+func (i *Instance) GetIntegerStatus(kind fmi.StatusKind) (int32, error) {
+	var value C.fmi2Integer
+	if err := statusError("fmi2GetIntegerStatus", C.bridge_fmi2GetIntegerStatus(i.fns(), i.component, C.fmi2StatusKind(kind), &value)); err != nil {
+		return 0, err
+	}
+	return int32(value), nil
+}
+
+// GetBooleanStatus calls fmi2GetBooleanStatus
+func (i *Instance) GetBooleanStatus(kind fmi.StatusKind) (bool, error) {
+	var value C.fmi2Boolean
+	if err := statusError("fmi2GetBooleanStatus", C.bridge_fmi2GetBooleanStatus(i.fns(), i.component, C.fmi2StatusKind(kind), &value)); err != nil {
+		return false, err
+	}
+	return fmuBool(value), nil
+}
+
+// GetStringStatus calls fmi2GetStringStatus
+func (i *Instance) GetStringStatus(kind fmi.StatusKind) (string, error) {
+	var value C.fmi2String
+	if err := statusError("fmi2GetStringStatus", C.bridge_fmi2GetStringStatus(i.fns(), i.component, C.fmi2StatusKind(kind), &value)); err != nil {
+		return "", err
+	}
+	return C.GoString(value), nil
+}
+
+func cIntegers(is []int32) []C.fmi2Integer {
+	cs := make([]C.fmi2Integer, len(is))
+	for j, v := range is {
+		cs[j] = C.fmi2Integer(v)
+	}
+	return cs
+}
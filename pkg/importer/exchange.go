@@ -0,0 +1,92 @@
+package importer
+
+// #include "bridge.h"
+import "C"
+import (
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// EnterEventMode calls fmi2EnterEventMode
+func (i *Instance) EnterEventMode() error {
+	return statusError("fmi2EnterEventMode", C.bridge_fmi2EnterEventMode(i.fns(), i.component))
+}
+
+// NewDiscreteStates calls fmi2NewDiscreteStates
+func (i *Instance) NewDiscreteStates() (fmi.EventInfo, error) {
+	var info C.fmi2EventInfo
+	if err := statusError("fmi2NewDiscreteStates", C.bridge_fmi2NewDiscreteStates(i.fns(), i.component, &info)); err != nil {
+		return fmi.EventInfo{}, err
+	}
+	return fmi.EventInfo{
+		NewDiscreteStatesNeeded:           fmuBool(info.newDiscreteStatesNeeded),
+		TerminateSimulation:               fmuBool(info.terminateSimulation),
+		NominalsOfContinuousStatesChanged: fmuBool(info.nominalsOfContinuousStatesChanged),
+		ValuesOfContinuousStatesChanged:   fmuBool(info.valuesOfContinuousStatesChanged),
+		NextEventTimeDefined:              fmuBool(info.nextEventTimeDefined),
+		NextEventTime:                     float64(info.nextEventTime),
+	}, nil
+}
+
+// EnterContinuousTimeMode calls fmi2EnterContinuousTimeMode
+func (i *Instance) EnterContinuousTimeMode() error {
+	return statusError("fmi2EnterContinuousTimeMode", C.bridge_fmi2EnterContinuousTimeMode(i.fns(), i.component))
+}
+
+// CompletedIntegratorStep calls fmi2CompletedIntegratorStep
+func (i *Instance) CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint bool) (enterEventMode, terminateSimulation bool, err error) {
+	var e, t C.fmi2Boolean
+	err = statusError("fmi2CompletedIntegratorStep",
+		C.bridge_fmi2CompletedIntegratorStep(i.fns(), i.component, boolFMU(noSetFMUStatePriorToCurrentPoint), &e, &t))
+	return fmuBool(e), fmuBool(t), err
+}
+
+// SetTime calls fmi2SetTime
+func (i *Instance) SetTime(time float64) error {
+	return statusError("fmi2SetTime", C.bridge_fmi2SetTime(i.fns(), i.component, C.fmi2Real(time)))
+}
+
+// SetContinuousStates calls fmi2SetContinuousStates
+func (i *Instance) SetContinuousStates(x []float64) error {
+	return statusError("fmi2SetContinuousStates",
+		C.bridge_fmi2SetContinuousStates(i.fns(), i.component, realsPtr(x), C.size_t(len(x))))
+}
+
+// GetDerivatives calls fmi2GetDerivatives for nx continuous states
+func (i *Instance) GetDerivatives(nx int) ([]float64, error) {
+	dx := make([]float64, nx)
+	if err := statusError("fmi2GetDerivatives",
+		C.bridge_fmi2GetDerivatives(i.fns(), i.component, realsPtr(dx), C.size_t(nx))); err != nil {
+		return nil, err
+	}
+	return dx, nil
+}
+
+// GetEventIndicators calls fmi2GetEventIndicators for ni event indicators
+func (i *Instance) GetEventIndicators(ni int) ([]float64, error) {
+	z := make([]float64, ni)
+	if err := statusError("fmi2GetEventIndicators",
+		C.bridge_fmi2GetEventIndicators(i.fns(), i.component, realsPtr(z), C.size_t(ni))); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+// GetContinuousStates calls fmi2GetContinuousStates for nx continuous states
+func (i *Instance) GetContinuousStates(nx int) ([]float64, error) {
+	x := make([]float64, nx)
+	if err := statusError("fmi2GetContinuousStates",
+		C.bridge_fmi2GetContinuousStates(i.fns(), i.component, realsPtr(x), C.size_t(nx))); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// GetNominalsOfContinuousStates calls fmi2GetNominalsOfContinuousStates for nx continuous states
+func (i *Instance) GetNominalsOfContinuousStates(nx int) ([]float64, error) {
+	x := make([]float64, nx)
+	if err := statusError("fmi2GetNominalsOfContinuousStates",
+		C.bridge_fmi2GetNominalsOfContinuousStates(i.fns(), i.component, realsPtr(x), C.size_t(nx))); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
@@ -0,0 +1,196 @@
+/*
+Package importer loads FMI 2.0 FMU shared libraries built by any tool and calls them from Go.
+
+A Library is opened with dlopen, so only POSIX platforms are supported. Every fmi2 function is
+resolved when the library is loaded. The common functions are required. Model exchange and
+co-simulation functions are only required when an instance of that type is created.
+
+	path, err := importer.BinaryPath(dir, "BouncingBall")
+	...
+	lib, err := importer.Load(path)
+	...
+	inst, err := lib.Instantiate("ball", fmi.FMUTypeCoSimulation, guid, resources, importer.Callbacks{}, false, false)
+	...
+	defer inst.FreeInstance()
+*/
+package importer
+
+// #cgo CFLAGS: -I${SRCDIR}/../fmi/c
+// #cgo LDFLAGS: -ldl
+// #include <dlfcn.h>
+// #include <stdlib.h>
+// #include "bridge.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+var platforms = map[string]string{
+	"linux/amd64":   "linux64",
+	"linux/386":     "linux32",
+	"windows/amd64": "win64",
+	"windows/386":   "win32",
+	"darwin/amd64":  "darwin64",
+}
+
+var extensions = map[string]string{
+	"linux":   ".so",
+	"windows": ".dll",
+	"darwin":  ".dylib",
+}
+
+// Library is a loaded FMU shared library
+type Library struct {
+	path   string
+	handle unsafe.Pointer
+	// fns is allocated in C memory so it can be passed to every bridge call
+	fns *C.bridge_functions
+
+	mu        sync.Mutex
+	instances int
+}
+
+// StatusError is returned when an FMU function returns a status other than fmi2OK or fmi2Warning
+type StatusError struct {
+	// Function is the name of the fmi2 function that failed
+	Function string
+	// Status is the status returned by the function
+	Status fmi.Status
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %s", e.Function, e.Status)
+}
+
+// StatusOf returns the status of err, fmi2OK for a nil error and fmi2Error for errors that are not a StatusError
+func StatusOf(err error) fmi.Status {
+	if err == nil {
+		return fmi.StatusOK
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Status
+	}
+	return fmi.StatusError
+}
+
+// Allocations returns the number of blocks FMUs allocated with the allocateMemory callback and have not freed.
+// It is shared by all instances, an FMU that frees its memory returns to the count before it was instantiated.
+func Allocations() int {
+	return int(C.bridge_allocations())
+}
+
+// Platform returns the FMI binaries directory name for the running platform
+func Platform() (string, error) {
+	p, ok := platforms[runtime.GOOS+"/"+runtime.GOARCH]
+	if !ok {
+		return "", fmt.Errorf("Platform %s/%s is not supported by FMI 2.0", runtime.GOOS, runtime.GOARCH)
+	}
+	return p, nil
+}
+
+// BinaryPath returns the path of the shared library for modelIdentifier in an extracted FMU directory
+func BinaryPath(dir, modelIdentifier string) (string, error) {
+	p, err := Platform()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "binaries", p, modelIdentifier+extensions[runtime.GOOS]), nil
+}
+
+// Load opens the FMU shared library at path and resolves its fmi2 functions
+func Load(path string) (*Library, error) {
+	p := C.CString(path)
+	defer C.free(unsafe.Pointer(p))
+
+	handle := C.dlopen(p, C.RTLD_NOW|C.RTLD_LOCAL)
+	if handle == nil {
+		return nil, fmt.Errorf("Error loading %s: %s", path, C.GoString(C.dlerror()))
+	}
+
+	fns := (*C.bridge_functions)(C.calloc(1, C.sizeof_bridge_functions))
+	C.bridge_load(handle, fns)
+	if missing := C.bridge_missing(fns, -1); missing != nil {
+		C.free(unsafe.Pointer(fns))
+		C.dlclose(handle)
+		return nil, fmt.Errorf("Error loading %s: function %s is missing", path, C.GoString(missing))
+	}
+	return &Library{
+		path:   path,
+		handle: handle,
+		fns:    fns,
+	}, nil
+}
+
+// Close unloads the library. All instances must be freed first.
+func (l *Library) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.instances > 0 {
+		return fmt.Errorf("Cannot close %s with %d instances", l.path, l.instances)
+	}
+	if l.handle == nil {
+		return nil
+	}
+	C.free(unsafe.Pointer(l.fns))
+	l.fns = nil
+	handle := l.handle
+	l.handle = nil
+	if C.dlclose(handle) != 0 {
+		return fmt.Errorf("Error closing %s: %s", l.path, C.GoString(C.dlerror()))
+	}
+	return nil
+}
+
+// Path is the file the library was loaded from
+func (l *Library) Path() string {
+	return l.path
+}
+
+// Version returns fmi2GetVersion of the library
+func (l *Library) Version() string {
+	return C.GoString(C.bridge_fmi2GetVersion(l.fns))
+}
+
+// TypesPlatform returns fmi2GetTypesPlatform of the library
+func (l *Library) TypesPlatform() string {
+	return C.GoString(C.bridge_fmi2GetTypesPlatform(l.fns))
+}
+
+// supports checks that the library provides all functions for fmuType
+func (l *Library) supports(fmuType fmi.FMUType) error {
+	if missing := C.bridge_missing(l.fns, C.int(fmuType)); missing != nil {
+		return fmt.Errorf("%s does not support FMU type %d: function %s is missing", l.path, fmuType, C.GoString(missing))
+	}
+	return nil
+}
+
+// statusError returns nil for fmi2OK and fmi2Warning, otherwise a StatusError for function
+func statusError(function string, status C.fmi2Status) error {
+	s := fmi.Status(status)
+	if s == fmi.StatusOK || s == fmi.StatusWarning {
+		return nil
+	}
+	return &StatusError{
+		Function: function,
+		Status:   s,
+	}
+}
+
+func fmuBool(b C.fmi2Boolean) bool {
+	return b != C.fmi2False
+}
+
+func boolFMU(b bool) C.fmi2Boolean {
+	if b {
+		return C.fmi2True
+	}
+	return C.fmi2False
+}
@@ -0,0 +1,456 @@
+package importer_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+const (
+	vrH = 1
+	vrV = 3
+)
+
+var (
+	// bouncingBall is the BouncingBall example built as a shared library in TestMain
+	bouncingBall string
+	guid         string
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "importer")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	bouncingBall, err = importer.BinaryPath(dir, "BouncingBall")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	cmd := exec.Command("go", "build", "-buildmode", "c-shared", "-o", bouncingBall, "../../examples/BouncingBall")
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error building BouncingBall:", err)
+		return 1
+	}
+
+	f, err := os.Open("../fmi/testdata/BouncingBall.xml")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	desc, err := fmi.ParseModelDescription(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	guid = desc.GUID
+
+	return m.Run()
+}
+
+type logMessage struct {
+	status   fmi.Status
+	category string
+	message  string
+}
+
+// messages collects messages from the logger callback
+type messages struct {
+	mu   sync.Mutex
+	logs []logMessage
+}
+
+func (m *messages) logger(status fmi.Status, category, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs = append(m.logs, logMessage{status, category, message})
+}
+
+func loadBouncingBall(t testing.TB) *importer.Library {
+	lib, err := importer.Load(bouncingBall)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := lib.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return lib
+}
+
+func instantiate(t testing.TB, lib *importer.Library, fmuType fmi.FMUType, callbacks importer.Callbacks) *importer.Instance {
+	inst, err := lib.Instantiate("ball", fmuType, guid, "", callbacks, false, false)
+	if err != nil {
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	t.Cleanup(inst.FreeInstance)
+	return inst
+}
+
+func initialize(t testing.TB, inst *importer.Instance) {
+	if err := inst.SetupExperiment(false, 0, 0, true, 3); err != nil {
+		t.Fatalf("SetupExperiment() error = %v", err)
+	}
+	if err := inst.EnterInitializationMode(); err != nil {
+		t.Fatalf("EnterInitializationMode() error = %v", err)
+	}
+	if err := inst.ExitInitializationMode(); err != nil {
+		t.Fatalf("ExitInitializationMode() error = %v", err)
+	}
+}
+
+func TestBinaryPath(t *testing.T) {
+	p, err := importer.Platform()
+	if err != nil {
+		t.Skip(err)
+	}
+	got, err := importer.BinaryPath("fmu", "Model")
+	if err != nil {
+		t.Fatalf("BinaryPath() error = %v", err)
+	}
+	want := filepath.Join("fmu", "binaries", p, "Model")
+	if !strings.HasPrefix(got, want+".") {
+		t.Errorf("BinaryPath() = %s, want %s with extension", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := importer.Load(filepath.Join(t.TempDir(), "missing.so")); err == nil {
+		t.Errorf("Load() expected error for missing library")
+	}
+
+	lib := loadBouncingBall(t)
+	if v := lib.Version(); v != "2.0" {
+		t.Errorf("Version() = %s, want 2.0", v)
+	}
+	if p := lib.TypesPlatform(); p != "default" {
+		t.Errorf("TypesPlatform() = %s, want default", p)
+	}
+}
+
+func TestLibrary_Instantiate(t *testing.T) {
+	lib := loadBouncingBall(t)
+	msgs := &messages{}
+	_, err := lib.Instantiate("ball", fmi.FMUTypeCoSimulation, "wrong", "", importer.Callbacks{Logger: msgs.logger}, false, false)
+	if err == nil {
+		t.Fatalf("Instantiate() expected error for wrong GUID")
+	}
+	if len(msgs.logs) != 1 {
+		t.Fatalf("Expected one log message, got %v", msgs.logs)
+	}
+	got := msgs.logs[0]
+	if got.status != fmi.StatusError || got.category != "logStatusError" ||
+		got.message != "GUID wrong does not match any registered model" {
+		t.Errorf("Unexpected log message %+v", got)
+	}
+
+	inst := instantiate(t, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+	if err := lib.Close(); err == nil {
+		t.Errorf("Close() expected error with active instance")
+	}
+	inst.FreeInstance()
+}
+
+func TestInstance_CoSimulation(t *testing.T) {
+	lib := loadBouncingBall(t)
+	inst := instantiate(t, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+
+	err := inst.DoStep(0, 0.1, true)
+	if s := importer.StatusOf(err); s != fmi.StatusError {
+		t.Errorf("DoStep() before initialization status = %s, want fmi2Error", s)
+	}
+	var se *importer.StatusError
+	if !errors.As(err, &se) || se.Function != "fmi2DoStep" {
+		t.Errorf("DoStep() error = %v, want StatusError", err)
+	}
+
+	initialize(t, inst)
+	vr := fmi.ValueReference{vrH, vrV}
+	if err := inst.SetReal(vr, []float64{2, 0}); err != nil {
+		t.Fatalf("SetReal() error = %v", err)
+	}
+
+	step := 0.1
+	for i := 0; i < 5; i++ {
+		if err := inst.DoStep(float64(i)*step, step, true); err != nil {
+			t.Fatalf("DoStep() error = %v", err)
+		}
+	}
+	got, err := inst.GetReal(vr)
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	if got[0] >= 2 || got[1] >= 0 {
+		t.Errorf("GetReal() = %v, expected ball to be falling", got)
+	}
+
+	time, err := inst.GetRealStatus(fmi.StatusKindLastSuccessfulTime)
+	if err != nil {
+		t.Fatalf("GetRealStatus() error = %v", err)
+	}
+	if time != 0.5 {
+		t.Errorf("GetRealStatus() = %f, want 0.5", time)
+	}
+
+	if err := inst.Terminate(); err != nil {
+		t.Errorf("Terminate() error = %v", err)
+	}
+
+	if _, err := inst.GetInteger(fmi.ValueReference{vrH}); importer.StatusOf(err) != fmi.StatusError {
+		t.Errorf("GetInteger() error = %v, want fmi2Error", err)
+	}
+}
+
+func TestInstance_FMUState(t *testing.T) {
+	lib := loadBouncingBall(t)
+	inst := instantiate(t, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+	initialize(t, inst)
+
+	vr := fmi.ValueReference{vrH}
+	state, err := inst.GetFMUstate()
+	if err != nil {
+		t.Fatalf("GetFMUstate() error = %v", err)
+	}
+	bs, err := inst.SerializeFMUstate(state)
+	if err != nil {
+		t.Fatalf("SerializeFMUstate() error = %v", err)
+	}
+	if err := inst.FreeFMUstate(state); err != nil {
+		t.Fatalf("FreeFMUstate() error = %v", err)
+	}
+
+	if err := inst.SetReal(vr, []float64{5}); err != nil {
+		t.Fatalf("SetReal() error = %v", err)
+	}
+
+	corrupt := append([]byte{}, bs...)
+	corrupt[len(corrupt)/2] ^= 1
+	if _, err := inst.DeSerializeFMUstate(corrupt); importer.StatusOf(err) != fmi.StatusError {
+		t.Errorf("DeSerializeFMUstate() of corrupt state error = %v, want fmi2Error", err)
+	}
+
+	state, err = inst.DeSerializeFMUstate(bs)
+	if err != nil {
+		t.Fatalf("DeSerializeFMUstate() error = %v", err)
+	}
+	defer inst.FreeFMUstate(state)
+	if err := inst.SetFMUstate(state); err != nil {
+		t.Fatalf("SetFMUstate() error = %v", err)
+	}
+	got, err := inst.GetReal(vr)
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	if got[0] != 1 {
+		t.Errorf("GetReal() = %v, want restored height 1", got)
+	}
+}
+
+func TestInstance_Memory(t *testing.T) {
+	lib := loadBouncingBall(t)
+	before := importer.Allocations()
+	checkAllocations := func(when string, want int) {
+		t.Helper()
+		if got := importer.Allocations() - before; got != want {
+			t.Errorf("Allocations() after %s = %d, want %d", when, got, want)
+		}
+	}
+
+	inst, err := lib.Instantiate("ball", fmi.FMUTypeCoSimulation, guid, "", importer.Callbacks{}, false, false)
+	if err != nil {
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	checkAllocations("Instantiate", 1)
+	initialize(t, inst)
+
+	state, err := inst.GetFMUstate()
+	if err != nil {
+		t.Fatalf("GetFMUstate() error = %v", err)
+	}
+	checkAllocations("GetFMUstate", 2)
+	bs, err := inst.SerializeFMUstate(state)
+	if err != nil {
+		t.Fatalf("SerializeFMUstate() error = %v", err)
+	}
+	copied, err := inst.DeSerializeFMUstate(bs)
+	if err != nil {
+		t.Fatalf("DeSerializeFMUstate() error = %v", err)
+	}
+	checkAllocations("DeSerializeFMUstate", 3)
+	for _, s := range []*importer.FMUState{state, copied} {
+		if err := inst.FreeFMUstate(s); err != nil {
+			t.Fatalf("FreeFMUstate() error = %v", err)
+		}
+	}
+	checkAllocations("FreeFMUstate", 1)
+
+	inst.FreeInstance()
+	checkAllocations("FreeInstance", 0)
+}
+
+func TestInstance_UpdateFMUstate(t *testing.T) {
+	lib := loadBouncingBall(t)
+	inst := instantiate(t, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+	initialize(t, inst)
+	other := instantiate(t, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+	initialize(t, other)
+
+	vr := fmi.ValueReference{vrH}
+	state, err := inst.GetFMUstate()
+	if err != nil {
+		t.Fatalf("GetFMUstate() error = %v", err)
+	}
+	allocations := importer.Allocations()
+	for i := 0; i < 100; i++ {
+		if err := inst.DoStep(float64(i)*0.01, 0.01, false); err != nil {
+			t.Fatalf("DoStep() error = %v", err)
+		}
+		if err := inst.UpdateFMUstate(state); err != nil {
+			t.Fatalf("UpdateFMUstate() error = %v", err)
+		}
+	}
+	if got := importer.Allocations(); got != allocations {
+		t.Errorf("Allocations() after UpdateFMUstate = %d, want %d", got, allocations)
+	}
+	want, err := inst.GetReal(vr)
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	if err := inst.SetReal(vr, []float64{5}); err != nil {
+		t.Fatalf("SetReal() error = %v", err)
+	}
+	if err := inst.SetFMUstate(state); err != nil {
+		t.Fatalf("SetFMUstate() error = %v", err)
+	}
+	if got, err := inst.GetReal(vr); err != nil || got[0] != want[0] {
+		t.Errorf("GetReal() = %v, %v, want updated height %v", got, err, want)
+	}
+
+	if err := other.SetFMUstate(state); importer.StatusOf(err) != fmi.StatusError {
+		t.Errorf("SetFMUstate() of another instance error = %v, want fmi2Error", err)
+	}
+	if err := other.UpdateFMUstate(state); importer.StatusOf(err) != fmi.StatusError {
+		t.Errorf("UpdateFMUstate() of another instance error = %v, want fmi2Error", err)
+	}
+
+	freed := *state
+	if err := inst.FreeFMUstate(state); err != nil {
+		t.Fatalf("FreeFMUstate() error = %v", err)
+	}
+	if err := inst.FreeFMUstate(&freed); importer.StatusOf(err) != fmi.StatusError {
+		t.Errorf("FreeFMUstate() of freed state error = %v, want fmi2Error", err)
+	}
+}
+
+func BenchmarkInstance_FMUState(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		// update reuses the state instead of freeing it and getting a new one
+		update bool
+	}{
+		{
+			"GetFMUstate and FreeFMUstate",
+			false,
+		},
+		{
+			"UpdateFMUstate",
+			true,
+		},
+	}
+	lib := loadBouncingBall(b)
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			inst := instantiate(b, lib, fmi.FMUTypeCoSimulation, importer.Callbacks{})
+			initialize(b, inst)
+			state, err := inst.GetFMUstate()
+			if err != nil {
+				b.Fatalf("GetFMUstate() error = %v", err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if bm.update {
+					err = inst.UpdateFMUstate(state)
+				} else {
+					if err = inst.FreeFMUstate(state); err == nil {
+						state, err = inst.GetFMUstate()
+					}
+				}
+				if err != nil {
+					b.Fatalf("Get state error = %v", err)
+				}
+				if err := inst.SetFMUstate(state); err != nil {
+					b.Fatalf("SetFMUstate() error = %v", err)
+				}
+			}
+			b.StopTimer()
+			if err := inst.FreeFMUstate(state); err != nil {
+				b.Fatalf("FreeFMUstate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestInstance_ModelExchange(t *testing.T) {
+	lib := loadBouncingBall(t)
+	inst := instantiate(t, lib, fmi.FMUTypeModelExchange, importer.Callbacks{})
+	initialize(t, inst)
+
+	info, err := inst.NewDiscreteStates()
+	if err != nil {
+		t.Fatalf("NewDiscreteStates() error = %v", err)
+	}
+	if info.TerminateSimulation {
+		t.Errorf("NewDiscreteStates() = %+v, did not expect termination", info)
+	}
+	if err := inst.EnterContinuousTimeMode(); err != nil {
+		t.Fatalf("EnterContinuousTimeMode() error = %v", err)
+	}
+	if err := inst.SetTime(0.1); err != nil {
+		t.Fatalf("SetTime() error = %v", err)
+	}
+	x, err := inst.GetContinuousStates(2)
+	if err != nil {
+		t.Fatalf("GetContinuousStates() error = %v", err)
+	}
+	if x[0] != 1 || x[1] != 0 {
+		t.Errorf("GetContinuousStates() = %v, want [1 0]", x)
+	}
+	dx, err := inst.GetDerivatives(2)
+	if err != nil {
+		t.Fatalf("GetDerivatives() error = %v", err)
+	}
+	if dx[0] != 0 || dx[1] != -9.81 {
+		t.Errorf("GetDerivatives() = %v, want [0 -9.81]", dx)
+	}
+	z, err := inst.GetEventIndicators(1)
+	if err != nil {
+		t.Fatalf("GetEventIndicators() error = %v", err)
+	}
+	if len(z) != 1 {
+		t.Errorf("GetEventIndicators() = %v, want 1 indicator", z)
+	}
+	enterEventMode, terminate, err := inst.CompletedIntegratorStep(true)
+	if err != nil || enterEventMode || terminate {
+		t.Errorf("CompletedIntegratorStep() = %v, %v, %v", enterEventMode, terminate, err)
+	}
+}
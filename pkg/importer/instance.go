@@ -0,0 +1,437 @@
+package importer
+
+// #include <stdlib.h>
+// #include "bridge.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+var (
+	// instances maps the componentEnvironment passed to callbacks to the instance
+	instances   = map[uintptr]*Instance{}
+	instancesMu sync.RWMutex
+)
+
+// Callbacks are the Go functions called by the FMU through fmi2CallbackFunctions
+type Callbacks struct {
+	// Logger receives messages logged by the FMU, messages are dropped if nil
+	Logger fmi.LoggerCallback
+	// StepFinished is called when an asynchronous fmi2DoStep completes.
+	// If nil the FMU must compute fmi2DoStep synchronously.
+	StepFinished fmi.StepFinishedCallback
+}
+
+// Instance is an instantiated FMU
+type Instance struct {
+	Name string
+	Type fmi.FMUType
+
+	lib       *Library
+	component C.fmi2Component
+	callbacks Callbacks
+	// env is passed to callbacks as componentEnvironment to find the instance
+	env       unsafe.Pointer
+	functions *C.fmi2CallbackFunctions
+}
+
+// FMUState is a copy of the internal FMU state created by GetFMUstate or DeSerializeFMUstate
+type FMUState struct {
+	state C.fmi2FMUstate
+}
+
+//export importerLogger
+func importerLogger(env unsafe.Pointer, _ *C.char, status C.fmi2Status, category, message *C.char) {
+	instancesMu.RLock()
+	inst, ok := instances[uintptr(env)]
+	instancesMu.RUnlock()
+	if !ok || inst.callbacks.Logger == nil {
+		return
+	}
+	inst.callbacks.Logger(fmi.Status(status), C.GoString(category), C.GoString(message))
+}
+
+//export importerStepFinished
+func importerStepFinished(env unsafe.Pointer, status C.fmi2Status) {
+	instancesMu.RLock()
+	inst, ok := instances[uintptr(env)]
+	instancesMu.RUnlock()
+	if !ok || inst.callbacks.StepFinished == nil {
+		return
+	}
+	inst.callbacks.StepFinished(fmi.Status(status))
+}
+
+/*
+Instantiate calls fmi2Instantiate to create a new instance of the FMU.
+resourceLocation is the file URI of the resources directory of the extracted FMU.
+The callbacks are wired through fmi2CallbackFunctions and memory is allocated with calloc and free,
+counted by Allocations.
+*/
+func (l *Library) Instantiate(instanceName string, fmuType fmi.FMUType, guid, resourceLocation string,
+	callbacks Callbacks, visible, loggingOn bool) (*Instance, error) {
+	if err := l.supports(fmuType); err != nil {
+		return nil, err
+	}
+
+	env := C.malloc(1)
+	functions := C.bridge_callbacks(C.fmi2ComponentEnvironment(env), boolInt(callbacks.StepFinished != nil))
+	if functions == nil {
+		C.free(env)
+		return nil, errors.New("Error allocating callback functions")
+	}
+	inst := &Instance{
+		Name:      instanceName,
+		Type:      fmuType,
+		lib:       l,
+		callbacks: callbacks,
+		env:       env,
+		functions: functions,
+	}
+	// register before instantiating so messages logged by fmi2Instantiate are received
+	instancesMu.Lock()
+	instances[uintptr(env)] = inst
+	instancesMu.Unlock()
+
+	name := C.CString(instanceName)
+	g := C.CString(guid)
+	r := C.CString(resourceLocation)
+	defer C.free(unsafe.Pointer(name))
+	defer C.free(unsafe.Pointer(g))
+	defer C.free(unsafe.Pointer(r))
+
+	inst.component = C.bridge_fmi2Instantiate(l.fns, name, C.fmi2Type(fmuType), g, r, functions, boolFMU(visible), boolFMU(loggingOn))
+	if inst.component == nil {
+		inst.release()
+		return nil, fmt.Errorf("fmi2Instantiate failed for instance %s", instanceName)
+	}
+
+	l.mu.Lock()
+	l.instances++
+	l.mu.Unlock()
+	return inst, nil
+}
+
+// FreeInstance calls fmi2FreeInstance and releases the callbacks. The instance cannot be used afterwards.
+func (i *Instance) FreeInstance() {
+	if i.component == nil {
+		return
+	}
+	C.bridge_fmi2FreeInstance(i.fns(), i.component)
+	i.component = nil
+	i.release()
+
+	i.lib.mu.Lock()
+	i.lib.instances--
+	i.lib.mu.Unlock()
+}
+
+// release unregisters the instance and frees callback memory
+func (i *Instance) release() {
+	instancesMu.Lock()
+	delete(instances, uintptr(i.env))
+	instancesMu.Unlock()
+	C.free(unsafe.Pointer(i.functions))
+	C.free(i.env)
+	i.functions = nil
+	i.env = nil
+}
+
+func (i *Instance) fns() *C.bridge_functions {
+	return i.lib.fns
+}
+
+// SetDebugLogging calls fmi2SetDebugLogging
+func (i *Instance) SetDebugLogging(loggingOn bool, categories []string) error {
+	cs, free := cStrings(categories)
+	defer free()
+	return statusError("fmi2SetDebugLogging",
+		C.bridge_fmi2SetDebugLogging(i.fns(), i.component, boolFMU(loggingOn), C.size_t(len(categories)), cs))
+}
+
+// SetupExperiment calls fmi2SetupExperiment
+func (i *Instance) SetupExperiment(toleranceDefined bool, tolerance float64,
+	startTime float64, stopTimeDefined bool, stopTime float64) error {
+	return statusError("fmi2SetupExperiment",
+		C.bridge_fmi2SetupExperiment(i.fns(), i.component, boolFMU(toleranceDefined), C.fmi2Real(tolerance),
+			C.fmi2Real(startTime), boolFMU(stopTimeDefined), C.fmi2Real(stopTime)))
+}
+
+// EnterInitializationMode calls fmi2EnterInitializationMode
+func (i *Instance) EnterInitializationMode() error {
+	return statusError("fmi2EnterInitializationMode", C.bridge_fmi2EnterInitializationMode(i.fns(), i.component))
+}
+
+// ExitInitializationMode calls fmi2ExitInitializationMode
+func (i *Instance) ExitInitializationMode() error {
+	return statusError("fmi2ExitInitializationMode", C.bridge_fmi2ExitInitializationMode(i.fns(), i.component))
+}
+
+// Terminate calls fmi2Terminate
+func (i *Instance) Terminate() error {
+	return statusError("fmi2Terminate", C.bridge_fmi2Terminate(i.fns(), i.component))
+}
+
+// Reset calls fmi2Reset
+func (i *Instance) Reset() error {
+	return statusError("fmi2Reset", C.bridge_fmi2Reset(i.fns(), i.component))
+}
+
+// GetReal calls fmi2GetReal
+func (i *Instance) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	values := make([]float64, len(vr))
+	vrs := cValueReferences(vr)
+	s := C.bridge_fmi2GetReal(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), realsPtr(values))
+	if err := statusError("fmi2GetReal", s); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// GetInteger calls fmi2GetInteger
+func (i *Instance) GetInteger(vr fmi.ValueReference) ([]int32, error) {
+	values := make([]C.fmi2Integer, len(vr))
+	vrs := cValueReferences(vr)
+	s := C.bridge_fmi2GetInteger(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), integersPtr(values))
+	if err := statusError("fmi2GetInteger", s); err != nil {
+		return nil, err
+	}
+	is := make([]int32, len(values))
+	for j, v := range values {
+		is[j] = int32(v)
+	}
+	return is, nil
+}
+
+// GetBoolean calls fmi2GetBoolean
+func (i *Instance) GetBoolean(vr fmi.ValueReference) ([]bool, error) {
+	values := make([]C.fmi2Boolean, len(vr))
+	vrs := cValueReferences(vr)
+	s := C.bridge_fmi2GetBoolean(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), booleansPtr(values))
+	if err := statusError("fmi2GetBoolean", s); err != nil {
+		return nil, err
+	}
+	bs := make([]bool, len(values))
+	for j, v := range values {
+		bs[j] = fmuBool(v)
+	}
+	return bs, nil
+}
+
+// GetString calls fmi2GetString. The strings are copied before returning.
+func (i *Instance) GetString(vr fmi.ValueReference) ([]string, error) {
+	values := make([]C.fmi2String, len(vr))
+	vrs := cValueReferences(vr)
+	var p *C.fmi2String
+	if len(values) > 0 {
+		p = &values[0]
+	}
+	s := C.bridge_fmi2GetString(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), p)
+	if err := statusError("fmi2GetString", s); err != nil {
+		return nil, err
+	}
+	ss := make([]string, len(values))
+	for j, v := range values {
+		ss[j] = C.GoString(v)
+	}
+	return ss, nil
+}
+
+// SetReal calls fmi2SetReal
+func (i *Instance) SetReal(vr fmi.ValueReference, values []float64) error {
+	if err := checkLength(vr, len(values)); err != nil {
+		return err
+	}
+	vrs := cValueReferences(vr)
+	return statusError("fmi2SetReal",
+		C.bridge_fmi2SetReal(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), realsPtr(values)))
+}
+
+// SetInteger calls fmi2SetInteger
+func (i *Instance) SetInteger(vr fmi.ValueReference, values []int32) error {
+	if err := checkLength(vr, len(values)); err != nil {
+		return err
+	}
+	is := make([]C.fmi2Integer, len(values))
+	for j, v := range values {
+		is[j] = C.fmi2Integer(v)
+	}
+	vrs := cValueReferences(vr)
+	return statusError("fmi2SetInteger",
+		C.bridge_fmi2SetInteger(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), integersPtr(is)))
+}
+
+// SetBoolean calls fmi2SetBoolean
+func (i *Instance) SetBoolean(vr fmi.ValueReference, values []bool) error {
+	if err := checkLength(vr, len(values)); err != nil {
+		return err
+	}
+	bs := make([]C.fmi2Boolean, len(values))
+	for j, v := range values {
+		bs[j] = boolFMU(v)
+	}
+	vrs := cValueReferences(vr)
+	return statusError("fmi2SetBoolean",
+		C.bridge_fmi2SetBoolean(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), booleansPtr(bs)))
+}
+
+// SetString calls fmi2SetString
+func (i *Instance) SetString(vr fmi.ValueReference, values []string) error {
+	if err := checkLength(vr, len(values)); err != nil {
+		return err
+	}
+	ss, free := cStrings(values)
+	defer free()
+	vrs := cValueReferences(vr)
+	return statusError("fmi2SetString",
+		C.bridge_fmi2SetString(i.fns(), i.component, valueReferencesPtr(vrs), C.size_t(len(vr)), ss))
+}
+
+// GetFMUstate calls fmi2GetFMUstate to copy the current FMU state. The state must be freed with FreeFMUstate.
+func (i *Instance) GetFMUstate() (*FMUState, error) {
+	state := &FMUState{}
+	if err := statusError("fmi2GetFMUstate", C.bridge_fmi2GetFMUstate(i.fns(), i.component, &state.state)); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+/*
+UpdateFMUstate calls fmi2GetFMUstate to copy the current FMU state into a state previously returned by the instance.
+The FMU may reuse the memory of the state, which still has to be freed with FreeFMUstate.
+*/
+func (i *Instance) UpdateFMUstate(state *FMUState) error {
+	return statusError("fmi2GetFMUstate", C.bridge_fmi2GetFMUstate(i.fns(), i.component, &state.state))
+}
+
+// SetFMUstate calls fmi2SetFMUstate to restore a state copied from this instance
+func (i *Instance) SetFMUstate(state *FMUState) error {
+	return statusError("fmi2SetFMUstate", C.bridge_fmi2SetFMUstate(i.fns(), i.component, state.state))
+}
+
+// FreeFMUstate calls fmi2FreeFMUstate
+func (i *Instance) FreeFMUstate(state *FMUState) error {
+	return statusError("fmi2FreeFMUstate", C.bridge_fmi2FreeFMUstate(i.fns(), i.component, &state.state))
+}
+
+// SerializeFMUstate calls fmi2SerializedFMUstateSize and fmi2SerializeFMUstate
+func (i *Instance) SerializeFMUstate(state *FMUState) ([]byte, error) {
+	var size C.size_t
+	if err := statusError("fmi2SerializedFMUstateSize",
+		C.bridge_fmi2SerializedFMUstateSize(i.fns(), i.component, state.state, &size)); err != nil {
+		return nil, err
+	}
+	bs := make([]byte, int(size))
+	var p *C.fmi2Byte
+	if size > 0 {
+		p = (*C.fmi2Byte)(unsafe.Pointer(&bs[0]))
+	}
+	if err := statusError("fmi2SerializeFMUstate",
+		C.bridge_fmi2SerializeFMUstate(i.fns(), i.component, state.state, p, size)); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// DeSerializeFMUstate calls fmi2DeSerializeFMUstate. The state must be freed with FreeFMUstate.
+func (i *Instance) DeSerializeFMUstate(bs []byte) (*FMUState, error) {
+	var p *C.fmi2Byte
+	if len(bs) > 0 {
+		p = (*C.fmi2Byte)(unsafe.Pointer(&bs[0]))
+	}
+	state := &FMUState{}
+	if err := statusError("fmi2DeSerializeFMUstate",
+		C.bridge_fmi2DeSerializeFMUstate(i.fns(), i.component, p, C.size_t(len(bs)), &state.state)); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// GetDirectionalDerivative calls fmi2GetDirectionalDerivative
+func (i *Instance) GetDirectionalDerivative(vUnknown, vKnown fmi.ValueReference, dvKnown []float64) ([]float64, error) {
+	if err := checkLength(vKnown, len(dvKnown)); err != nil {
+		return nil, err
+	}
+	dvUnknown := make([]float64, len(vUnknown))
+	unknowns := cValueReferences(vUnknown)
+	knowns := cValueReferences(vKnown)
+	s := C.bridge_fmi2GetDirectionalDerivative(i.fns(), i.component,
+		valueReferencesPtr(unknowns), C.size_t(len(vUnknown)),
+		valueReferencesPtr(knowns), C.size_t(len(vKnown)),
+		realsPtr(dvKnown), realsPtr(dvUnknown))
+	if err := statusError("fmi2GetDirectionalDerivative", s); err != nil {
+		return nil, err
+	}
+	return dvUnknown, nil
+}
+
+func checkLength(vr fmi.ValueReference, n int) error {
+	if len(vr) != n {
+		return fmt.Errorf("Expected %d values for %d value references", n, len(vr))
+	}
+	return nil
+}
+
+func cValueReferences(vr fmi.ValueReference) []C.fmi2ValueReference {
+	vrs := make([]C.fmi2ValueReference, len(vr))
+	for j, v := range vr {
+		vrs[j] = C.fmi2ValueReference(v)
+	}
+	return vrs
+}
+
+func valueReferencesPtr(vrs []C.fmi2ValueReference) *C.fmi2ValueReference {
+	if len(vrs) == 0 {
+		return nil
+	}
+	return &vrs[0]
+}
+
+func realsPtr(fs []float64) *C.fmi2Real {
+	if len(fs) == 0 {
+		return nil
+	}
+	return (*C.fmi2Real)(unsafe.Pointer(&fs[0]))
+}
+
+func integersPtr(is []C.fmi2Integer) *C.fmi2Integer {
+	if len(is) == 0 {
+		return nil
+	}
+	return &is[0]
+}
+
+func booleansPtr(bs []C.fmi2Boolean) *C.fmi2Boolean {
+	if len(bs) == 0 {
+		return nil
+	}
+	return &bs[0]
+}
+
+// cStrings converts ss to an array of C strings, free must be called to release them
+func cStrings(ss []string) (*C.fmi2String, func()) {
+	if len(ss) == 0 {
+		return nil, func() {}
+	}
+	cs := make([]C.fmi2String, len(ss))
+	for j, s := range ss {
+		cs[j] = C.CString(s)
+	}
+	return &cs[0], func() {
+		for _, c := range cs {
+			C.free(unsafe.Pointer(c))
+		}
+	}
+}
+
+func boolInt(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,266 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+const (
+	// safety scales the optimal step size estimated from the error
+	safety = 0.9
+	// minScale and maxScale limit the change of step size after a macro step
+	minScale = 0.2
+	maxScale = 5
+)
+
+// StateInstance is an Instance that can save and restore its state, as provided by importer.Instance
+type StateInstance interface {
+	Instance
+	GetFMUstate() (*importer.FMUState, error)
+	SetFMUstate(*importer.FMUState) error
+	FreeFMUstate(*importer.FMUState) error
+}
+
+// AdaptiveOptions configures the step size control of an Adaptive master
+type AdaptiveOptions struct {
+	// Schedule is used for every macro step
+	Schedule Schedule
+	// InitialStepSize is the first communication step size tried, and the fixed step size when slaves cannot roll back
+	InitialStepSize float64
+	// MinStepSize is the smallest step size before the simulation fails, defaults to InitialStepSize * 1e-6
+	MinStepSize float64
+	// MaxStepSize is the largest step size, defaults to no limit
+	MaxStepSize float64
+	// RelativeTolerance and AbsoluteTolerance bound the estimated error of connected Real outputs in a macro step
+	RelativeTolerance float64
+	AbsoluteTolerance float64
+}
+
+/*
+Adaptive is a master that controls the communication step size.
+
+The state of every slave is saved before each macro step. The coupling error is estimated by comparing the
+connected Real outputs after one full step with the outputs after two half steps. If a slave returns fmi2Discard
+or the error is above tolerance, all slaves are rolled back and the step is retried with a smaller step size.
+
+Rolling back needs every slave to support canGetAndSetFMUstate. Otherwise the master steps at InitialStepSize
+without error control.
+*/
+type Adaptive struct {
+	*Master
+
+	options  AdaptiveOptions
+	states   []StateInstance
+	stepSize float64
+}
+
+// NewAdaptive creates an adaptive master for slaves coupled with connections
+func NewAdaptive(slaves []Slave, connections []Connection, options AdaptiveOptions) (*Adaptive, error) {
+	if options.InitialStepSize <= 0 {
+		return nil, fmt.Errorf("Initial step size must be positive, got %g", options.InitialStepSize)
+	}
+	if options.MinStepSize == 0 {
+		options.MinStepSize = options.InitialStepSize * 1e-6
+	}
+	if options.MaxStepSize == 0 {
+		options.MaxStepSize = math.Inf(1)
+	}
+	if options.MinStepSize > options.InitialStepSize || options.InitialStepSize > options.MaxStepSize {
+		return nil, errors.New("Step sizes must satisfy MinStepSize <= InitialStepSize <= MaxStepSize")
+	}
+	if options.RelativeTolerance < 0 || options.AbsoluteTolerance < 0 ||
+		options.RelativeTolerance == 0 && options.AbsoluteTolerance == 0 {
+		return nil, errors.New("Tolerances cannot be negative and one must be positive")
+	}
+
+	m, err := New(slaves, connections, options.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	a := &Adaptive{
+		Master:   m,
+		options:  options,
+		stepSize: options.InitialStepSize,
+	}
+	for _, s := range slaves {
+		si, ok := s.Instance.(StateInstance)
+		if !ok || s.Description.CoSimulation == nil || !s.Description.CoSimulation.CanGetAndSetFMUstate {
+			a.states = nil
+			break
+		}
+		a.states = append(a.states, si)
+	}
+	m.rollback = a.states != nil
+	return a, nil
+}
+
+// Rollback returns true if all slaves can be rolled back and the step size is controlled
+func (a *Adaptive) Rollback() bool {
+	return a.states != nil
+}
+
+// StepSize is the step size that will be tried in the next macro step
+func (a *Adaptive) StepSize() float64 {
+	return a.stepSize
+}
+
+/*
+Step performs one accepted macro step of at most maxStepSize and returns the step size taken.
+The step size is reduced until a step succeeds within tolerance or MinStepSize is reached.
+*/
+func (a *Adaptive) Step(maxStepSize float64) (float64, error) {
+	if maxStepSize <= 0 {
+		return 0, fmt.Errorf("Maximum step size must be positive, got %g", maxStepSize)
+	}
+	if !a.Rollback() {
+		h := math.Min(a.stepSize, maxStepSize)
+		return h, a.advance(h)
+	}
+
+	snapshot, err := a.snapshot()
+	if err != nil {
+		return 0, err
+	}
+	defer a.free(snapshot)
+
+	start := a.time
+	for {
+		h := math.Min(a.stepSize, maxStepSize)
+		e, err := a.trial(snapshot, start, h)
+		discard := importer.StatusOf(err) == fmi.StatusDiscard
+		if err != nil && !discard {
+			return 0, err
+		}
+		if !discard && e <= 1 {
+			// a step shortened to maxStepSize says nothing about larger steps
+			if h == a.stepSize {
+				a.stepSize = math.Min(h*scale(e), a.options.MaxStepSize)
+			}
+			return h, nil
+		}
+
+		if err := a.restore(snapshot, start); err != nil {
+			return 0, err
+		}
+		if discard {
+			a.stepSize = h / 2
+		} else {
+			a.stepSize = h * scale(e)
+		}
+		if a.stepSize < a.options.MinStepSize {
+			return 0, fmt.Errorf("Step size %g at time %g is below minimum step size %g", a.stepSize, start, a.options.MinStepSize)
+		}
+	}
+}
+
+// Run steps until stopTime with adaptive step sizes
+func (a *Adaptive) Run(stopTime float64) error {
+	epsilon := a.options.MinStepSize * 1e-3
+	for a.time < stopTime-epsilon {
+		if _, err := a.Step(stopTime - a.time); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+trial does one full step and two half steps of h from the snapshot and returns the scaled error
+of connected Real outputs. The slaves are left after the two half steps.
+*/
+func (a *Adaptive) trial(snapshot []*importer.FMUState, start, h float64) (float64, error) {
+	if err := a.advance(h); err != nil {
+		return 0, err
+	}
+	full, err := a.coupling()
+	if err != nil {
+		return 0, err
+	}
+	if err := a.restore(snapshot, start); err != nil {
+		return 0, err
+	}
+	for i := 0; i < 2; i++ {
+		if err := a.advance(h / 2); err != nil {
+			return 0, err
+		}
+	}
+	half, err := a.coupling()
+	if err != nil {
+		return 0, err
+	}
+
+	e := 0.0
+	for i := range full {
+		tolerance := a.options.AbsoluteTolerance + a.options.RelativeTolerance*math.Abs(half[i])
+		e = math.Max(e, math.Abs(full[i]-half[i])/tolerance)
+	}
+	return e, nil
+}
+
+// coupling returns the values of connected Real outputs
+func (a *Adaptive) coupling() ([]float64, error) {
+	var values []float64
+	for _, c := range a.connections {
+		if c.from.typ != fmi.VariableTypeReal {
+			continue
+		}
+		vs, err := a.slaves[c.from.slave].Instance.GetReal(c.from.vr)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting %s.%s: %w", c.FromSlave, c.FromVariable, err)
+		}
+		values = append(values, vs[0])
+	}
+	return values, nil
+}
+
+// snapshot gets the state of every slave
+func (a *Adaptive) snapshot() ([]*importer.FMUState, error) {
+	states := make([]*importer.FMUState, len(a.states))
+	for i, s := range a.states {
+		st, err := s.GetFMUstate()
+		if err != nil {
+			a.free(states[:i])
+			return nil, fmt.Errorf("Error getting state of slave %s: %w", a.slaves[i].Name, err)
+		}
+		states[i] = st
+	}
+	return states, nil
+}
+
+// restore sets the state of every slave and the master time
+func (a *Adaptive) restore(states []*importer.FMUState, time float64) error {
+	for i, s := range a.states {
+		if err := s.SetFMUstate(states[i]); err != nil {
+			return fmt.Errorf("Error restoring state of slave %s: %w", a.slaves[i].Name, err)
+		}
+	}
+	a.time = time
+	return nil
+}
+
+// free releases saved states
+func (a *Adaptive) free(states []*importer.FMUState) error {
+	var errs []string
+	for i, st := range states {
+		if err := a.states[i].FreeFMUstate(st); err != nil {
+			errs = append(errs, fmt.Sprintf("slave %s: %v", a.slaves[i].Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error freeing states of %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// scale returns the step size factor for a scaled error e, assuming a local coupling error of order h^2
+func scale(e float64) float64 {
+	if e == 0 {
+		return maxScale
+	}
+	return math.Max(minScale, math.Min(maxScale, safety/math.Sqrt(e)))
+}
@@ -0,0 +1,265 @@
+package master_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+	"github.com/tanenbaum/go-fmi/pkg/master"
+)
+
+// stateBlock is a block that can save and restore its variables
+type stateBlock struct {
+	*block
+	// maxStep is the largest step size before DoStep returns fmi2Discard, 0 for no limit
+	maxStep float64
+	states  map[*importer.FMUState][]float64
+	gets    int
+	// noSet is noSetFMUStatePriorToCurrentPoint of the last DoStep
+	noSet bool
+}
+
+func (b *stateBlock) DoStep(t, h float64, noSetFMUStatePriorToCurrentPoint bool) error {
+	b.noSet = noSetFMUStatePriorToCurrentPoint
+	if b.maxStep > 0 && h > b.maxStep {
+		return &importer.StatusError{Function: "fmi2DoStep", Status: fmi.StatusDiscard}
+	}
+	return b.block.DoStep(t, h, noSetFMUStatePriorToCurrentPoint)
+}
+
+func (b *stateBlock) GetFMUstate() (*importer.FMUState, error) {
+	b.gets++
+	s := &importer.FMUState{}
+	b.states[s] = append([]float64(nil), b.reals...)
+	return s, nil
+}
+
+func (b *stateBlock) SetFMUstate(s *importer.FMUState) error {
+	rs, ok := b.states[s]
+	if !ok {
+		return errors.New("Unknown state")
+	}
+	copy(b.reals, rs)
+	return nil
+}
+
+func (b *stateBlock) FreeFMUstate(s *importer.FMUState) error {
+	if _, ok := b.states[s]; !ok {
+		return errors.New("Unknown state")
+	}
+	delete(b.states, s)
+	return nil
+}
+
+// stateful makes slave s support canGetAndSetFMUstate
+func stateful(s master.Slave, maxStep float64) (master.Slave, *stateBlock) {
+	b := &stateBlock{
+		block:   s.Instance.(*block),
+		maxStep: maxStep,
+		states:  map[*importer.FMUState][]float64{},
+	}
+	s.Instance = b
+	s.Description.CoSimulation = &fmi.CoSimulation{FMUShared: fmi.FMUShared{CanGetAndSetFMUstate: true}}
+	return s, b
+}
+
+func TestNewAdaptive_Errors(t *testing.T) {
+	calls := &[]string{}
+	slaves := []master.Slave{integrator("a", 0, calls)}
+	tests := []struct {
+		name    string
+		options master.AdaptiveOptions
+	}{
+		{"No initial step size", master.AdaptiveOptions{RelativeTolerance: 1e-3}},
+		{"Minimum above initial", master.AdaptiveOptions{InitialStepSize: 0.1, MinStepSize: 0.2, RelativeTolerance: 1e-3}},
+		{"Maximum below initial", master.AdaptiveOptions{InitialStepSize: 0.1, MaxStepSize: 0.05, RelativeTolerance: 1e-3}},
+		{"No tolerance", master.AdaptiveOptions{InitialStepSize: 0.1}},
+		{"Negative tolerance", master.AdaptiveOptions{InitialStepSize: 0.1, RelativeTolerance: 1e-3, AbsoluteTolerance: -1}},
+		{"Unknown schedule", master.AdaptiveOptions{Schedule: 5, InitialStepSize: 0.1, RelativeTolerance: 1e-3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := master.NewAdaptive(slaves, nil, tt.options); err == nil {
+				t.Errorf("NewAdaptive() expected error")
+			}
+		})
+	}
+}
+
+func TestAdaptive_Step_Discard(t *testing.T) {
+	calls := &[]string{}
+	x, b := stateful(integrator("x", 0, calls), 0.1)
+	a, err := master.NewAdaptive([]master.Slave{x}, nil, master.AdaptiveOptions{
+		InitialStepSize:   0.4,
+		RelativeTolerance: 1e-3,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive() error = %v", err)
+	}
+	if !a.Rollback() {
+		t.Fatalf("Rollback() = false, want true")
+	}
+	if err := a.SetReal("x", "u", 1); err != nil {
+		t.Fatalf("SetReal() error = %v", err)
+	}
+	if err := a.Initialize(0, 1); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	h, err := a.Step(1)
+	if err != nil {
+		t.Fatalf("Step() error = %v", err)
+	}
+	if h != 0.1 {
+		t.Errorf("Step() = %g, want 0.1 after discarded steps", h)
+	}
+	got, err := a.GetReal("x", "y")
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	// x is only correct if discarded steps were rolled back
+	if math.Abs(got-0.1) > 1e-12 {
+		t.Errorf("x = %g, want 0.1", got)
+	}
+	if a.Time() != 0.1 {
+		t.Errorf("Time() = %g, want 0.1", a.Time())
+	}
+	if len(b.states) != 0 {
+		t.Errorf("Expected all states to be freed, got %d", len(b.states))
+	}
+	if b.noSet {
+		t.Errorf("DoStep() noSetFMUStatePriorToCurrentPoint = true, want false")
+	}
+
+	b.maxStep = 1e-9
+	if _, err := a.Step(1); err == nil {
+		t.Errorf("Step() expected error below minimum step size")
+	}
+}
+
+func TestAdaptive_Run(t *testing.T) {
+	// a' = b, b' = -a from a = 1, b = 0 is a = cos(t), b = -sin(t)
+	run := func(t *testing.T, tolerance float64) (float64, int) {
+		calls := &[]string{}
+		a, _ := stateful(integrator("a", 1, calls), 0)
+		b, _ := stateful(integrator("b", 0, calls), 0)
+		k, _ := stateful(gain("k", -1, calls), 0)
+		m, err := master.NewAdaptive(
+			[]master.Slave{a, b, k},
+			[]master.Connection{connect("b", "a"), connect("a", "k"), connect("k", "b")},
+			master.AdaptiveOptions{
+				Schedule:          master.ScheduleGaussSeidel,
+				InitialStepSize:   0.1,
+				RelativeTolerance: tolerance,
+				AbsoluteTolerance: tolerance,
+			},
+		)
+		if err != nil {
+			t.Fatalf("NewAdaptive() error = %v", err)
+		}
+		if err := m.Initialize(0, 1); err != nil {
+			t.Fatalf("Initialize() error = %v", err)
+		}
+		steps := 0
+		for m.Time() < 1 {
+			if _, err := m.Step(1 - m.Time()); err != nil {
+				t.Fatalf("Step() error = %v", err)
+			}
+			steps++
+		}
+		if m.Time() != 1 {
+			t.Errorf("Time() = %g, want 1", m.Time())
+		}
+		got, err := m.GetReal("a", "y")
+		if err != nil {
+			t.Fatalf("GetReal() error = %v", err)
+		}
+		return math.Abs(got - math.Cos(1)), steps
+	}
+
+	looseErr, looseSteps := run(t, 1e-2)
+	tightErr, tightSteps := run(t, 1e-4)
+	if tightSteps <= looseSteps {
+		t.Errorf("Expected more steps with tight tolerance, got %d and %d", tightSteps, looseSteps)
+	}
+	if tightErr >= looseErr || tightErr > 1e-2 {
+		t.Errorf("Expected smaller error with tight tolerance, got %g and %g", tightErr, looseErr)
+	}
+}
+
+func TestAdaptive_FixedStep(t *testing.T) {
+	calls := &[]string{}
+	x, b := stateful(integrator("x", 0, calls), 0)
+	// y cannot roll back, so x is stepped at a fixed step size too
+	y := integrator("y", 0, calls)
+	a, err := master.NewAdaptive([]master.Slave{x, y}, nil, master.AdaptiveOptions{
+		InitialStepSize:   0.25,
+		RelativeTolerance: 1e-3,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive() error = %v", err)
+	}
+	if a.Rollback() {
+		t.Fatalf("Rollback() = true, want false")
+	}
+	if err := a.Initialize(0, 1); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		h, err := a.Step(1)
+		if err != nil {
+			t.Fatalf("Step() error = %v", err)
+		}
+		if h != 0.25 {
+			t.Errorf("Step() = %g, want fixed step 0.25", h)
+		}
+	}
+	if a.Time() != 0.5 {
+		t.Errorf("Time() = %g, want 0.5", a.Time())
+	}
+	if !b.noSet {
+		t.Errorf("DoStep() noSetFMUStatePriorToCurrentPoint = false, want true")
+	}
+	if b.gets != 0 {
+		t.Errorf("Expected no GetFMUstate calls, got %d", b.gets)
+	}
+}
+
+func TestAdaptive_FMU(t *testing.T) {
+	if _, err := importer.Platform(); err != nil {
+		t.Skip(err)
+	}
+	ball := loadFMU(t, "ball")
+	a, err := master.NewAdaptive([]master.Slave{ball.Slave}, nil, master.AdaptiveOptions{
+		InitialStepSize:   0.01,
+		MaxStepSize:       0.1,
+		RelativeTolerance: 1e-3,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive() error = %v", err)
+	}
+	if !a.Rollback() {
+		t.Fatalf("Rollback() = false, want true")
+	}
+	if err := a.Initialize(0, 1); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if err := a.Run(0.3); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if math.Abs(a.Time()-0.3) > 1e-9 {
+		t.Errorf("Time() = %g, want 0.3", a.Time())
+	}
+	if a.StepSize() != 0.1 {
+		t.Errorf("StepSize() = %g, want maximum 0.1 without connections", a.StepSize())
+	}
+	h, err := a.GetReal("ball", "h")
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	if h >= 1 {
+		t.Errorf("h = %g, expected ball to fall", h)
+	}
+}
@@ -0,0 +1,81 @@
+package master
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// FMU is a co-simulation slave loaded from a .fmu file
+type FMU struct {
+	Slave
+
+	dir  string
+	lib  *importer.Library
+	inst *importer.Instance
+}
+
+// LoadFMU extracts the .fmu file at path and instantiates it for co-simulation as slave name.
+// Close must be called when the FMU is no longer used.
+func LoadFMU(name, path string, callbacks importer.Callbacks) (*FMU, error) {
+	a, err := importer.OpenArchive(path)
+	if err != nil {
+		return nil, err
+	}
+	defer a.Close()
+
+	md := a.ModelDescription
+	if md.CoSimulation == nil {
+		return nil, fmt.Errorf("FMU %s does not support co-simulation", path)
+	}
+	dir, err := a.ExtractTemp()
+	if err != nil {
+		return nil, err
+	}
+	f := &FMU{dir: dir}
+
+	binary, err := importer.BinaryPath(dir, md.CoSimulation.ModelIdentifier)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	resources, err := importer.ResourceLocation(dir)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if f.lib, err = importer.Load(binary); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if f.inst, err = f.lib.Instantiate(name, fmi.FMUTypeCoSimulation, md.GUID, resources, callbacks, false, false); err != nil {
+		f.Close()
+		return nil, err
+	}
+	f.Slave = Slave{
+		Name:        name,
+		Description: md,
+		Instance:    f.inst,
+	}
+	return f, nil
+}
+
+// Close frees the instance, unloads the library and removes the extracted files
+func (f *FMU) Close() error {
+	if f.inst != nil {
+		f.inst.FreeInstance()
+		f.inst = nil
+	}
+	if f.lib != nil {
+		if err := f.lib.Close(); err != nil {
+			return err
+		}
+		f.lib = nil
+	}
+	if err := os.RemoveAll(f.dir); err != nil {
+		return fmt.Errorf("Error removing FMU directory: %w", err)
+	}
+	return nil
+}
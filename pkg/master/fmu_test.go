@@ -0,0 +1,143 @@
+package master_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+	"github.com/tanenbaum/go-fmi/pkg/master"
+)
+
+// bouncingBall is the BouncingBall example and discard the Discard test model, built as FMUs in TestMain
+var bouncingBall, discard string
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "master")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	bouncingBall = filepath.Join(dir, "BouncingBall.fmu")
+	discard = filepath.Join(dir, "Discard.fmu")
+	for _, f := range []struct{ fmu, pkg string }{
+		{bouncingBall, "../../examples/BouncingBall"},
+		{discard, "./testdata/Discard"},
+	} {
+		cmd := exec.Command("go", "run", "../../cmd/fmubuild", "-o", f.fmu, f.pkg)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error building %s: %v\n", filepath.Base(f.fmu), err)
+			return 1
+		}
+	}
+	return m.Run()
+}
+
+func loadFMU(t *testing.T, name string) *master.FMU {
+	return loadFMUPath(t, name, bouncingBall)
+}
+
+func loadFMUPath(t *testing.T, name, path string) *master.FMU {
+	f, err := master.LoadFMU(name, path, importer.Callbacks{})
+	if err != nil {
+		t.Fatalf("LoadFMU() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if err := f.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return f
+}
+
+func TestLoadFMU(t *testing.T) {
+	if _, err := importer.Platform(); err != nil {
+		t.Skip(err)
+	}
+	if _, err := master.LoadFMU("ball", filepath.Join(t.TempDir(), "missing.fmu"), importer.Callbacks{}); err == nil {
+		t.Errorf("LoadFMU() expected error for missing file")
+	}
+
+	high, low := loadFMU(t, "high"), loadFMU(t, "low")
+	m, err := master.New([]master.Slave{high.Slave, low.Slave}, nil, master.ScheduleGaussSeidel)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := m.SetReal("high", "h", 2); err != nil {
+		t.Fatalf("SetReal() error = %v", err)
+	}
+	if err := m.Initialize(0, 1); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if err := m.Run(0.3, 0.1); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, s := range []struct {
+		name  string
+		start float64
+	}{{"high", 2}, {"low", 1}} {
+		h, err := m.GetReal(s.name, "h")
+		if err != nil {
+			t.Fatalf("GetReal() error = %v", err)
+		}
+		if h >= s.start || h < s.start-0.5 {
+			t.Errorf("%s.h = %g, expected ball to fall from %g", s.name, h, s.start)
+		}
+	}
+	if err := m.Terminate(); err != nil {
+		t.Errorf("Terminate() error = %v", err)
+	}
+}
+
+func TestAdaptive_Step_DiscardFMU(t *testing.T) {
+	if _, err := importer.Platform(); err != nil {
+		t.Skip(err)
+	}
+	x := loadFMUPath(t, "x", discard)
+	a, err := master.NewAdaptive([]master.Slave{x.Slave}, nil, master.AdaptiveOptions{
+		InitialStepSize:   0.4,
+		RelativeTolerance: 1e-3,
+	})
+	if err != nil {
+		t.Fatalf("NewAdaptive() error = %v", err)
+	}
+	if !a.Rollback() {
+		t.Fatalf("Rollback() = false, want true")
+	}
+	if err := a.Initialize(0, 1); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	// the FMU discards steps above 0.1 after integrating part of the step
+	h, err := a.Step(1)
+	if err != nil {
+		t.Fatalf("Step() error = %v", err)
+	}
+	if h != 0.1 {
+		t.Errorf("Step() = %g, want 0.1 after halving discarded steps", h)
+	}
+	y, err := a.GetReal("x", "Y")
+	if err != nil {
+		t.Fatalf("GetReal() error = %v", err)
+	}
+	if math.Abs(y-0.1) > 1e-12 {
+		t.Errorf("Y = %g, want 0.1 with discarded steps rolled back", y)
+	}
+	if _, err := a.Step(1); err != nil {
+		t.Errorf("Step() after rollback error = %v", err)
+	}
+	if err := a.Terminate(); err != nil {
+		t.Errorf("Terminate() error = %v", err)
+	}
+}
@@ -0,0 +1,523 @@
+/*
+Package master couples FMI 2.0 co-simulation slaves and steps them at a fixed communication step.
+
+Outputs are connected to inputs by variable name. The Outputs dependencies in each ModelStructure
+define which outputs depend directly on inputs (direct feedthrough). They are used to order data
+exchange between slaves at every communication point and to reject algebraic loops.
+*/
+package master
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+const (
+	// ScheduleJacobi steps all slaves with inputs from the previous communication point
+	ScheduleJacobi Schedule = iota
+	// ScheduleGaussSeidel steps slaves in dependency order, updating inputs from slaves that have already stepped
+	ScheduleGaussSeidel
+)
+
+// Schedule is the order in which slaves are stepped and inputs are updated
+type Schedule uint
+
+var scheduleNames = [...]string{"Jacobi", "GaussSeidel"}
+
+func (s Schedule) String() string {
+	if int(s) >= len(scheduleNames) {
+		return fmt.Sprintf("Schedule(%d)", s)
+	}
+	return scheduleNames[s]
+}
+
+// Instance is an instantiated co-simulation FMU, as provided by importer.Instance
+type Instance interface {
+	SetupExperiment(toleranceDefined bool, tolerance float64, startTime float64, stopTimeDefined bool, stopTime float64) error
+	EnterInitializationMode() error
+	ExitInitializationMode() error
+	DoStep(currentCommunicationPoint, communicationStepSize float64, noSetFMUStatePriorToCurrentPoint bool) error
+	Terminate() error
+
+	GetReal(fmi.ValueReference) ([]float64, error)
+	GetInteger(fmi.ValueReference) ([]int32, error)
+	GetBoolean(fmi.ValueReference) ([]bool, error)
+	GetString(fmi.ValueReference) ([]string, error)
+	SetReal(fmi.ValueReference, []float64) error
+	SetInteger(fmi.ValueReference, []int32) error
+	SetBoolean(fmi.ValueReference, []bool) error
+	SetString(fmi.ValueReference, []string) error
+}
+
+// Slave is a co-simulation FMU coupled by the master
+type Slave struct {
+	// Name identifies the slave in connections and must be unique
+	Name string
+	// Description is the model description of the FMU, used to find variables by name
+	Description fmi.ModelDescription
+	// Instance is the instantiated FMU
+	Instance Instance
+}
+
+// Connection connects an output variable of one slave to an input variable of another
+type Connection struct {
+	FromSlave    string
+	FromVariable string
+	ToSlave      string
+	ToVariable   string
+}
+
+func (c Connection) String() string {
+	return fmt.Sprintf("%s.%s -> %s.%s", c.FromSlave, c.FromVariable, c.ToSlave, c.ToVariable)
+}
+
+// AlgebraicLoopError is returned when connections and direct feedthrough form a cycle
+type AlgebraicLoopError struct {
+	// Connections are the connections that cannot be ordered
+	Connections []Connection
+}
+
+func (e *AlgebraicLoopError) Error() string {
+	cs := make([]string, len(e.Connections))
+	for i, c := range e.Connections {
+		cs[i] = c.String()
+	}
+	return "Algebraic loop between connections " + strings.Join(cs, ", ")
+}
+
+// port is a variable of a slave
+type port struct {
+	slave int
+	// index is the ScalarVariable index, starting at 1
+	index uint
+	vr    fmi.ValueReference
+	typ   fmi.VariableType
+}
+
+type connection struct {
+	Connection
+	from, to port
+}
+
+// Master steps coupled slaves
+type Master struct {
+	slaves      []Slave
+	schedule    Schedule
+	connections []connection
+	// order is the slave order for Gauss-Seidel
+	order []int
+	// inputs lists the ordered connections into each slave
+	inputs [][]connection
+	time   float64
+	// rollback is set when slave states may be restored to before the current communication point
+	rollback bool
+}
+
+// New creates a master for slaves coupled with connections.
+// An AlgebraicLoopError is returned if the connections cannot be ordered because of direct feedthrough.
+func New(slaves []Slave, connections []Connection, schedule Schedule) (*Master, error) {
+	if schedule != ScheduleJacobi && schedule != ScheduleGaussSeidel {
+		return nil, fmt.Errorf("Unknown schedule %d", schedule)
+	}
+	m := &Master{
+		slaves:   slaves,
+		schedule: schedule,
+		inputs:   make([][]connection, len(slaves)),
+	}
+
+	names := map[string]int{}
+	for i, s := range slaves {
+		if s.Name == "" {
+			return nil, errors.New("Slave name cannot be empty")
+		}
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("Slave name %s is not unique", s.Name)
+		}
+		if s.Instance == nil {
+			return nil, fmt.Errorf("Slave %s has no instance", s.Name)
+		}
+		names[s.Name] = i
+	}
+
+	cs := make([]connection, len(connections))
+	// connected records inputs by slave and variable index
+	connected := map[[2]uint]bool{}
+	for i, c := range connections {
+		from, err := m.port(names, c.FromSlave, c.FromVariable, fmi.VariableCausalityOutput)
+		if err != nil {
+			return nil, fmt.Errorf("Connection %s: %w", c, err)
+		}
+		to, err := m.port(names, c.ToSlave, c.ToVariable, fmi.VariableCausalityInput)
+		if err != nil {
+			return nil, fmt.Errorf("Connection %s: %w", c, err)
+		}
+		if baseType(from.typ) != baseType(to.typ) {
+			return nil, fmt.Errorf("Connection %s: variable types do not match", c)
+		}
+		key := [2]uint{uint(to.slave), to.index}
+		if connected[key] {
+			return nil, fmt.Errorf("Connection %s: input is already connected", c)
+		}
+		connected[key] = true
+		cs[i] = connection{c, from, to}
+	}
+
+	ordered, err := m.orderConnections(cs)
+	if err != nil {
+		return nil, err
+	}
+	m.connections = ordered
+	for _, c := range ordered {
+		m.inputs[c.to.slave] = append(m.inputs[c.to.slave], c)
+	}
+	m.order = orderSlaves(len(slaves), ordered)
+	return m, nil
+}
+
+// port finds variable in the named slave and checks its causality
+func (m *Master) port(names map[string]int, slave, variable string, causality fmi.VariableCausality) (port, error) {
+	i, ok := names[slave]
+	if !ok {
+		return port{}, fmt.Errorf("Slave %s does not exist", slave)
+	}
+	for j, sv := range m.slaves[i].Description.ModelVariables {
+		if sv.Name != variable {
+			continue
+		}
+		if sv.Causality == nil || *sv.Causality != causality {
+			return port{}, fmt.Errorf("Variable %s of slave %s must have causality %s", variable, slave, causalityName(causality))
+		}
+		if sv.ScalarVariableType == nil {
+			return port{}, fmt.Errorf("Variable %s of slave %s has no type", variable, slave)
+		}
+		return port{
+			slave: i,
+			index: uint(j + 1),
+			vr:    fmi.ValueReference{sv.ValueReference},
+			typ:   sv.Type(),
+		}, nil
+	}
+	return port{}, fmt.Errorf("Slave %s has no variable %s", slave, variable)
+}
+
+/*
+orderConnections sorts connections so that a connection is transferred after every connection
+into an input its source output depends on. Connections that cannot be sorted form an algebraic loop.
+*/
+func (m *Master) orderConnections(cs []connection) ([]connection, error) {
+	// after[i] lists connections that must be transferred after connection i
+	after := make([][]int, len(cs))
+	before := make([]int, len(cs))
+	for i, ci := range cs {
+		for j, cj := range cs {
+			if ci.to.slave == cj.from.slave && m.feedthrough(cj.from, ci.to) {
+				after[i] = append(after[i], j)
+				before[j]++
+			}
+		}
+	}
+
+	ordered := make([]connection, 0, len(cs))
+	done := make([]bool, len(cs))
+	for len(ordered) < len(cs) {
+		next := -1
+		for i := range cs {
+			if !done[i] && before[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			loop := &AlgebraicLoopError{}
+			for i, c := range cs {
+				if !done[i] {
+					loop.Connections = append(loop.Connections, c.Connection)
+				}
+			}
+			return nil, loop
+		}
+		done[next] = true
+		ordered = append(ordered, cs[next])
+		for _, j := range after[next] {
+			before[j]--
+		}
+	}
+	return ordered, nil
+}
+
+// feedthrough returns true if output depends directly on input of the same slave
+func (m *Master) feedthrough(output, input port) bool {
+	outputs := m.slaves[output.slave].Description.ModelStructure.Outputs
+	if outputs == nil {
+		return true
+	}
+	for _, u := range *outputs {
+		if u.Index != output.index {
+			continue
+		}
+		// no dependencies attribute means the output depends on all inputs
+		if u.Dependencies == nil {
+			return true
+		}
+		for _, d := range u.Dependencies {
+			if d == input.index {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+/*
+orderSlaves sorts slaves so that slaves providing inputs are stepped first.
+Slaves coupled in a cycle without feedthrough are stepped in the order they were given.
+*/
+func orderSlaves(n int, cs []connection) []int {
+	before := make([]int, n)
+	for _, c := range cs {
+		if c.from.slave != c.to.slave {
+			before[c.to.slave]++
+		}
+	}
+	order := make([]int, 0, n)
+	done := make([]bool, n)
+	for len(order) < n {
+		next := -1
+		for i := 0; i < n; i++ {
+			if !done[i] && before[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// break the cycle at the first remaining slave
+			for i := 0; i < n; i++ {
+				if !done[i] {
+					next = i
+					break
+				}
+			}
+		}
+		done[next] = true
+		order = append(order, next)
+		for _, c := range cs {
+			if c.from.slave == next && c.to.slave != next && !done[c.to.slave] {
+				before[c.to.slave]--
+			}
+		}
+	}
+	return order
+}
+
+// Time is the current communication point
+func (m *Master) Time() float64 {
+	return m.time
+}
+
+/*
+Initialize sets up the experiment for all slaves, transfers connected values in Initialization Mode
+and exits Initialization Mode. Values of parameters and unconnected inputs should be set before.
+A stopTime of NaN means no stop time is defined.
+*/
+func (m *Master) Initialize(startTime, stopTime float64) error {
+	stopTimeDefined := !math.IsNaN(stopTime)
+	if !stopTimeDefined {
+		stopTime = 0
+	}
+	for _, s := range m.slaves {
+		if err := s.Instance.SetupExperiment(false, 0, startTime, stopTimeDefined, stopTime); err != nil {
+			return fmt.Errorf("Error setting up experiment for slave %s: %w", s.Name, err)
+		}
+	}
+	for _, s := range m.slaves {
+		if err := s.Instance.EnterInitializationMode(); err != nil {
+			return fmt.Errorf("Error entering initialization mode for slave %s: %w", s.Name, err)
+		}
+	}
+	if err := m.transferAll(); err != nil {
+		return err
+	}
+	for _, s := range m.slaves {
+		if err := s.Instance.ExitInitializationMode(); err != nil {
+			return fmt.Errorf("Error exiting initialization mode for slave %s: %w", s.Name, err)
+		}
+	}
+	m.time = startTime
+	return nil
+}
+
+// Step advances all slaves by communicationStepSize with the master schedule
+func (m *Master) Step(communicationStepSize float64) error {
+	if communicationStepSize <= 0 {
+		return fmt.Errorf("Communication step size must be positive, got %g", communicationStepSize)
+	}
+	return m.advance(communicationStepSize)
+}
+
+// advance steps all slaves with the master schedule and transfers the new outputs
+func (m *Master) advance(communicationStepSize float64) error {
+	switch m.schedule {
+	case ScheduleJacobi:
+		for i := range m.slaves {
+			if err := m.doStep(i, communicationStepSize); err != nil {
+				return err
+			}
+		}
+	case ScheduleGaussSeidel:
+		for _, i := range m.order {
+			for _, c := range m.inputs[i] {
+				if err := m.transfer(c); err != nil {
+					return err
+				}
+			}
+			if err := m.doStep(i, communicationStepSize); err != nil {
+				return err
+			}
+		}
+	}
+	m.time += communicationStepSize
+	return m.transferAll()
+}
+
+/*
+Run steps until stopTime with a fixed communicationStepSize.
+The last step is shortened so that the simulation ends at stopTime.
+*/
+func (m *Master) Run(stopTime, communicationStepSize float64) error {
+	// avoid a tiny final step caused by rounding
+	epsilon := communicationStepSize * 1e-9
+	for m.time < stopTime-epsilon {
+		h := math.Min(communicationStepSize, stopTime-m.time)
+		if err := m.Step(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Terminate terminates all slaves
+func (m *Master) Terminate() error {
+	var errs []string
+	for _, s := range m.slaves {
+		if err := s.Instance.Terminate(); err != nil {
+			errs = append(errs, fmt.Sprintf("slave %s: %v", s.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error terminating %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// GetReal returns the value of a Real variable of the named slave
+func (m *Master) GetReal(slave, variable string) (float64, error) {
+	s, vr, err := m.variable(slave, variable)
+	if err != nil {
+		return 0, err
+	}
+	fs, err := s.Instance.GetReal(vr)
+	if err != nil {
+		return 0, fmt.Errorf("Error getting %s.%s: %w", slave, variable, err)
+	}
+	return fs[0], nil
+}
+
+// SetReal sets the value of a Real variable of the named slave
+func (m *Master) SetReal(slave, variable string, value float64) error {
+	s, vr, err := m.variable(slave, variable)
+	if err != nil {
+		return err
+	}
+	if err := s.Instance.SetReal(vr, []float64{value}); err != nil {
+		return fmt.Errorf("Error setting %s.%s: %w", slave, variable, err)
+	}
+	return nil
+}
+
+func (m *Master) variable(slave, variable string) (Slave, fmi.ValueReference, error) {
+	for _, s := range m.slaves {
+		if s.Name != slave {
+			continue
+		}
+		for _, sv := range s.Description.ModelVariables {
+			if sv.Name == variable {
+				return s, fmi.ValueReference{sv.ValueReference}, nil
+			}
+		}
+		return Slave{}, nil, fmt.Errorf("Slave %s has no variable %s", slave, variable)
+	}
+	return Slave{}, nil, fmt.Errorf("Slave %s does not exist", slave)
+}
+
+func (m *Master) doStep(i int, communicationStepSize float64) error {
+	s := m.slaves[i]
+	if err := s.Instance.DoStep(m.time, communicationStepSize, !m.rollback); err != nil {
+		return fmt.Errorf("Error stepping slave %s at time %g: %w", s.Name, m.time, err)
+	}
+	return nil
+}
+
+// transferAll transfers all connections in dependency order
+func (m *Master) transferAll() error {
+	for _, c := range m.connections {
+		if err := m.transfer(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transfer copies the connection output value to its input
+func (m *Master) transfer(c connection) error {
+	from := m.slaves[c.from.slave].Instance
+	to := m.slaves[c.to.slave].Instance
+
+	var err error
+	switch baseType(c.from.typ) {
+	case fmi.VariableTypeReal:
+		var vs []float64
+		if vs, err = from.GetReal(c.from.vr); err == nil {
+			err = to.SetReal(c.to.vr, vs)
+		}
+	case fmi.VariableTypeInteger:
+		var vs []int32
+		if vs, err = from.GetInteger(c.from.vr); err == nil {
+			err = to.SetInteger(c.to.vr, vs)
+		}
+	case fmi.VariableTypeBoolean:
+		var vs []bool
+		if vs, err = from.GetBoolean(c.from.vr); err == nil {
+			err = to.SetBoolean(c.to.vr, vs)
+		}
+	case fmi.VariableTypeString:
+		var vs []string
+		if vs, err = from.GetString(c.from.vr); err == nil {
+			err = to.SetString(c.to.vr, vs)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Error transferring %s: %w", c.Connection, err)
+	}
+	return nil
+}
+
+// baseType maps enumerations to integers, which are exchanged with fmi2GetInteger and fmi2SetInteger
+func baseType(t fmi.VariableType) fmi.VariableType {
+	if t == fmi.VariableTypeEnumeration {
+		return fmi.VariableTypeInteger
+	}
+	return t
+}
+
+func causalityName(c fmi.VariableCausality) string {
+	bs, err := c.MarshalText()
+	if err != nil {
+		return "unknown"
+	}
+	return string(bs)
+}
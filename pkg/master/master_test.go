@@ -0,0 +1,302 @@
+package master_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/master"
+)
+
+// block is a test slave with Real variables, where value reference i is ScalarVariable index i+1
+type block struct {
+	name  string
+	reals []float64
+	// outputs computes outputs from states and inputs
+	outputs func(r []float64)
+	// step advances states by h
+	step func(r []float64, h float64)
+	// calls records DoStep calls of all blocks in a test
+	calls *[]string
+}
+
+func (b *block) SetupExperiment(bool, float64, float64, bool, float64) error { return nil }
+func (b *block) EnterInitializationMode() error                              { return nil }
+func (b *block) ExitInitializationMode() error                               { return nil }
+func (b *block) Terminate() error                                            { return nil }
+
+func (b *block) DoStep(_, h float64, _ bool) error {
+	*b.calls = append(*b.calls, b.name)
+	if b.step != nil {
+		b.step(b.reals, h)
+	}
+	return nil
+}
+
+func (b *block) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	if b.outputs != nil {
+		b.outputs(b.reals)
+	}
+	fs := make([]float64, len(vr))
+	for i, r := range vr {
+		fs[i] = b.reals[r]
+	}
+	return fs, nil
+}
+
+func (b *block) SetReal(vr fmi.ValueReference, values []float64) error {
+	for i, r := range vr {
+		b.reals[r] = values[i]
+	}
+	return nil
+}
+
+var errNotReal = errors.New("Only Real variables are supported")
+
+func (b *block) GetInteger(fmi.ValueReference) ([]int32, error) { return nil, errNotReal }
+func (b *block) GetBoolean(fmi.ValueReference) ([]bool, error)  { return nil, errNotReal }
+func (b *block) GetString(fmi.ValueReference) ([]string, error) { return nil, errNotReal }
+func (b *block) SetInteger(fmi.ValueReference, []int32) error   { return errNotReal }
+func (b *block) SetBoolean(fmi.ValueReference, []bool) error    { return errNotReal }
+func (b *block) SetString(fmi.ValueReference, []string) error   { return errNotReal }
+
+// description returns a model description with an output y and input u.
+// feedthrough sets whether y depends on u.
+func description(feedthrough bool) fmi.ModelDescription {
+	output := fmi.VariableCausalityOutput
+	input := fmi.VariableCausalityInput
+	deps := fmi.UintAttributeList{}
+	if feedthrough {
+		deps = fmi.UintAttributeList{2}
+	}
+	return fmi.ModelDescription{
+		ModelVariables: []fmi.ScalarVariable{
+			{Name: "y", ValueReference: 0, Causality: &output, ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "u", ValueReference: 1, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "b", ValueReference: 2, Causality: &input, ScalarVariableType: &fmi.ScalarVariableType{Boolean: &fmi.BooleanVariable{}}},
+		},
+		ModelStructure: fmi.ModelStructure{
+			Outputs: &[]fmi.Unknown{{Index: 1, Dependencies: deps}},
+		},
+	}
+}
+
+// integrator is a slave with y = x, der(x) = u
+func integrator(name string, x float64, calls *[]string) master.Slave {
+	return master.Slave{
+		Name:        name,
+		Description: description(false),
+		Instance: &block{
+			name:  name,
+			reals: []float64{x, 0},
+			step:  func(r []float64, h float64) { r[0] += r[1] * h },
+			calls: calls,
+		},
+	}
+}
+
+// gain is a slave with y = k * u
+func gain(name string, k float64, calls *[]string) master.Slave {
+	return master.Slave{
+		Name:        name,
+		Description: description(true),
+		Instance: &block{
+			name:    name,
+			reals:   []float64{0, 0},
+			outputs: func(r []float64) { r[0] = k * r[1] },
+			calls:   calls,
+		},
+	}
+}
+
+func connect(from, to string) master.Connection {
+	return master.Connection{FromSlave: from, FromVariable: "y", ToSlave: to, ToVariable: "u"}
+}
+
+func TestNew_Errors(t *testing.T) {
+	calls := &[]string{}
+	a := integrator("a", 0, calls)
+	b := gain("b", 1, calls)
+	tests := []struct {
+		name        string
+		slaves      []master.Slave
+		connections []master.Connection
+		schedule    master.Schedule
+		err         string
+	}{
+		{"Unknown schedule", []master.Slave{a}, nil, 5, "Unknown schedule 5"},
+		{"Empty name", []master.Slave{{Instance: a.Instance}}, nil, master.ScheduleJacobi, "Slave name cannot be empty"},
+		{"Duplicate name", []master.Slave{a, a}, nil, master.ScheduleJacobi, "Slave name a is not unique"},
+		{"No instance", []master.Slave{{Name: "a"}}, nil, master.ScheduleJacobi, "Slave a has no instance"},
+		{
+			"Unknown slave", []master.Slave{a}, []master.Connection{connect("a", "c")}, master.ScheduleJacobi,
+			"Connection a.y -> c.u: Slave c does not exist",
+		},
+		{
+			"Unknown variable", []master.Slave{a, b},
+			[]master.Connection{{FromSlave: "a", FromVariable: "z", ToSlave: "b", ToVariable: "u"}}, master.ScheduleJacobi,
+			"Connection a.z -> b.u: Slave a has no variable z",
+		},
+		{
+			"From input", []master.Slave{a, b},
+			[]master.Connection{{FromSlave: "a", FromVariable: "u", ToSlave: "b", ToVariable: "u"}}, master.ScheduleJacobi,
+			"Connection a.u -> b.u: Variable u of slave a must have causality output",
+		},
+		{
+			"Type mismatch", []master.Slave{a, b},
+			[]master.Connection{{FromSlave: "a", FromVariable: "y", ToSlave: "b", ToVariable: "b"}}, master.ScheduleJacobi,
+			"Connection a.y -> b.b: variable types do not match",
+		},
+		{
+			"Input connected twice", []master.Slave{a, b},
+			[]master.Connection{connect("a", "b"), connect("b", "b")}, master.ScheduleJacobi,
+			"Connection b.y -> b.u: input is already connected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := master.New(tt.slaves, tt.connections, tt.schedule)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("New() error = %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestNew_AlgebraicLoop(t *testing.T) {
+	calls := &[]string{}
+	tests := []struct {
+		name   string
+		slaves []master.Slave
+		loop   bool
+	}{
+		{"Feedthrough", []master.Slave{gain("a", 1, calls), gain("b", 1, calls)}, true},
+		{"Integrator", []master.Slave{gain("a", 1, calls), integrator("b", 0, calls)}, false},
+	}
+	connections := []master.Connection{connect("a", "b"), connect("b", "a")}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := master.New(tt.slaves, connections, master.ScheduleGaussSeidel)
+			var loop *master.AlgebraicLoopError
+			if got := errors.As(err, &loop); got != tt.loop {
+				t.Fatalf("New() error = %v, want algebraic loop %v", err, tt.loop)
+			}
+			if tt.loop && !reflect.DeepEqual(loop.Connections, connections) {
+				t.Errorf("AlgebraicLoopError.Connections = %v, want %v", loop.Connections, connections)
+			}
+		})
+	}
+}
+
+func TestMaster_Step_Order(t *testing.T) {
+	tests := []struct {
+		schedule master.Schedule
+		calls    []string
+	}{
+		{master.ScheduleJacobi, []string{"x", "k", "c"}},
+		{master.ScheduleGaussSeidel, []string{"c", "k", "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.schedule), func(t *testing.T) {
+			calls := &[]string{}
+			c := integrator("c", 1, calls)
+			// connections are given against dependency order, x.u is only correct if c.y -> k.u is transferred first
+			m, err := master.New(
+				[]master.Slave{integrator("x", 0, calls), gain("k", 2, calls), c},
+				[]master.Connection{connect("k", "x"), connect("c", "k")},
+				tt.schedule,
+			)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := m.Initialize(0, 1); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			if err := m.Step(0.5); err != nil {
+				t.Fatalf("Step() error = %v", err)
+			}
+			if !reflect.DeepEqual(*calls, tt.calls) {
+				t.Errorf("DoStep calls = %v, want %v", *calls, tt.calls)
+			}
+			x, err := m.GetReal("x", "y")
+			if err != nil {
+				t.Fatalf("GetReal() error = %v", err)
+			}
+			if x != 1 {
+				t.Errorf("x = %g, want 1", x)
+			}
+		})
+	}
+}
+
+func TestMaster_Run(t *testing.T) {
+	tests := []struct {
+		schedule master.Schedule
+		a, b     float64
+	}{
+		// a' = b, b' = a from a = 1, b = 0 in two steps of 0.5
+		{master.ScheduleJacobi, 1.25, 1},
+		{master.ScheduleGaussSeidel, 1.25, 1.125},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.schedule), func(t *testing.T) {
+			calls := &[]string{}
+			m, err := master.New(
+				[]master.Slave{integrator("a", 0, calls), integrator("b", 0, calls)},
+				[]master.Connection{connect("a", "b"), connect("b", "a")},
+				tt.schedule,
+			)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := m.SetReal("a", "y", 1); err != nil {
+				t.Fatalf("SetReal() error = %v", err)
+			}
+			if err := m.Initialize(0, math.NaN()); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			if err := m.Run(1, 0.5); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if m.Time() != 1 {
+				t.Errorf("Time() = %g, want 1", m.Time())
+			}
+			for _, v := range []struct {
+				slave string
+				want  float64
+			}{{"a", tt.a}, {"b", tt.b}} {
+				got, err := m.GetReal(v.slave, "y")
+				if err != nil {
+					t.Fatalf("GetReal() error = %v", err)
+				}
+				if got != v.want {
+					t.Errorf("%s.y = %g, want %g", v.slave, got, v.want)
+				}
+			}
+			if err := m.Terminate(); err != nil {
+				t.Errorf("Terminate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestMaster_Step_Errors(t *testing.T) {
+	calls := &[]string{}
+	m, err := master.New([]master.Slave{integrator("a", 0, calls)}, nil, master.ScheduleJacobi)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := m.Step(0); err == nil || !strings.Contains(err.Error(), "must be positive") {
+		t.Errorf("Step() error = %v, want step size error", err)
+	}
+	if _, err := m.GetReal("b", "y"); err == nil {
+		t.Errorf("GetReal() expected error for unknown slave")
+	}
+	if err := m.SetReal("a", "z", 1); err == nil {
+		t.Errorf("SetReal() expected error for unknown variable")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Discard is a co-simulation model for the master tests. It integrates its input u into the output y
+and only completes steps of at most maxStep: a larger step is integrated up to maxStep and
+returns fmi2Discard, so the master has to roll the state back before retrying.
+*/
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+const (
+	guid    = "{5c7d2b9e-2f0e-4a51-9d62-6a8f3c1e0b47}"
+	name    = "Discard"
+	maxStep = 0.1
+)
+
+func init() {
+	fmi.RegisterModel(model{})
+}
+
+type variables struct {
+	U float64 `causality:"input" variability:"continuous" start:"1" description:"Integrated input"`
+	Y float64 `causality:"output" variability:"continuous" initial:"exact" start:"0" description:"Integral of u"`
+}
+
+type model struct{}
+
+func (m model) Description() fmi.ModelDescription {
+	vs, _ := fmi.NewModelVariables(variables{})
+	return fmi.ModelDescription{
+		GUID: guid,
+		Name: name,
+		CoSimulation: &fmi.CoSimulation{
+			FMUShared: fmi.FMUShared{
+				ModelIdentifier:      name,
+				CanGetAndSetFMUstate: true,
+			},
+			CanHandleVariableCommunicationStepSize: true,
+		},
+		ModelVariables: vs.Variables(),
+		ModelStructure: fmi.ModelStructure{
+			Outputs: &[]fmi.Unknown{{Index: 2}},
+		},
+	}
+}
+
+func (m model) Instantiate(fmi.Logger) (fmi.ModelInstance, error) {
+	return &instance{v: variables{U: 1}}, nil
+}
+
+type instance struct {
+	v variables
+}
+
+// reals returns pointers to the Real variables by value reference
+func (i *instance) reals(vr fmi.ValueReference) ([]*float64, error) {
+	ps := make([]*float64, len(vr))
+	for j, r := range vr {
+		switch r {
+		case 1:
+			ps[j] = &i.v.U
+		case 2:
+			ps[j] = &i.v.Y
+		default:
+			return nil, fmt.Errorf("Unknown value reference %d", r)
+		}
+	}
+	return ps, nil
+}
+
+func (i *instance) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	ps, err := i.reals(vr)
+	if err != nil {
+		return nil, err
+	}
+	fs := make([]float64, len(ps))
+	for j, p := range ps {
+		fs[j] = *p
+	}
+	return fs, nil
+}
+
+func (i *instance) SetReal(vr fmi.ValueReference, fs []float64) error {
+	ps, err := i.reals(vr)
+	if err != nil {
+		return err
+	}
+	for j, p := range ps {
+		*p = fs[j]
+	}
+	return nil
+}
+
+func (i *instance) GetInteger(fmi.ValueReference) ([]int32, error) {
+	return nil, errors.New("Model has no Integer variables")
+}
+
+func (i *instance) GetBoolean(fmi.ValueReference) ([]bool, error) {
+	return nil, errors.New("Model has no Boolean variables")
+}
+
+func (i *instance) GetString(fmi.ValueReference) ([]string, error) {
+	return nil, errors.New("Model has no String variables")
+}
+
+func (i *instance) SetInteger(fmi.ValueReference, []int32) error {
+	return errors.New("Model has no Integer variables")
+}
+
+func (i *instance) SetBoolean(fmi.ValueReference, []bool) error {
+	return errors.New("Model has no Boolean variables")
+}
+
+func (i *instance) SetString(fmi.ValueReference, []string) error {
+	return errors.New("Model has no String variables")
+}
+
+func (i *instance) Encode() ([]byte, error) {
+	return fmi.BinaryStateCodec.Encode(&i.v)
+}
+
+func (i *instance) Decode(bs []byte) error {
+	return fmi.BinaryStateCodec.Decode(bs, &i.v)
+}
+
+func (i *instance) SetupExperiment(bool, float64, float64, bool, float64) error {
+	return nil
+}
+
+func (i *instance) EnterInitializationMode() error {
+	return nil
+}
+
+func (i *instance) ExitInitializationMode() error {
+	return nil
+}
+
+func (i *instance) Terminate() error {
+	return nil
+}
+
+func (i *instance) Reset() error {
+	i.v = variables{U: 1}
+	return nil
+}
+
+func (i *instance) DoStep(currentCommunicationPoint, communicationStepSize float64, _ bool) (fmi.StepResult, error) {
+	if communicationStepSize > maxStep {
+		i.v.Y += i.v.U * maxStep
+		return fmi.StepResultPartial, nil
+	}
+	i.v.Y += i.v.U * communicationStepSize
+	return fmi.StepResultSuccess, nil
+}
+
+func main() {
+	if err := fmi.WriteModelDescription(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
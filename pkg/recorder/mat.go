@@ -0,0 +1,118 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// MATLAB v4 matrix types, little endian IEEE with the precision in the tens digit and text flag in the units digit
+const (
+	matDouble = 0
+	matInt32  = 20
+	matText   = 51
+)
+
+/*
+WriteMAT writes the samples as a MATLAB v4 file in the transposed Dymola result format read by fmpy,
+DyMat and OMPython. The file contains the matrices:
+
+	Aclass      file class "Atrajectory", version "1.1" and "binTrans" for transposed data
+	name        variable names, one per column, starting with time
+	description variable descriptions, one per column
+	dataInfo    for each variable the data matrix, its row in the matrix, interpolation and extrapolation
+	data_1      time at the start and end of the run
+	data_2      the samples, one per column, with time in the first row
+*/
+func (r *Recorder) WriteMAT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	names := []string{"time"}
+	descriptions := []string{"Simulation time [s]"}
+	for _, v := range r.variables {
+		names = append(names, v.Name)
+		descriptions = append(descriptions, v.Description)
+	}
+
+	// time is the abscissa of data_2, all variables are trajectories in data_2
+	dataInfo := make([]int32, 0, 4*len(names))
+	dataInfo = append(dataInfo, 0, 1, 0, -1)
+	for i := range r.variables {
+		dataInfo = append(dataInfo, 2, int32(i+2), 0, -1)
+	}
+
+	var start, end float64
+	if len(r.rows) > 0 {
+		start, end = r.rows[0][0], r.rows[len(r.rows)-1][0]
+	}
+	data := make([]float64, 0, len(r.rows)*len(names))
+	for _, row := range r.rows {
+		data = append(data, row...)
+	}
+
+	matrices := []struct {
+		name       string
+		typ        int32
+		rows, cols int
+		data       interface{}
+	}{
+		// the class is stored by row and padded to 11 characters
+		{"Aclass", matText, 4, 11, transposeText([]string{"Atrajectory", "1.1", "", "binTrans"}, 11)},
+		{"name", matText, maxLength(names), len(names), columnText(names)},
+		{"description", matText, maxLength(descriptions), len(descriptions), columnText(descriptions)},
+		{"dataInfo", matInt32, 4, len(names), dataInfo},
+		{"data_1", matDouble, 1, 2, []float64{start, end}},
+		{"data_2", matDouble, len(names), len(r.rows), data},
+	}
+	for _, m := range matrices {
+		if err := writeMatrix(bw, m.name, m.typ, m.rows, m.cols, m.data); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeMatrix writes a MATLAB v4 matrix header, name and column major data
+func writeMatrix(w io.Writer, name string, typ int32, rows, cols int, data interface{}) error {
+	header := []int32{typ, int32(rows), int32(cols), 0, int32(len(name) + 1)}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, name+"\x00"); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, data)
+}
+
+func maxLength(ss []string) int {
+	n := 1
+	for _, s := range ss {
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+	return n
+}
+
+// columnText pads strings to the same length so each string is a column of a text matrix
+func columnText(ss []string) []byte {
+	n := maxLength(ss)
+	bs := make([]byte, 0, n*len(ss))
+	for _, s := range ss {
+		bs = append(bs, s+strings.Repeat(" ", n-len(s))...)
+	}
+	return bs
+}
+
+// transposeText stores strings padded to n characters as rows of a column major text matrix
+func transposeText(ss []string, n int) []byte {
+	cs := columnText(append(ss, strings.Repeat(" ", n)))
+	bs := make([]byte, len(ss)*n)
+	for i := range ss {
+		for j := 0; j < n; j++ {
+			bs[j*len(ss)+i] = cs[i*n+j]
+		}
+	}
+	return bs
+}
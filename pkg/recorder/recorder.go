@@ -0,0 +1,260 @@
+/*
+Package recorder samples FMU variables during a simulation and writes them as CSV or MATLAB v4 result files.
+
+Variables are selected from the model description by name and causality. Real, Integer, Enumeration and
+Boolean variables can be recorded; String variables are skipped. Booleans are recorded as 0 and 1.
+
+	r, err := recorder.New(md, recorder.Options{Interval: 0.1, Variables: []string{"h", "der(*)"}})
+	...
+	for t := start; t < stop; t += step {
+		err := inst.DoStep(t, step, true)
+		...
+		err = r.Sample(t+step, inst)
+	}
+	err = r.WriteFile("result.mat")
+*/
+package recorder
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+// Options configures what is recorded in a run
+type Options struct {
+	/*
+		Interval is the time between samples taken by Sample, so communication points between samples are skipped.
+		Samples are taken on a grid from the first sample time. 0 records every communication point.
+	*/
+	Interval float64
+	// Variables are names or path.Match patterns of variables to record. All variables are recorded if empty.
+	Variables []string
+	// Causalities limits recorded variables to these causalities. All causalities are recorded if empty.
+	Causalities []fmi.VariableCausality
+}
+
+// Variable is a recorded ScalarVariable
+type Variable struct {
+	Name           string
+	Description    string
+	Type           fmi.VariableType
+	ValueReference uint
+}
+
+// Recorder collects samples of variables
+type Recorder struct {
+	options   Options
+	variables []Variable
+
+	// reals, integers and booleans are the value references to get for each type,
+	// and the column of each value in a row
+	reals, integers, booleans                   fmi.ValueReference
+	realColumns, integerColumns, booleanColumns []int
+
+	// rows are the samples, starting with time
+	rows [][]float64
+	// start is the time of the first sample, samples is the number of interval grid points passed
+	started bool
+	start   float64
+	samples int
+}
+
+// New selects the variables to record from md
+func New(md fmi.ModelDescription, options Options) (*Recorder, error) {
+	if options.Interval < 0 || math.IsNaN(options.Interval) {
+		return nil, fmt.Errorf("Sample interval must not be negative, got %g", options.Interval)
+	}
+	for _, p := range options.Variables {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("Invalid variable pattern %s: %w", p, err)
+		}
+	}
+
+	r := &Recorder{options: options}
+	for _, sv := range md.ModelVariables {
+		if sv.ScalarVariableType == nil || !r.selected(sv) {
+			continue
+		}
+		t := sv.Type()
+		column := len(r.variables) + 1
+		switch t {
+		case fmi.VariableTypeReal:
+			r.reals = append(r.reals, sv.ValueReference)
+			r.realColumns = append(r.realColumns, column)
+		case fmi.VariableTypeInteger, fmi.VariableTypeEnumeration:
+			r.integers = append(r.integers, sv.ValueReference)
+			r.integerColumns = append(r.integerColumns, column)
+		case fmi.VariableTypeBoolean:
+			r.booleans = append(r.booleans, sv.ValueReference)
+			r.booleanColumns = append(r.booleanColumns, column)
+		default:
+			continue
+		}
+		r.variables = append(r.variables, Variable{
+			Name:           sv.Name,
+			Description:    sv.Description,
+			Type:           t,
+			ValueReference: sv.ValueReference,
+		})
+	}
+	if len(options.Variables) > 0 && len(r.variables) == 0 {
+		return nil, errors.New("No variables match the recorder options")
+	}
+	return r, nil
+}
+
+// selected returns true if sv matches the name patterns and causalities
+func (r *Recorder) selected(sv fmi.ScalarVariable) bool {
+	if len(r.options.Causalities) > 0 {
+		causality := fmi.VariableCausalityLocal
+		if sv.Causality != nil {
+			causality = *sv.Causality
+		}
+		found := false
+		for _, c := range r.options.Causalities {
+			found = found || c == causality
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.options.Variables) == 0 {
+		return true
+	}
+	for _, p := range r.options.Variables {
+		if p == sv.Name {
+			return true
+		}
+		if ok, _ := path.Match(p, sv.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Variables are the recorded variables in column order, after time
+func (r *Recorder) Variables() []Variable {
+	return r.variables
+}
+
+// Rows returns the samples, each starting with time followed by the variables
+func (r *Recorder) Rows() [][]float64 {
+	return r.rows
+}
+
+/*
+Sample records the variables from getter at a communication point, if it is on the sample interval.
+A sample is taken at the first call and then once time reaches each multiple of the interval after it.
+*/
+func (r *Recorder) Sample(time float64, getter fmi.ValueGetter) error {
+	if r.options.Interval > 0 && r.started {
+		next := r.start + float64(r.samples)*r.options.Interval
+		// allow for rounding in the communication points
+		if time < next-r.options.Interval*1e-9 {
+			return nil
+		}
+	}
+	if err := r.record(time, getter); err != nil {
+		return err
+	}
+	if r.options.Interval > 0 {
+		r.samples = int(math.Floor((time-r.start)/r.options.Interval+1e-9)) + 1
+	}
+	return nil
+}
+
+// Event records the variables from getter at an event, regardless of the sample interval.
+// Record before and after an event to keep both values at the event time.
+func (r *Recorder) Event(time float64, getter fmi.ValueGetter) error {
+	return r.record(time, getter)
+}
+
+func (r *Recorder) record(time float64, getter fmi.ValueGetter) error {
+	row := make([]float64, len(r.variables)+1)
+	row[0] = time
+	if len(r.reals) > 0 {
+		fs, err := getter.GetReal(r.reals)
+		if err != nil {
+			return fmt.Errorf("Error recording Real variables at time %g: %w", time, err)
+		}
+		for i, c := range r.realColumns {
+			row[c] = fs[i]
+		}
+	}
+	if len(r.integers) > 0 {
+		is, err := getter.GetInteger(r.integers)
+		if err != nil {
+			return fmt.Errorf("Error recording Integer variables at time %g: %w", time, err)
+		}
+		for i, c := range r.integerColumns {
+			row[c] = float64(is[i])
+		}
+	}
+	if len(r.booleans) > 0 {
+		bs, err := getter.GetBoolean(r.booleans)
+		if err != nil {
+			return fmt.Errorf("Error recording Boolean variables at time %g: %w", time, err)
+		}
+		for i, c := range r.booleanColumns {
+			if bs[i] {
+				row[c] = 1
+			}
+		}
+	}
+	r.rows = append(r.rows, row)
+	if !r.started {
+		r.started = true
+		r.start = time
+	}
+	return nil
+}
+
+// WriteCSV writes the samples as CSV with a header of time and the variable names, as used by the FMI cross-check
+func (r *Recorder) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := make([]string, len(r.variables)+1)
+	header[0] = "time"
+	for i, v := range r.variables {
+		header[i+1] = v.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for _, row := range r.rows {
+		for i, v := range row {
+			record[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFile writes the samples to name, as a MATLAB v4 file if it has the extension .mat or CSV otherwise
+func (r *Recorder) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("Error creating result file: %w", err)
+	}
+	write := r.WriteCSV
+	if filepath.Ext(name) == ".mat" {
+		write = r.WriteMAT
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("Error writing result file %s: %w", name, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,312 @@
+package recorder_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/recorder"
+)
+
+// values is a ValueGetter returning values indexed by value reference
+type values struct {
+	reals    []float64
+	integers []int32
+	booleans []bool
+	err      error
+}
+
+func (v *values) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	fs := make([]float64, len(vr))
+	for i, r := range vr {
+		fs[i] = v.reals[r]
+	}
+	return fs, v.err
+}
+
+func (v *values) GetInteger(vr fmi.ValueReference) ([]int32, error) {
+	is := make([]int32, len(vr))
+	for i, r := range vr {
+		is[i] = v.integers[r]
+	}
+	return is, nil
+}
+
+func (v *values) GetBoolean(vr fmi.ValueReference) ([]bool, error) {
+	bs := make([]bool, len(vr))
+	for i, r := range vr {
+		bs[i] = v.booleans[r]
+	}
+	return bs, nil
+}
+
+func (v *values) GetString(vr fmi.ValueReference) ([]string, error) {
+	return make([]string, len(vr)), nil
+}
+
+func description() fmi.ModelDescription {
+	output := fmi.VariableCausalityOutput
+	parameter := fmi.VariableCausalityParameter
+	return fmi.ModelDescription{
+		ModelVariables: []fmi.ScalarVariable{
+			{Name: "h", ValueReference: 0, Description: "Height", ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "der(h)", ValueReference: 1, ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "bounces", ValueReference: 0, Causality: &output, ScalarVariableType: &fmi.ScalarVariableType{Integer: &fmi.IntegerVariable{}}},
+			{Name: "ground", ValueReference: 0, Causality: &output, ScalarVariableType: &fmi.ScalarVariableType{Boolean: &fmi.BooleanVariable{}}},
+			{Name: "g", ValueReference: 2, Causality: &parameter, ScalarVariableType: &fmi.ScalarVariableType{Real: &fmi.RealVariable{}}},
+			{Name: "label", ValueReference: 0, ScalarVariableType: &fmi.ScalarVariableType{String: &fmi.StringVariable{}}},
+		},
+	}
+}
+
+func names(r *recorder.Recorder) []string {
+	var ns []string
+	for _, v := range r.Variables() {
+		ns = append(ns, v.Name)
+	}
+	return ns
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		options recorder.Options
+		want    []string
+		wantErr bool
+	}{
+		{"All", recorder.Options{}, []string{"h", "der(h)", "bounces", "ground", "g"}, false},
+		{"Names", recorder.Options{Variables: []string{"g", "h"}}, []string{"h", "g"}, false},
+		{"Pattern", recorder.Options{Variables: []string{"der(*)", "b*"}}, []string{"der(h)", "bounces"}, false},
+		{"Causality", recorder.Options{Causalities: []fmi.VariableCausality{fmi.VariableCausalityOutput}}, []string{"bounces", "ground"}, false},
+		{"Local", recorder.Options{Causalities: []fmi.VariableCausality{fmi.VariableCausalityLocal}}, []string{"h", "der(h)"}, false},
+		{"NamesAndCausality", recorder.Options{Variables: []string{"h", "ground"}, Causalities: []fmi.VariableCausality{fmi.VariableCausalityOutput}}, []string{"ground"}, false},
+		{"String", recorder.Options{Variables: []string{"label"}}, nil, true},
+		{"NoMatch", recorder.Options{Variables: []string{"x"}}, nil, true},
+		{"BadPattern", recorder.Options{Variables: []string{"["}}, nil, true},
+		{"NegativeInterval", recorder.Options{Interval: -1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := recorder.New(description(), tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, names(r)); diff != "" {
+				t.Errorf("Variables() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecorder_Sample(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval float64
+		want     []float64
+	}{
+		{"EveryStep", 0, []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6}},
+		{"Interval", 0.2, []float64{0, 0.2, 0.4, 0.6}},
+		{"LongerThanStep", 0.25, []float64{0, 0.3, 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := recorder.New(description(), recorder.Options{Interval: tt.interval, Variables: []string{"h"}})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			v := &values{reals: []float64{1, 0, 0}}
+			for _, time := range []float64{0, 0.1, 0.2, 0.3, 0.4, 0.5, 0.6} {
+				if err := r.Sample(time, v); err != nil {
+					t.Fatalf("Sample() error = %v", err)
+				}
+			}
+			var got []float64
+			for _, row := range r.Rows() {
+				got = append(got, row[0])
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Sample times mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRecorder_Event(t *testing.T) {
+	r, err := recorder.New(description(), recorder.Options{Interval: 1})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	v := &values{reals: []float64{1, -2, 9.81}, integers: []int32{0}, booleans: []bool{false}}
+	if err := r.Sample(0, v); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	if err := r.Event(0.5, v); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	v.reals[0], v.reals[1], v.integers[0], v.booleans[0] = 0, 1, 1, true
+	if err := r.Event(0.5, v); err != nil {
+		t.Fatalf("Event() error = %v", err)
+	}
+	// events do not move the sample grid
+	if err := r.Sample(0.9, v); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	want := [][]float64{
+		{0, 1, -2, 0, 0, 9.81},
+		{0.5, 1, -2, 0, 0, 9.81},
+		{0.5, 0, 1, 1, 1, 9.81},
+	}
+	if diff := cmp.Diff(want, r.Rows()); diff != "" {
+		t.Errorf("Rows() mismatch (-want +got):\n%s", diff)
+	}
+
+	v.err = errors.New("Get failed")
+	if err := r.Event(1, v); err == nil {
+		t.Errorf("Event() expected error from getter")
+	}
+	if len(r.Rows()) != 3 {
+		t.Errorf("Rows() has %d rows after failed sample, want 3", len(r.Rows()))
+	}
+}
+
+func TestRecorder_WriteCSV(t *testing.T) {
+	r, err := recorder.New(description(), recorder.Options{Variables: []string{"h", "der(h)", "ground"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	v := &values{reals: []float64{1, -0.5, 0}, booleans: []bool{false}}
+	if err := r.Sample(0, v); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	v.reals[0], v.booleans[0] = 0.25, true
+	if err := r.Sample(0.1, v); err != nil {
+		t.Fatalf("Sample() error = %v", err)
+	}
+	var b bytes.Buffer
+	if err := r.WriteCSV(&b); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	want := "time,h,der(h),ground\n0,1,-0.5,0\n0.1,0.25,-0.5,1\n"
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("WriteCSV() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// matrix is a MATLAB v4 matrix read back from a file
+type matrix struct {
+	typ, rows, cols int32
+	data            interface{}
+}
+
+func readMAT(t *testing.T, rd io.Reader) map[string]matrix {
+	ms := map[string]matrix{}
+	for {
+		var header [5]int32
+		if err := binary.Read(rd, binary.LittleEndian, &header); err == io.EOF {
+			return ms
+		} else if err != nil {
+			t.Fatalf("Error reading matrix header: %v", err)
+		}
+		name := make([]byte, header[4])
+		if _, err := io.ReadFull(rd, name); err != nil {
+			t.Fatalf("Error reading matrix name: %v", err)
+		}
+		m := matrix{typ: header[0], rows: header[1], cols: header[2]}
+		n := header[1] * header[2]
+		switch m.typ {
+		case 0:
+			m.data = make([]float64, n)
+		case 20:
+			m.data = make([]int32, n)
+		case 51:
+			m.data = make([]byte, n)
+		default:
+			t.Fatalf("Unexpected matrix type %d", m.typ)
+		}
+		if err := binary.Read(rd, binary.LittleEndian, m.data); err != nil {
+			t.Fatalf("Error reading matrix data: %v", err)
+		}
+		ms[strings.TrimRight(string(name), "\x00")] = m
+	}
+}
+
+// columns splits a column major text matrix into trimmed strings
+func columns(m matrix) []string {
+	var ss []string
+	bs := m.data.([]byte)
+	for c := int32(0); c < m.cols; c++ {
+		ss = append(ss, strings.TrimRight(string(bs[c*m.rows:(c+1)*m.rows]), " "))
+	}
+	return ss
+}
+
+// rows splits a column major text matrix into trimmed strings
+func rows(m matrix) []string {
+	var ss []string
+	bs := m.data.([]byte)
+	for r := int32(0); r < m.rows; r++ {
+		var s []byte
+		for c := int32(0); c < m.cols; c++ {
+			s = append(s, bs[c*m.rows+r])
+		}
+		ss = append(ss, strings.TrimRight(string(s), " "))
+	}
+	return ss
+}
+
+func TestRecorder_WriteMAT(t *testing.T) {
+	r, err := recorder.New(description(), recorder.Options{Variables: []string{"h", "bounces"}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	v := &values{reals: []float64{1, 0, 0}, integers: []int32{0}}
+	for i, h := range []float64{1, 0.5, 0} {
+		v.reals[0], v.integers[0] = h, int32(i)
+		if err := r.Sample(float64(i), v); err != nil {
+			t.Fatalf("Sample() error = %v", err)
+		}
+	}
+	name := filepath.Join(t.TempDir(), "result.mat")
+	if err := r.WriteFile(name); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Error opening result: %v", err)
+	}
+	defer f.Close()
+	ms := readMAT(t, f)
+
+	if diff := cmp.Diff([]string{"Atrajectory", "1.1", "", "binTrans"}, rows(ms["Aclass"])); diff != "" {
+		t.Errorf("Aclass mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"time", "h", "bounces"}, columns(ms["name"])); diff != "" {
+		t.Errorf("name mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"Simulation time [s]", "Height", ""}, columns(ms["description"])); diff != "" {
+		t.Errorf("description mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int32{0, 1, 0, -1, 2, 2, 0, -1, 2, 3, 0, -1}, ms["dataInfo"].data); diff != "" {
+		t.Errorf("dataInfo mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]float64{0, 2}, ms["data_1"].data); diff != "" {
+		t.Errorf("data_1 mismatch (-want +got):\n%s", diff)
+	}
+	data := ms["data_2"]
+	if data.rows != 3 || data.cols != 3 {
+		t.Errorf("data_2 is %dx%d, want 3x3", data.rows, data.cols)
+	}
+	if diff := cmp.Diff([]float64{0, 1, 0, 1, 0.5, 1, 2, 0, 2}, data.data); diff != "" {
+		t.Errorf("data_2 mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,112 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	// defaultNewtonTolerance is the default relative convergence tolerance of BDF Newton iterations
+	defaultNewtonTolerance = 1e-10
+	maxNewtonIterations    = 20
+)
+
+/*
+BDF is the implicit second order backward differentiation formula with variable step size.
+The first step after a reset uses BDF1 (backward Euler). Each step solves the implicit equation with
+Newton iterations on a finite difference Jacobian, so BDF is suited to small stiff models.
+*/
+type BDF struct {
+	// Tolerance is the relative convergence tolerance of the Newton iteration, defaults to 1e-10
+	Tolerance float64
+
+	// xPrev and hPrev are the states and step size of the previous step, nil after a reset
+	xPrev []float64
+	hPrev float64
+}
+
+// NewBDF returns a BDF2 solver
+func NewBDF() *BDF {
+	return &BDF{}
+}
+
+// Step takes an implicit BDF step of h
+func (b *BDF) Step(f Derivatives, t float64, x []float64, h float64) ([]float64, float64, error) {
+	// x(t+h) = alpha*x - beta*xPrev + gamma*h*f(t+h, x(t+h))
+	alpha, beta, gamma := 1.0, 0.0, 1.0
+	if b.xPrev != nil {
+		w := h / b.hPrev
+		alpha = (1 + w) * (1 + w) / (1 + 2*w)
+		beta = w * w / (1 + 2*w)
+		gamma = (1 + w) / (1 + 2*w)
+	}
+	tolerance := b.Tolerance
+	if tolerance == 0 {
+		tolerance = defaultNewtonTolerance
+	}
+
+	dx, err := f(t, x)
+	if err != nil {
+		return nil, 0, err
+	}
+	// explicit Euler predictor
+	y := axpy(x, h, dx)
+	tn := t + h
+	n := len(x)
+	for iteration := 0; iteration < maxNewtonIterations; iteration++ {
+		fy, err := f(tn, y)
+		if err != nil {
+			return nil, 0, err
+		}
+		g := make([]float64, n)
+		for i := range y {
+			g[i] = -(y[i] - alpha*x[i] - gamma*h*fy[i])
+			if beta != 0 {
+				g[i] -= beta * b.xPrev[i]
+			}
+		}
+
+		// J = I - gamma*h*df/dx by forward differences
+		j := make([][]float64, n)
+		for i := range j {
+			j[i] = make([]float64, n)
+			j[i][i] = 1
+		}
+		for c := 0; c < n; c++ {
+			delta := math.Sqrt(2.2e-16) * math.Max(math.Abs(y[c]), 1)
+			yd := append([]float64(nil), y...)
+			yd[c] += delta
+			fd, err := f(tn, yd)
+			if err != nil {
+				return nil, 0, err
+			}
+			for r := 0; r < n; r++ {
+				j[r][c] -= gamma * h * (fd[r] - fy[r]) / delta
+			}
+		}
+
+		d, err := solveLinear(j, g)
+		if err != nil {
+			return nil, 0, fmt.Errorf("BDF Newton iteration at time %g: %w", t, err)
+		}
+		converged := true
+		for i := range y {
+			y[i] += d[i]
+			if math.Abs(d[i]) > tolerance*(1+math.Abs(y[i])) {
+				converged = false
+			}
+		}
+		if converged {
+			b.xPrev = append([]float64(nil), x...)
+			b.hPrev = h
+			return y, h, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("BDF Newton iteration did not converge at time %g", t)
+}
+
+// Reset discards the previous step so the next step uses BDF1
+func (b *BDF) Reset() {
+	b.xPrev = nil
+	b.hPrev = 0
+}
@@ -0,0 +1,445 @@
+package solver
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+)
+
+const (
+	// maxEventIterations limits fmi2NewDiscreteStates calls at one event
+	maxEventIterations = 100
+	// rootIterations bisects the step interval to locate a state event
+	rootIterations = 60
+)
+
+// Model is a continuous-time model instance, as implemented by model exchange instances
+type Model interface {
+	fmi.ModelInstance
+	fmi.ValueGetterSetter
+
+	// SetTime sets the independent variable time
+	SetTime(time float64) error
+	// SetContinuousStates sets the continuous states
+	SetContinuousStates(x []float64) error
+	// GetContinuousStates returns the continuous states
+	GetContinuousStates() ([]float64, error)
+	// GetDerivatives returns the derivatives of the continuous states
+	GetDerivatives() ([]float64, error)
+	// GetEventIndicators returns the event indicators. A state event occurs when one changes sign.
+	GetEventIndicators() ([]float64, error)
+	// NewDiscreteStates updates the model at an event
+	NewDiscreteStates() (fmi.EventInfo, error)
+}
+
+// eventModeEnterer, continuousTimeModeEnterer, integratorStepCompleter and nominalsGetter are
+// optional model exchange methods of a Model
+type eventModeEnterer interface {
+	EnterEventMode() error
+}
+
+type continuousTimeModeEnterer interface {
+	EnterContinuousTimeMode() error
+}
+
+type integratorStepCompleter interface {
+	CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint bool) (enterEventMode, terminateSimulation bool, err error)
+}
+
+type nominalsGetter interface {
+	GetNominalsOfContinuousStates() ([]float64, error)
+}
+
+/*
+CoSimulator integrates a Model between communication points.
+
+Each communication step is split into solver steps of at most StepSize, ending at time events.
+When an event indicator changes sign in a solver step, the event time is located by bisection on
+a cubic Hermite interpolation of the step. At state, time and step events the model is updated
+with NewDiscreteStates until no further iteration is needed. DoStep returns StepResultPartial
+if the model requests termination, and LastSuccessfulTime reports the time it was requested.
+*/
+type CoSimulator struct {
+	Model
+
+	solver   Solver
+	stepSize float64
+
+	// state is saved in FMU states
+	state cosimState
+}
+
+type cosimState struct {
+	Time float64
+	// Started is set once the initial event iteration was done in the first DoStep
+	Started bool
+	// Indicators are the event indicators at Time
+	Indicators []float64
+	// Event holds the next time event
+	Event              fmi.EventInfo
+	Terminated         bool
+	LastSuccessfulTime float64
+}
+
+// NewCoSimulator wraps model to integrate it with solver in steps of at most stepSize
+func NewCoSimulator(model Model, solver Solver, stepSize float64) *CoSimulator {
+	return &CoSimulator{
+		Model:    model,
+		solver:   solver,
+		stepSize: stepSize,
+	}
+}
+
+// SetupExperiment passes a defined tolerance to the solver and records the start time
+func (c *CoSimulator) SetupExperiment(toleranceDefined bool, tolerance float64,
+	startTime float64, stopTimeDefined bool, stopTime float64) error {
+	if toleranceDefined {
+		if ts, ok := c.solver.(ToleranceSetter); ok {
+			ts.SetTolerance(tolerance)
+		}
+	}
+	c.state.Time = startTime
+	c.state.LastSuccessfulTime = startTime
+	return c.Model.SetupExperiment(toleranceDefined, tolerance, startTime, stopTimeDefined, stopTime)
+}
+
+// Reset resets the model and the integration
+func (c *CoSimulator) Reset() error {
+	c.state = cosimState{}
+	c.solver.Reset()
+	return c.Model.Reset()
+}
+
+// DoStep integrates the model from currentCommunicationPoint by communicationStepSize
+func (c *CoSimulator) DoStep(currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) (fmi.StepResult, error) {
+	if c.state.Terminated {
+		return fmi.StepResultPartial, fmt.Errorf("Simulation was terminated at time %g", c.state.LastSuccessfulTime)
+	}
+	if err := c.integrate(currentCommunicationPoint, communicationStepSize, noSetFMUStatePriorToCurrentPoint); err != nil {
+		return fmi.StepResultPartial, err
+	}
+	if c.state.Terminated {
+		return fmi.StepResultPartial, nil
+	}
+	return fmi.StepResultSuccess, nil
+}
+
+// integrate takes solver steps up to the end of the communication step or until termination
+func (c *CoSimulator) integrate(currentCommunicationPoint, communicationStepSize float64,
+	noSetFMUStatePriorToCurrentPoint bool) error {
+	c.state.Time = currentCommunicationPoint
+	if !c.state.Started {
+		if err := c.setTime(c.state.Time); err != nil {
+			return err
+		}
+		if err := c.handleEvent(); err != nil {
+			return err
+		}
+		c.state.Started = true
+	}
+
+	end := currentCommunicationPoint + communicationStepSize
+	epsilon := 1e-9 * communicationStepSize
+	for !c.state.Terminated && c.state.Time < end-epsilon {
+		event := c.state.Event
+		if event.NextEventTimeDefined && event.NextEventTime <= c.state.Time+epsilon {
+			if err := c.handleEvent(); err != nil {
+				return err
+			}
+			next := c.state.Event
+			if next.NextEventTimeDefined && next.NextEventTime <= event.NextEventTime {
+				return fmt.Errorf("Next time event %g is not after time event %g", next.NextEventTime, event.NextEventTime)
+			}
+			continue
+		}
+		h := math.Min(c.stepSize, end-c.state.Time)
+		if event.NextEventTimeDefined && event.NextEventTime < c.state.Time+h {
+			h = event.NextEventTime - c.state.Time
+		}
+		if err := c.step(h, noSetFMUStatePriorToCurrentPoint); err != nil {
+			return err
+		}
+	}
+	if c.state.Terminated {
+		return nil
+	}
+	c.state.Time = end
+	c.state.LastSuccessfulTime = end
+	return c.setTime(end)
+}
+
+// step takes one solver step of at most h and handles events in it
+func (c *CoSimulator) step(h float64, noSetFMUStatePriorToCurrentPoint bool) error {
+	t := c.state.Time
+	x, err := c.GetContinuousStates()
+	if err != nil {
+		return err
+	}
+	x = append([]float64(nil), x...)
+	dx, err := c.derivatives(t, x)
+	if err != nil {
+		return err
+	}
+	xn, taken, err := c.solver.Step(c.derivatives, t, x, h)
+	if err != nil {
+		return err
+	}
+	tn := t + taken
+	if err := c.setState(tn, xn); err != nil {
+		return err
+	}
+	z, err := c.indicators()
+	if err != nil {
+		return err
+	}
+
+	stateEvent := crossed(c.state.Indicators, z)
+	if stateEvent {
+		dxn, err := c.derivatives(tn, xn)
+		if err != nil {
+			return err
+		}
+		if tn, err = c.locate(t, x, dx, tn, xn, dxn); err != nil {
+			return err
+		}
+	}
+	next := c.state.Event
+	timeEvent := next.NextEventTimeDefined && tn >= next.NextEventTime-minStepSize(tn)
+	event := stateEvent || timeEvent
+	c.state.Time = tn
+	c.state.LastSuccessfulTime = tn
+
+	if isc, ok := c.Model.(integratorStepCompleter); ok {
+		enterEventMode, terminate, err := isc.CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint)
+		if err != nil {
+			return err
+		}
+		if terminate {
+			c.state.Terminated = true
+			return nil
+		}
+		event = event || enterEventMode
+	}
+	if event {
+		return c.handleEvent()
+	}
+	c.state.Indicators = z
+	return nil
+}
+
+/*
+locate finds the first time in (t, tn] where an event indicator changes sign, interpolating states with a
+cubic Hermite polynomial through x, dx at t and xn, dxn at tn. The model is left at the located time.
+*/
+func (c *CoSimulator) locate(t float64, x, dx []float64, tn float64, xn, dxn []float64) (float64, error) {
+	h := tn - t
+	interpolate := func(theta float64) []float64 {
+		s := theta / h
+		h00 := (1 + 2*s) * (1 - s) * (1 - s)
+		h10 := s * (1 - s) * (1 - s)
+		h01 := s * s * (3 - 2*s)
+		h11 := s * s * (s - 1)
+		xs := make([]float64, len(x))
+		for i := range x {
+			xs[i] = h00*x[i] + h10*h*dx[i] + h01*xn[i] + h11*h*dxn[i]
+		}
+		return xs
+	}
+
+	// the indicators have changed sign between lo and hi
+	lo, hi := 0.0, h
+	for i := 0; i < rootIterations && hi-lo > minStepSize(t); i++ {
+		mid := (lo + hi) / 2
+		if err := c.setState(t+mid, interpolate(mid)); err != nil {
+			return 0, err
+		}
+		z, err := c.indicators()
+		if err != nil {
+			return 0, err
+		}
+		if crossed(c.state.Indicators, z) {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	if hi == h {
+		return tn, c.setState(tn, xn)
+	}
+	return t + hi, c.setState(t+hi, interpolate(hi))
+}
+
+// handleEvent updates the model with NewDiscreteStates until no further iteration is needed
+func (c *CoSimulator) handleEvent() error {
+	if eme, ok := c.Model.(eventModeEnterer); ok {
+		if err := eme.EnterEventMode(); err != nil {
+			return err
+		}
+	}
+	for i := 0; ; i++ {
+		if i == maxEventIterations {
+			return fmt.Errorf("Event iteration at time %g did not converge after %d iterations", c.state.Time, maxEventIterations)
+		}
+		info, err := c.NewDiscreteStates()
+		if err != nil {
+			return err
+		}
+		c.state.Event = info
+		if info.TerminateSimulation {
+			c.state.Terminated = true
+			c.state.LastSuccessfulTime = c.state.Time
+			return nil
+		}
+		if !info.NewDiscreteStatesNeeded {
+			break
+		}
+	}
+	if ctme, ok := c.Model.(continuousTimeModeEnterer); ok {
+		if err := ctme.EnterContinuousTimeMode(); err != nil {
+			return err
+		}
+	}
+	c.solver.Reset()
+	z, err := c.indicators()
+	if err != nil {
+		return err
+	}
+	c.state.Indicators = z
+	return nil
+}
+
+// derivatives sets the model to time t and states x and returns the derivatives
+func (c *CoSimulator) derivatives(t float64, x []float64) ([]float64, error) {
+	if err := c.setState(t, x); err != nil {
+		return nil, err
+	}
+	dx, err := c.GetDerivatives()
+	if err != nil {
+		return nil, err
+	}
+	return append([]float64(nil), dx...), nil
+}
+
+func (c *CoSimulator) setState(t float64, x []float64) error {
+	if err := c.setTime(t); err != nil {
+		return err
+	}
+	return c.SetContinuousStates(x)
+}
+
+func (c *CoSimulator) setTime(t float64) error {
+	return c.Model.SetTime(t)
+}
+
+func (c *CoSimulator) indicators() ([]float64, error) {
+	z, err := c.GetEventIndicators()
+	if err != nil {
+		return nil, err
+	}
+	return append([]float64(nil), z...), nil
+}
+
+// crossed returns true if any indicator changed sign from z to zn
+func crossed(z, zn []float64) bool {
+	for i := range z {
+		if i < len(zn) && (z[i] < 0 && zn[i] >= 0 || z[i] > 0 && zn[i] <= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// LastSuccessfulTime is the time up to which the last step was computed
+func (c *CoSimulator) LastSuccessfulTime() float64 {
+	return c.state.LastSuccessfulTime
+}
+
+// Terminated returns true if the model requested termination
+func (c *CoSimulator) Terminated() bool {
+	return c.state.Terminated
+}
+
+// EnterEventMode is forwarded to the model if it implements it
+func (c *CoSimulator) EnterEventMode() error {
+	if eme, ok := c.Model.(eventModeEnterer); ok {
+		return eme.EnterEventMode()
+	}
+	return nil
+}
+
+// EnterContinuousTimeMode is forwarded to the model if it implements it
+func (c *CoSimulator) EnterContinuousTimeMode() error {
+	if ctme, ok := c.Model.(continuousTimeModeEnterer); ok {
+		return ctme.EnterContinuousTimeMode()
+	}
+	return nil
+}
+
+// CompletedIntegratorStep is forwarded to the model if it implements it
+func (c *CoSimulator) CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint bool) (bool, bool, error) {
+	if isc, ok := c.Model.(integratorStepCompleter); ok {
+		return isc.CompletedIntegratorStep(noSetFMUStatePriorToCurrentPoint)
+	}
+	return false, false, nil
+}
+
+// GetNominalsOfContinuousStates is forwarded to the model if it implements it, otherwise all nominals are 1
+func (c *CoSimulator) GetNominalsOfContinuousStates() ([]float64, error) {
+	if ng, ok := c.Model.(nominalsGetter); ok {
+		return ng.GetNominalsOfContinuousStates()
+	}
+	x, err := c.GetContinuousStates()
+	if err != nil {
+		return nil, err
+	}
+	nominals := make([]float64, len(x))
+	for i := range nominals {
+		nominals[i] = 1
+	}
+	return nominals, nil
+}
+
+type encodedState struct {
+	State cosimState
+	Model []byte
+}
+
+// Encode saves the integration state with the model state, if the model implements fmi.StateEncoder
+func (c *CoSimulator) Encode() ([]byte, error) {
+	se, ok := c.Model.(fmi.StateEncoder)
+	if !ok {
+		return nil, errors.New("Model does not implement state encoding")
+	}
+	bs, err := se.Encode()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(encodedState{c.state, bs}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode restores the integration state and the model state, if the model implements fmi.StateDecoder
+func (c *CoSimulator) Decode(bs []byte) error {
+	sd, ok := c.Model.(fmi.StateDecoder)
+	if !ok {
+		return errors.New("Model does not implement state decoding")
+	}
+	var s encodedState
+	if err := gob.NewDecoder(bytes.NewReader(bs)).Decode(&s); err != nil {
+		return err
+	}
+	if err := sd.Decode(s.Model); err != nil {
+		return err
+	}
+	c.state = s.State
+	c.solver.Reset()
+	return nil
+}
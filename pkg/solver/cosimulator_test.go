@@ -0,0 +1,239 @@
+package solver_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/solver"
+)
+
+var (
+	_ fmi.CoSimulator        = &solver.CoSimulator{}
+	_ fmi.ModelExchanger     = &solver.CoSimulator{}
+	_ fmi.StepStatusReporter = &solver.CoSimulator{}
+	_ fmi.StateEncoder       = &solver.CoSimulator{}
+	_ fmi.StateDecoder       = &solver.CoSimulator{}
+)
+
+// ball is a bouncing ball with height H and velocity V, falling with gravity G
+type ball struct {
+	Time float64
+	H, V float64
+	G, E float64
+	// Kicks are times when the velocity is increased by 1
+	Kicks []float64
+	// MaxBounces terminates the simulation after this many bounces, 0 for no limit
+	MaxBounces int
+	// Events records the times of all events
+	Events []float64
+}
+
+func (b *ball) SetupExperiment(bool, float64, float64, bool, float64) error { return nil }
+func (b *ball) EnterInitializationMode() error                              { return nil }
+func (b *ball) ExitInitializationMode() error                               { return nil }
+func (b *ball) Terminate() error                                            { return nil }
+func (b *ball) Reset() error                                                { return nil }
+
+func (b *ball) GetReal(vr fmi.ValueReference) ([]float64, error) {
+	return []float64{b.H, b.V}[:len(vr)], nil
+}
+
+var errUnsupported = errors.New("Unsupported")
+
+func (b *ball) GetInteger(fmi.ValueReference) ([]int32, error) { return nil, errUnsupported }
+func (b *ball) GetBoolean(fmi.ValueReference) ([]bool, error)  { return nil, errUnsupported }
+func (b *ball) GetString(fmi.ValueReference) ([]string, error) { return nil, errUnsupported }
+func (b *ball) SetReal(fmi.ValueReference, []float64) error    { return errUnsupported }
+func (b *ball) SetInteger(fmi.ValueReference, []int32) error   { return errUnsupported }
+func (b *ball) SetBoolean(fmi.ValueReference, []bool) error    { return errUnsupported }
+func (b *ball) SetString(fmi.ValueReference, []string) error   { return errUnsupported }
+
+func (b *ball) SetTime(time float64) error {
+	b.Time = time
+	return nil
+}
+
+func (b *ball) SetContinuousStates(x []float64) error {
+	b.H, b.V = x[0], x[1]
+	return nil
+}
+
+func (b *ball) GetContinuousStates() ([]float64, error) {
+	return []float64{b.H, b.V}, nil
+}
+
+func (b *ball) GetDerivatives() ([]float64, error) {
+	return []float64{b.V, -b.G}, nil
+}
+
+func (b *ball) GetEventIndicators() ([]float64, error) {
+	return []float64{b.H}, nil
+}
+
+func (b *ball) NewDiscreteStates() (fmi.EventInfo, error) {
+	info := fmi.EventInfo{}
+	if len(b.Kicks) > 0 && b.Time >= b.Kicks[0] {
+		b.Events = append(b.Events, b.Time)
+		b.V++
+		b.Kicks = b.Kicks[1:]
+		info.ValuesOfContinuousStatesChanged = true
+	}
+	if b.H <= 0 && b.V < 0 {
+		b.Events = append(b.Events, b.Time)
+		b.H, b.V = 0, -b.E*b.V
+		info.ValuesOfContinuousStatesChanged = true
+	}
+	if b.MaxBounces > 0 && len(b.Events) >= b.MaxBounces {
+		info.TerminateSimulation = true
+	}
+	if len(b.Kicks) > 0 {
+		info.NextEventTimeDefined = true
+		info.NextEventTime = b.Kicks[0]
+	}
+	return info, nil
+}
+
+func (b *ball) Encode() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+func (b *ball) Decode(bs []byte) error {
+	return json.Unmarshal(bs, b)
+}
+
+func TestCoSimulator_StateEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		solver    solver.Solver
+		tolerance float64
+	}{
+		// the trajectory is quadratic, so only Euler and the first BDF1 step are inexact
+		{"Euler", solver.NewEuler(), 5e-2},
+		{"RK4", solver.NewRK4(), 1e-9},
+		{"DormandPrince", solver.NewDormandPrince(1e-6, 1e-6), 1e-9},
+		{"BDF", solver.NewBDF(), 1e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &ball{H: 5, G: 10, E: 0.5}
+			c := solver.NewCoSimulator(b, tt.solver, 0.03)
+			if err := c.SetupExperiment(false, 0, 0, false, 0); err != nil {
+				t.Fatalf("SetupExperiment() error = %v", err)
+			}
+			res, err := c.DoStep(0, 1.5, false)
+			if err != nil || res != fmi.StepResultSuccess {
+				t.Fatalf("DoStep() = %v, %v", res, err)
+			}
+			// the ball hits the ground at t = 1 with v = -10 and bounces with v = 5
+			if len(b.Events) != 1 || math.Abs(b.Events[0]-1) > tt.tolerance {
+				t.Errorf("Events = %v, want bounce at 1", b.Events)
+			}
+			wantH := 5*0.5 - 5*0.5*0.5
+			if math.Abs(b.H-wantH) > 10*tt.tolerance || b.Time != 1.5 {
+				t.Errorf("Ball at time %g has height %g, want %g at time 1.5", b.Time, b.H, wantH)
+			}
+			if c.LastSuccessfulTime() != 1.5 || c.Terminated() {
+				t.Errorf("LastSuccessfulTime() = %g, Terminated() = %v", c.LastSuccessfulTime(), c.Terminated())
+			}
+		})
+	}
+}
+
+func TestCoSimulator_TimeEvent(t *testing.T) {
+	b := &ball{H: 5, Kicks: []float64{0.25, 0.3}}
+	c := solver.NewCoSimulator(b, solver.NewRK4(), 0.1)
+	for i := 0; i < 5; i++ {
+		if _, err := c.DoStep(float64(i)*0.1, 0.1, false); err != nil {
+			t.Fatalf("DoStep() error = %v", err)
+		}
+	}
+	if len(b.Events) != 2 || b.Events[0] != 0.25 || math.Abs(b.Events[1]-0.3) > 1e-12 {
+		t.Errorf("Events = %v, want kicks at 0.25 and 0.3", b.Events)
+	}
+	if want := 5 + 0.05 + 2*0.2; math.Abs(b.H-want) > 1e-9 {
+		t.Errorf("H = %g, want %g", b.H, want)
+	}
+}
+
+func TestCoSimulator_Terminate(t *testing.T) {
+	b := &ball{H: 5, G: 10, E: 0.5, MaxBounces: 1}
+	c := solver.NewCoSimulator(b, solver.NewRK4(), 0.01)
+	res, err := c.DoStep(0, 2, false)
+	if err != nil || res != fmi.StepResultPartial {
+		t.Fatalf("DoStep() = %v, %v, want partial result", res, err)
+	}
+	if !c.Terminated() || math.Abs(c.LastSuccessfulTime()-1) > 1e-9 {
+		t.Errorf("Terminated() = %v, LastSuccessfulTime() = %g, want termination at 1", c.Terminated(), c.LastSuccessfulTime())
+	}
+	if _, err := c.DoStep(2, 1, false); err == nil {
+		t.Errorf("DoStep() expected error after termination")
+	}
+	if err := c.Reset(); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if c.Terminated() {
+		t.Errorf("Terminated() = true after Reset")
+	}
+}
+
+func TestCoSimulator_SetupExperiment(t *testing.T) {
+	s := solver.NewDormandPrince(1, 1)
+	c := solver.NewCoSimulator(&ball{}, s, 0.1)
+	if err := c.SetupExperiment(true, 1e-5, 2, false, 0); err != nil {
+		t.Fatalf("SetupExperiment() error = %v", err)
+	}
+	if s.RelativeTolerance != 1e-5 || s.AbsoluteTolerance != 1e-5 {
+		t.Errorf("Tolerances = %g, %g, want 1e-5", s.RelativeTolerance, s.AbsoluteTolerance)
+	}
+	if c.LastSuccessfulTime() != 2 {
+		t.Errorf("LastSuccessfulTime() = %g, want start time 2", c.LastSuccessfulTime())
+	}
+
+	// a zero tolerance from the host uses the default tolerance of the solver
+	if err := c.SetupExperiment(true, 0, 2, false, 0); err != nil {
+		t.Fatalf("SetupExperiment() error = %v", err)
+	}
+	if _, err := c.DoStep(2, 0.1, false); err != nil {
+		t.Errorf("DoStep() with zero tolerance error = %v", err)
+	}
+}
+
+func TestCoSimulator_EncodeDecode(t *testing.T) {
+	b := &ball{H: 5, G: 10, E: 0.5, MaxBounces: 1}
+	c := solver.NewCoSimulator(b, solver.NewRK4(), 0.01)
+	if _, err := c.DoStep(0, 0.5, false); err != nil {
+		t.Fatalf("DoStep() error = %v", err)
+	}
+	bs, err := c.Encode()
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if res, err := c.DoStep(0.5, 1, false); err != nil || res != fmi.StepResultPartial {
+		t.Fatalf("DoStep() = %v, %v, want termination", res, err)
+	}
+
+	if err := c.Decode(bs); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if c.Terminated() || c.LastSuccessfulTime() != 0.5 || b.H != 5-1.25 {
+		t.Errorf("Decode() did not restore state at 0.5, got terminated %v at %g with H %g",
+			c.Terminated(), c.LastSuccessfulTime(), b.H)
+	}
+	if _, err := c.DoStep(0.5, 0.25, false); err != nil {
+		t.Errorf("DoStep() after Decode error = %v", err)
+	}
+}
+
+func TestCoSimulator_GetNominalsOfContinuousStates(t *testing.T) {
+	c := solver.NewCoSimulator(&ball{}, solver.NewEuler(), 0.1)
+	n, err := c.GetNominalsOfContinuousStates()
+	if err != nil {
+		t.Fatalf("GetNominalsOfContinuousStates() error = %v", err)
+	}
+	if len(n) != 2 || n[0] != 1 || n[1] != 1 {
+		t.Errorf("GetNominalsOfContinuousStates() = %v, want [1 1]", n)
+	}
+}
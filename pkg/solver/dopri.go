@@ -0,0 +1,123 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+)
+
+// defaultDormandPrinceTolerance replaces tolerances of zero or less, which would never accept a step
+const defaultDormandPrinceTolerance = 1e-6
+
+// Dormand-Prince coefficients, e is the difference between the fifth and fourth order weights
+var (
+	dopriC = [...]float64{0, 1. / 5, 3. / 10, 4. / 5, 8. / 9, 1, 1}
+	dopriA = [...][]float64{
+		{},
+		{1. / 5},
+		{3. / 40, 9. / 40},
+		{44. / 45, -56. / 15, 32. / 9},
+		{19372. / 6561, -25360. / 2187, 64448. / 6561, -212. / 729},
+		{9017. / 3168, -355. / 33, 46732. / 5247, 49. / 176, -5103. / 18656},
+		{35. / 384, 0, 500. / 1113, 125. / 192, -2187. / 6784, 11. / 84},
+	}
+	dopriE = [...]float64{71. / 57600, 0, -71. / 16695, 71. / 1920, -17253. / 339200, 22. / 525, -1. / 40}
+)
+
+/*
+DormandPrince is the adaptive Dormand-Prince RK45 method.
+The error of each step is estimated from the embedded fourth order solution and steps are
+repeated with a smaller step size until the error is within tolerance.
+*/
+type DormandPrince struct {
+	RelativeTolerance float64
+	AbsoluteTolerance float64
+
+	// h is the step size estimated from the last step, 0 if unknown
+	h float64
+}
+
+// NewDormandPrince returns an RK45 solver with tolerances, which default to 1e-6 if zero or less
+func NewDormandPrince(relativeTolerance, absoluteTolerance float64) *DormandPrince {
+	return &DormandPrince{
+		RelativeTolerance: positiveTolerance(relativeTolerance),
+		AbsoluteTolerance: positiveTolerance(absoluteTolerance),
+	}
+}
+
+// SetTolerance sets the relative tolerance and an absolute tolerance of the same value,
+// the default tolerance is used if tolerance is zero or less
+func (d *DormandPrince) SetTolerance(tolerance float64) {
+	d.RelativeTolerance = positiveTolerance(tolerance)
+	d.AbsoluteTolerance = positiveTolerance(tolerance)
+}
+
+func positiveTolerance(tolerance float64) float64 {
+	if tolerance > 0 {
+		return tolerance
+	}
+	return defaultDormandPrinceTolerance
+}
+
+// Step takes an RK45 step of at most h within tolerance
+func (d *DormandPrince) Step(f Derivatives, t float64, x []float64, h float64) ([]float64, float64, error) {
+	if d.h > 0 && d.h < h {
+		h = d.h
+	}
+	k := make([][]float64, len(dopriC))
+	var err error
+	if k[0], err = f(t, x); err != nil {
+		return nil, 0, err
+	}
+	for {
+		xs := x
+		for s := 1; s < len(dopriC); s++ {
+			xs = make([]float64, len(x))
+			for i := range x {
+				xs[i] = x[i]
+				for j, a := range dopriA[s] {
+					xs[i] += h * a * k[j][i]
+				}
+			}
+			if k[s], err = f(t+dopriC[s]*h, xs); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		// the last stage is evaluated at the fifth order solution
+		e := 0.0
+		for i := range x {
+			ei := 0.0
+			for s, c := range dopriE {
+				ei += h * c * k[s][i]
+			}
+			scale := d.AbsoluteTolerance + d.RelativeTolerance*math.Max(math.Abs(x[i]), math.Abs(xs[i]))
+			e += (ei / scale) * (ei / scale)
+		}
+		if len(x) > 0 {
+			e = math.Sqrt(e / float64(len(x)))
+		}
+		// NaN never passes or fails the error check, so the step could not end
+		if math.IsNaN(e) || math.IsInf(e, 0) {
+			return nil, 0, fmt.Errorf("Dormand-Prince error estimate at time %g is %g, "+
+				"the tolerances or derivatives are not finite", t, e)
+		}
+
+		factor := 5.0
+		if e > 0 {
+			factor = math.Max(0.2, math.Min(5, 0.9*math.Pow(e, -0.2)))
+		}
+		if e <= 1 {
+			d.h = h * factor
+			return xs, h, nil
+		}
+		h *= factor
+		if h < minStepSize(t) {
+			return nil, 0, stepSizeError("Dormand-Prince", t, h)
+		}
+	}
+}
+
+// Reset forgets the estimated step size
+func (d *DormandPrince) Reset() {
+	d.h = 0
+}
@@ -0,0 +1,151 @@
+/*
+Package solver integrates continuous-time models and wraps them into co-simulation FMUs.
+
+A Model exposes continuous states, derivatives and event indicators like a model exchange FMU.
+NewCoSimulator wraps a Model into an fmi.CoSimulator that integrates it with a Solver between
+communication points, locating state events from event indicators and handling time events and
+termination requests. The wrapper also forwards model exchange calls, so a single model instance can
+be registered for both ModelExchange and CoSimulation.
+
+	func (m model) Instantiate(l fmi.Logger) (fmi.ModelInstance, error) {
+		return solver.NewCoSimulator(&instance{Logger: l}, solver.NewRK4(), 1e-3), nil
+	}
+*/
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Derivatives returns the state derivatives at time t and states x
+type Derivatives func(t float64, x []float64) ([]float64, error)
+
+// Solver integrates states over a single step. Solvers can keep history between steps,
+// so a solver should only be used by one CoSimulator.
+type Solver interface {
+	/*
+		Step integrates states x from time t by at most h.
+		It returns the new states and the step size taken, which adaptive solvers can reduce below h.
+	*/
+	Step(f Derivatives, t float64, x []float64, h float64) ([]float64, float64, error)
+
+	// Reset discards history, called when states change discontinuously at an event
+	Reset()
+}
+
+// ToleranceSetter is implemented by solvers that control their error.
+// The tolerance of fmi2SetupExperiment is passed to it when defined.
+type ToleranceSetter interface {
+	// SetTolerance sets the relative tolerance of the solver
+	SetTolerance(tolerance float64)
+}
+
+// Euler is the explicit (forward) Euler method
+type Euler struct{}
+
+// NewEuler returns an explicit Euler solver
+func NewEuler() *Euler {
+	return &Euler{}
+}
+
+// Step takes a forward Euler step of h
+func (e *Euler) Step(f Derivatives, t float64, x []float64, h float64) ([]float64, float64, error) {
+	dx, err := f(t, x)
+	if err != nil {
+		return nil, 0, err
+	}
+	return axpy(x, h, dx), h, nil
+}
+
+// Reset does nothing as Euler has no history
+func (e *Euler) Reset() {}
+
+// RK4 is the classic fourth order Runge-Kutta method
+type RK4 struct{}
+
+// NewRK4 returns a fourth order Runge-Kutta solver
+func NewRK4() *RK4 {
+	return &RK4{}
+}
+
+// Step takes a fourth order Runge-Kutta step of h
+func (r *RK4) Step(f Derivatives, t float64, x []float64, h float64) ([]float64, float64, error) {
+	k1, err := f(t, x)
+	if err != nil {
+		return nil, 0, err
+	}
+	k2, err := f(t+h/2, axpy(x, h/2, k1))
+	if err != nil {
+		return nil, 0, err
+	}
+	k3, err := f(t+h/2, axpy(x, h/2, k2))
+	if err != nil {
+		return nil, 0, err
+	}
+	k4, err := f(t+h, axpy(x, h, k3))
+	if err != nil {
+		return nil, 0, err
+	}
+	xn := make([]float64, len(x))
+	for i := range x {
+		xn[i] = x[i] + h/6*(k1[i]+2*k2[i]+2*k3[i]+k4[i])
+	}
+	return xn, h, nil
+}
+
+// Reset does nothing as RK4 has no history
+func (r *RK4) Reset() {}
+
+// axpy returns x + a*y
+func axpy(x []float64, a float64, y []float64) []float64 {
+	z := make([]float64, len(x))
+	for i := range x {
+		z[i] = x[i] + a*y[i]
+	}
+	return z
+}
+
+// solveLinear solves a*x = b with Gaussian elimination and partial pivoting. a and b are overwritten.
+func solveLinear(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i][k]) > math.Abs(a[p][k]) {
+				p = i
+			}
+		}
+		if a[p][k] == 0 {
+			return nil, errors.New("Singular matrix")
+		}
+		a[k], a[p] = a[p], a[k]
+		b[k], b[p] = b[p], b[k]
+		for i := k + 1; i < n; i++ {
+			m := a[i][k] / a[k][k]
+			for j := k; j < n; j++ {
+				a[i][j] -= m * a[k][j]
+			}
+			b[i] -= m * b[k]
+		}
+	}
+	x := make([]float64, n)
+	for i := n - 1; i >= 0; i-- {
+		s := b[i]
+		for j := i + 1; j < n; j++ {
+			s -= a[i][j] * x[j]
+		}
+		x[i] = s / a[i][i]
+	}
+	return x, nil
+}
+
+// minStepSize is the smallest step size adaptive solvers reduce to at time t
+func minStepSize(t float64) float64 {
+	return 1e-12 * (1 + math.Abs(t))
+}
+
+func stepSizeError(name string, t, h float64) error {
+	return fmt.Errorf("%s step size %g at time %g is too small", name, h, t)
+}
@@ -0,0 +1,140 @@
+package solver_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/solver"
+)
+
+// decay is x' = -x with solution x = exp(-t)
+func decay(t float64, x []float64) ([]float64, error) {
+	return []float64{-x[0]}, nil
+}
+
+// integrate solves f from 0 to end with step size h and returns the final states and number of steps
+func integrate(t *testing.T, s solver.Solver, f solver.Derivatives, x []float64, end, h float64) ([]float64, int) {
+	time, steps := 0.0, 0
+	for time < end-1e-12 {
+		var taken float64
+		var err error
+		x, taken, err = s.Step(f, time, x, math.Min(h, end-time))
+		if err != nil {
+			t.Fatalf("Step() error = %v", err)
+		}
+		time += taken
+		steps++
+	}
+	return x, steps
+}
+
+func TestSolver_Order(t *testing.T) {
+	tests := []struct {
+		name   string
+		solver func() solver.Solver
+		order  float64
+	}{
+		{"Euler", func() solver.Solver { return solver.NewEuler() }, 1},
+		{"RK4", func() solver.Solver { return solver.NewRK4() }, 4},
+		{"BDF", func() solver.Solver { return solver.NewBDF() }, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make([]float64, 2)
+			for i, h := range []float64{0.02, 0.01} {
+				x, _ := integrate(t, tt.solver(), decay, []float64{1}, 1, h)
+				errs[i] = math.Abs(x[0] - math.Exp(-1))
+			}
+			// halving the step size divides the error by 2^order
+			order := math.Log2(errs[0] / errs[1])
+			if math.Abs(order-tt.order) > 0.2 {
+				t.Errorf("Observed order %.2f, want %g", order, tt.order)
+			}
+		})
+	}
+}
+
+func TestDormandPrince_Tolerance(t *testing.T) {
+	tests := []struct {
+		tolerance float64
+	}{
+		{1e-3},
+		{1e-6},
+		{1e-9},
+	}
+	steps := 0
+	for _, tt := range tests {
+		s := solver.NewDormandPrince(0, 0)
+		s.SetTolerance(tt.tolerance)
+		x, n := integrate(t, s, decay, []float64{1}, 5, 1)
+		if e := math.Abs(x[0] - math.Exp(-5)); e > tt.tolerance {
+			t.Errorf("Tolerance %g: error %g above tolerance", tt.tolerance, e)
+		}
+		if n <= steps {
+			t.Errorf("Tolerance %g: %d steps, expected more than %d", tt.tolerance, n, steps)
+		}
+		steps = n
+	}
+}
+
+func TestDormandPrince_ZeroTolerance(t *testing.T) {
+	fall := func(t float64, x []float64) ([]float64, error) {
+		return []float64{x[1], -9.81}, nil
+	}
+	s := solver.NewDormandPrince(0, 0)
+	if _, _, err := s.Step(fall, 0, []float64{0, 1}, 0.1); err != nil {
+		t.Errorf("Step() with default tolerances error = %v", err)
+	}
+	s.SetTolerance(0)
+	if s.RelativeTolerance <= 0 || s.AbsoluteTolerance <= 0 {
+		t.Errorf("SetTolerance(0) tolerances = %g, %g, want default", s.RelativeTolerance, s.AbsoluteTolerance)
+	}
+
+	// tolerances set directly are not defaulted, the step must fail instead of looping
+	s.RelativeTolerance, s.AbsoluteTolerance = 0, 0
+	if _, _, err := s.Step(fall, 0, []float64{0, 1}, 0.1); err == nil {
+		t.Errorf("Step() with zero tolerances expected error")
+	}
+}
+
+func TestDormandPrince_Reset(t *testing.T) {
+	s := solver.NewDormandPrince(1e-6, 1e-6)
+	_, h, err := s.Step(decay, 0, []float64{1}, 1)
+	if err != nil {
+		t.Fatalf("Step() error = %v", err)
+	}
+	if h >= 1 {
+		t.Fatalf("Step() = %g, expected step to be reduced", h)
+	}
+	// the next step starts from the estimated step size, unless reset
+	_, h2, _ := s.Step(decay, h, []float64{1}, 1)
+	s.Reset()
+	_, h3, _ := s.Step(decay, h, []float64{1}, 1)
+	if h2 >= 1 || h3 != h {
+		t.Errorf("Step() after step = %g and after reset = %g, want < 1 and %g", h2, h3, h)
+	}
+}
+
+func TestBDF_Stiff(t *testing.T) {
+	// x' = -1000(x - cos(t)) quickly follows x = cos(t), explicit methods are unstable for h > 0.002
+	stiff := func(t float64, x []float64) ([]float64, error) {
+		return []float64{-1000 * (x[0] - math.Cos(t))}, nil
+	}
+	tests := []struct {
+		name   string
+		solver solver.Solver
+		stable bool
+	}{
+		{"Euler", solver.NewEuler(), false},
+		{"BDF", solver.NewBDF(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, _ := integrate(t, tt.solver, stiff, []float64{0}, 1, 0.05)
+			e := math.Abs(x[0] - math.Cos(1))
+			if stable := e < 1e-2; stable != tt.stable {
+				t.Errorf("Error %g, want stable %v", e, tt.stable)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Package test runs integration tests against the FMU in the TEST_FMU environment variable.
+The FMU is checked for compliance with pkg/checker and simulated with cmd/fmusim for each FMI type it supports.
+Tests are skipped if TEST_FMU is not set.
+*/
+package test
+
+import (
+	"encoding/csv"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tanenbaum/go-fmi/pkg/checker"
+	"github.com/tanenbaum/go-fmi/pkg/fmi"
+	"github.com/tanenbaum/go-fmi/pkg/importer"
+)
+
+// testFMU returns the path of the tested FMU, skipping the test if it is not set
+func testFMU(t *testing.T) string {
+	path := os.Getenv("TEST_FMU")
+	if path == "" {
+		t.Skip("TEST_FMU environment variable should be set")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return abs
+}
+
+func modelDescription(t *testing.T) fmi.ModelDescription {
+	a, err := importer.OpenArchive(testFMU(t))
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer a.Close()
+	return a.ModelDescription
+}
+
+// instantiate loads the FMU and instantiates it as fmuType, freeing it when the test completes
+func instantiate(t *testing.T, fmuType fmi.FMUType) (fmi.ModelDescription, *importer.Library, *importer.Instance) {
+	a, err := importer.OpenArchive(testFMU(t))
+	if err != nil {
+		t.Fatalf("OpenArchive() error = %v", err)
+	}
+	defer a.Close()
+	md := a.ModelDescription
+	id := ""
+	switch {
+	case fmuType == fmi.FMUTypeCoSimulation && md.CoSimulation != nil:
+		id = md.CoSimulation.ModelIdentifier
+	case fmuType == fmi.FMUTypeModelExchange && md.ModelExchange != nil:
+		id = md.ModelExchange.ModelIdentifier
+	default:
+		t.Skip("FMU does not support the FMU type")
+	}
+
+	dir := t.TempDir()
+	if err := a.Extract(dir); err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	binary, err := importer.BinaryPath(dir, id)
+	if err != nil {
+		t.Fatalf("BinaryPath() error = %v", err)
+	}
+	resources, err := importer.ResourceLocation(dir)
+	if err != nil {
+		t.Fatalf("ResourceLocation() error = %v", err)
+	}
+	lib, err := importer.Load(binary)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	inst, err := lib.Instantiate("test", fmuType, md.GUID, resources, importer.Callbacks{}, false, false)
+	if err != nil {
+		lib.Close()
+		t.Fatalf("Instantiate() error = %v", err)
+	}
+	t.Cleanup(func() {
+		inst.FreeInstance()
+		if err := lib.Close(); err != nil {
+			t.Errorf("Close() error = %v", err)
+		}
+	})
+	return md, lib, inst
+}
+
+func TestCompliance(t *testing.T) {
+	r, err := checker.Check(testFMU(t))
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	for _, res := range r.Results {
+		if res.Outcome == checker.OutcomeFail {
+			t.Errorf("%s: %s", res.Name(), res.Message)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	md, lib, _ := instantiate(t, fmi.FMUTypeCoSimulation)
+	if v := lib.Version(); v != md.FMIVersion {
+		t.Errorf("Version() = %s, want %s", v, md.FMIVersion)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	fmu := testFMU(t)
+	md := modelDescription(t)
+	tests := []struct {
+		name      string
+		supported bool
+	}{
+		{"cs", md.CoSimulation != nil},
+		{"me", md.ModelExchange != nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.supported {
+				t.Skipf("FMU does not support %s", tt.name)
+			}
+			output := filepath.Join(t.TempDir(), "out.csv")
+			cmd := exec.Command("go", "run", "../cmd/fmusim", "-interface", tt.name, "-o", output, fmu)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("fmusim error = %v\n%s", err, out)
+			}
+			f, err := os.Open(output)
+			if err != nil {
+				t.Fatalf("Error opening output: %v", err)
+			}
+			defer f.Close()
+			records, err := csv.NewReader(f).ReadAll()
+			if err != nil {
+				t.Fatalf("Error reading output: %v", err)
+			}
+			if len(records) < 2 || records[0][0] != "time" {
+				t.Errorf("Output has %d records, want header and results", len(records))
+			}
+		})
+	}
+}